@@ -1,29 +1,316 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/netip"
+	"os"
+	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"renfound_v1/internal/utils/logcontrol"
+	"renfound_v1/internal/utils/redact"
 )
 
 // Config holds the application configurations
 type Config struct {
-	DB       PostgresConfig `mapstructure:"postgres"`
-	Server   ServerConfig   `mapstructure:"server"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	Logger   LoggerConfig   `mapstructure:"logger"`
-	Telegram TelegramConfig `mapstructure:"telegram"` // Add this line
+	Env         string            `mapstructure:"env"` // "development" or "production"
+	DB          PostgresConfig    `mapstructure:"postgres"`
+	Server      ServerConfig      `mapstructure:"server"`
+	JWT         JWTConfig         `mapstructure:"jwt"`
+	Redis       RedisConfig       `mapstructure:"redis"`
+	Logger      LoggerConfig      `mapstructure:"logger"`
+	Telegram    TelegramConfig    `mapstructure:"telegram"`
+	WorkerPool  WorkerPoolConfig  `mapstructure:"workerpool"`
+	RateLimit   RateLimitConfig   `mapstructure:"ratelimit"`
+	Metrics     MetricsConfig     `mapstructure:"metrics"`
+	Tracing     TracingConfig     `mapstructure:"tracing"`
+	Health      HealthConfig      `mapstructure:"health"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	CORS        CORSConfig        `mapstructure:"cors"`
+	Security    SecurityConfig    `mapstructure:"security"`
+	Proxy       ProxyConfig       `mapstructure:"proxy"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	Admin       AdminConfig       `mapstructure:"admin"`
+	Logins      LoginsConfig      `mapstructure:"logins"`
+	Broadcast   BroadcastConfig   `mapstructure:"broadcast"`
+	Payments    PaymentsConfig    `mapstructure:"payments"`
+}
+
+// PaymentsConfig holds Telegram Stars payment configurations
+type PaymentsConfig struct {
+	// InvoiceTTL is how long an invoice link can still be paid after it
+	// was created
+	InvoiceTTL time.Duration   `mapstructure:"invoicettl"`
+	Products   []ProductConfig `mapstructure:"products"`
+}
+
+// ProductConfig is a premium feature sold for Telegram Stars
+type ProductConfig struct {
+	ID          string `mapstructure:"id"`
+	Title       string `mapstructure:"title"`       // up to 32 characters
+	Description string `mapstructure:"description"` // up to 255 characters
+	Price       int    `mapstructure:"price"`       // in Stars
+	// Entitlement is the feature unlocked by the purchase. Products may
+	// share one, e.g. monthly and yearly premium.
+	Entitlement string `mapstructure:"entitlement"`
+	// Duration of the entitlement, 0 for one that never expires. Buying
+	// again extends an entitlement that has not expired yet.
+	Duration time.Duration `mapstructure:"duration"`
+}
+
+// BroadcastConfig holds broadcast campaign delivery configurations
+type BroadcastConfig struct {
+	// RateLimit is the messages per second sent by all instances together,
+	// shared through redis. Without redis.url each instance sends at this
+	// rate, so only one may run. Keep it below telegram.ratelimit so bot
+	// replies are not starved.
+	RateLimit float64 `mapstructure:"ratelimit"`
+	// BatchSize is the recipients claimed at a time
+	BatchSize    int           `mapstructure:"batchsize"`
+	PollInterval time.Duration `mapstructure:"pollinterval"` // wait between checks when idle
+	// LeaseTimeout is how long claimed recipients stay with an instance
+	// before another one may send to them, after a crash
+	LeaseTimeout time.Duration `mapstructure:"leasetimeout"`
+}
+
+// LoginsConfig holds login history configurations
+type LoginsConfig struct {
+	// Devices are told apart by user agent and client network. The prefix
+	// lengths set how large that network is, so that a phone moving
+	// between addresses of its carrier stays the same device.
+	IPv4PrefixLength int `mapstructure:"ipv4prefixlength"`
+	IPv6PrefixLength int `mapstructure:"ipv6prefixlength"`
+	// NewDeviceAlerts notifies users of sign ins from unknown devices
+	NewDeviceAlerts bool `mapstructure:"newdevicealerts"`
+}
+
+// AdminConfig holds admin access configurations
+type AdminConfig struct {
+	// TelegramIDs lists the Telegram users allowed to use the admin API
+	TelegramIDs []int64 `mapstructure:"telegramids"`
+}
+
+// IsAdmin reports whether the Telegram user is an admin
+func (c AdminConfig) IsAdmin(telegramID int64) bool {
+	for _, id := range c.TelegramIDs {
+		if id == telegramID {
+			return true
+		}
+	}
+	return false
+}
+
+// IdempotencyConfig holds Idempotency-Key handling configurations
+type IdempotencyConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Backend string        `mapstructure:"backend"` // "postgres" or "redis"
+	TTL     time.Duration `mapstructure:"ttl"`     // how long responses are replayed
+	LockTTL time.Duration `mapstructure:"lockttl"` // how long a crashed request blocks its key
+}
+
+// SecurityConfig holds security response header configurations
+type SecurityConfig struct {
+	HSTSMaxAge            time.Duration `mapstructure:"hstsmaxage"` // 0 disables HSTS
+	HSTSIncludeSubdomains bool          `mapstructure:"hstsincludesubdomains"`
+	// AllowTelegramEmbedding lets Telegram web clients frame the app
+	AllowTelegramEmbedding bool `mapstructure:"allowtelegramembedding"`
+}
+
+// ProxyConfig holds reverse proxy configurations
+type ProxyConfig struct {
+	// TrustedProxies lists the IPs or CIDR ranges whose X-Forwarded-For and
+	// X-Real-IP headers are honored
+	TrustedProxies []string `mapstructure:"trustedproxies"`
+}
+
+// TrustedPrefixes parses the trusted proxies, single IPs become /32 or /128
+func (c ProxyConfig) TrustedPrefixes() ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, entry := range c.TrustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %v", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", entry, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// AuthConfig holds refresh token transport configurations
+type AuthConfig struct {
+	RefreshTokenMode string           `mapstructure:"refreshtokenmode"` // "body" or "cookie"
+	Cookie           AuthCookieConfig `mapstructure:"cookie"`
+}
+
+// AuthCookieConfig holds the refresh token and CSRF cookie settings used in
+// cookie mode
+type AuthCookieConfig struct {
+	Name     string `mapstructure:"name"`
+	CSRFName string `mapstructure:"csrfname"`
+	Domain   string `mapstructure:"domain"`
+	// Paths scope the refresh token cookie to the auth route groups. It is
+	// set and cleared on each, so either group finds it.
+	Paths    []string `mapstructure:"paths"` // comma separated in the environment
+	Secure   bool     `mapstructure:"secure"`
+	SameSite string   `mapstructure:"samesite"` // "Strict", "Lax" or "None"
+}
+
+// CORSConfig holds CORS configurations
+type CORSConfig struct {
+	AllowOrigins     string `mapstructure:"alloworigins"`  // comma separated
+	AllowTelegram    bool   `mapstructure:"allowtelegram"` // adds the Telegram web client origins
+	AllowCredentials bool   `mapstructure:"allowcredentials"`
+}
+
+// TelegramWebOrigins are the origins of the Telegram web clients that
+// embed Mini Apps
+var TelegramWebOrigins = []string{
+	"https://web.telegram.org",
+	"https://webk.telegram.org",
+	"https://webz.telegram.org",
+}
+
+// Origins returns the allowed origins including Telegram's when enabled
+func (c CORSConfig) Origins() string {
+	if !c.AllowTelegram || c.AllowOrigins == "*" {
+		return c.AllowOrigins
+	}
+
+	origins := c.AllowOrigins
+	for _, origin := range TelegramWebOrigins {
+		if origins != "" {
+			origins += ","
+		}
+		origins += origin
+	}
+	return origins
+}
+
+// HealthConfig holds health check configurations
+type HealthConfig struct {
+	CheckTimeout    time.Duration `mapstructure:"checktimeout"`
+	CacheTTL        time.Duration `mapstructure:"cachettl"`
+	QueueSaturation float64       `mapstructure:"queuesaturation"`
+	// StallTimeout is how long the worker pool may stay fully busy without
+	// finishing a task before the liveness probe fails
+	StallTimeout time.Duration `mapstructure:"stalltimeout"`
+}
+
+// TracingConfig holds OpenTelemetry tracing configurations
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Exporter    string  `mapstructure:"exporter"` // "otlp" or "stdout"
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	ServiceName string  `mapstructure:"servicename"`
+	SampleRatio float64 `mapstructure:"sampleratio"`
+}
+
+// MetricsConfig holds the internal metrics listener configurations
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Host    string `mapstructure:"host"`
+	Port    string `mapstructure:"port"`
+	Path    string `mapstructure:"path"`
+}
+
+// RateLimitConfig holds rate limiting configurations
+type RateLimitConfig struct {
+	Enabled  bool                       `mapstructure:"enabled"`
+	Backend  string                     `mapstructure:"backend"` // "memory" or "redis"
+	Policies map[string]RateLimitPolicy `mapstructure:"policies"`
+}
+
+// Rate limit policies applied by the routes, each must be configured when
+// rate limiting is enabled
+const (
+	RateLimitPolicyDefault = "default"
+	RateLimitPolicyAuth    = "auth"
+)
+
+// routeRateLimitPolicies lists the policies applied by the routes
+var routeRateLimitPolicies = []string{RateLimitPolicyDefault, RateLimitPolicyAuth}
+
+// RateLimitPolicy holds a named rate limit applied to one or more routes
+type RateLimitPolicy struct {
+	Limit  int           `mapstructure:"limit"`
+	Period time.Duration `mapstructure:"period"`
+	Burst  int           `mapstructure:"burst"`
+	// KeyBy is "ip", "user" or "route". Routes outside the authenticated
+	// groups have no user, "user" keys their callers by IP.
+	KeyBy string `mapstructure:"keyby"`
+}
+
+// WorkerPoolConfig holds async worker pool configurations
+type WorkerPoolConfig struct {
+	Workers int                `mapstructure:"workers"`
+	Lanes   []WorkerLaneConfig `mapstructure:"lanes"`
+}
+
+// WorkerLaneConfig holds the settings of a single worker pool lane
+type WorkerLaneConfig struct {
+	Name           string  `mapstructure:"name"`
+	Priority       int     `mapstructure:"priority"`
+	QueueSize      int     `mapstructure:"queuesize"`
+	MaxConcurrency int     `mapstructure:"maxconcurrency"`
+	RateLimit      float64 `mapstructure:"ratelimit"`
+	RateBurst      int     `mapstructure:"rateburst"`
 }
 
 // TelegramConfig holds Telegram configurations
 type TelegramConfig struct {
 	BotToken string `mapstructure:"bottoken"`
+	// APIURL is the Bot API server, overridden to point at a local server
+	APIURL     string        `mapstructure:"apiurl"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	MaxRetries int           `mapstructure:"maxretries"` // retries of requests rejected with 429
+	// Outgoing message rates in messages per second, kept below Telegram's
+	// limits of 30 overall, 1 per private chat and 20 a minute per group
+	RateLimit      float64 `mapstructure:"ratelimit"`
+	ChatRateLimit  float64 `mapstructure:"chatratelimit"`
+	GroupRateLimit float64 `mapstructure:"groupratelimit"`
+
+	Webhook TelegramWebhookConfig `mapstructure:"webhook"`
+	// WebAppURL is the Mini App opened from the bot's buttons
+	WebAppURL string `mapstructure:"webappurl"`
+	// MiniAppLink is the direct link of the Mini App, such as
+	// https://t.me/renfound_bot/app. Referral links are built on it.
+	MiniAppLink string `mapstructure:"miniapplink"`
+}
+
+// TelegramWebhookConfig holds bot update webhook configurations. The
+// webhook is disabled without a secret.
+type TelegramWebhookConfig struct {
+	// Secret is sent by Telegram in X-Telegram-Bot-Api-Secret-Token
+	Secret string `mapstructure:"secret"`
+	// URL is registered with Telegram on startup when set
+	URL           string        `mapstructure:"url"`
+	DedupeBackend string        `mapstructure:"dedupebackend"` // "memory" or "redis"
+	DedupeTTL     time.Duration `mapstructure:"dedupettl"`     // how long update IDs are remembered
 }
 
 // PostgresConfig holds PostgreSQL configurations
@@ -57,12 +344,38 @@ type LoggerConfig struct {
 	Level      string `mapstructure:"level"`
 	Encoding   string `mapstructure:"encoding"`
 	OutputPath string `mapstructure:"outputpath"`
+	// RedactKeys lists personal data fields masked in every log entry, on
+	// top of the tokens and secrets that are always masked
+	RedactKeys []string `mapstructure:"redactkeys"`
+	// ComponentLevels overrides the level of loggers tagged with a
+	// component, e.g. {"worker_pool": "debug"}
+	ComponentLevels map[string]string `mapstructure:"componentlevels"`
+	Sampling        LogSamplingConfig `mapstructure:"sampling"`
+	Control         LogControlConfig  `mapstructure:"control"`
+}
+
+// LogControlConfig holds the listener of the unauthenticated /loglevel
+// endpoint, kept apart from the metrics listener that scrapers reach
+type LogControlConfig struct {
+	Host string `mapstructure:"host"`
+	Port string `mapstructure:"port"`
+}
+
+// LogSamplingConfig holds sampling of high volume info and debug logs
+type LogSamplingConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Tick       time.Duration `mapstructure:"tick"`
+	Initial    int           `mapstructure:"initial"`    // entries with the same message logged per tick
+	Thereafter int           `mapstructure:"thereafter"` // then every Nth is logged
+	Components []string      `mapstructure:"components"`
 }
 
 // AppConfig holds the application configuration and logger instance
 type AppConfig struct {
 	Config *Config
 	Logger *zap.Logger
+	// LogLevels changes the logger levels at runtime
+	LogLevels *logcontrol.Controller
 }
 
 // LoadConfig initializes and returns the application configuration with logger
@@ -85,13 +398,23 @@ func LoadConfig() (*AppConfig, error) {
 	// Set default values
 	setDefaults()
 
+	// Lists and maps of structs can't be read from plain environment
+	// variables, so they are passed as JSON
+	if err := bindJSONEnvs(); err != nil {
+		return nil, err
+	}
+
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %v", err)
 	}
 
+	if err := validate(&config); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+
 	// Initialize logger
-	logger, err := initLogger(config.Logger)
+	logger, logLevels, err := initLogger(config.Logger)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing logger: %v", err)
 	}
@@ -103,22 +426,279 @@ func LoadConfig() (*AppConfig, error) {
 
 	// Log successful configuration loading
 	logger.Info("Configuration loaded successfully",
-		zap.String("db_schema", "postgres://****:****@"+strings.Split(config.DB.URL, "@")[1]),
+		zap.String("db_url", redact.URL(config.DB.URL)),
 		zap.String("server_host", config.Server.Host),
 		zap.String("server_port", config.Server.Port),
 	)
 
 	return &AppConfig{
-		Config: &config,
-		Logger: logger,
+		Config:    &config,
+		Logger:    logger,
+		LogLevels: logLevels,
 	}, nil
 }
 
-// initLogger creates and configures a new Zap logger
-func initLogger(cfg LoggerConfig) (*zap.Logger, error) {
+// ReloadLogLevels reapplies the configured log levels, dropping overrides
+// made at runtime. The .env file is read again, so levels can be changed
+// there without a restart.
+func (c *AppConfig) ReloadLogLevels() error {
+	if err := godotenv.Overload(); err != nil {
+		c.Logger.Debug("No .env file to reload", zap.Error(err))
+	}
+	if err := bindJSONEnvs(); err != nil {
+		return err
+	}
+
+	components, err := componentLevels(viper.GetStringMapString("logger.componentlevels"))
+	if err != nil {
+		return err
+	}
+
+	c.LogLevels.Reset(getLogLevel(viper.GetString("logger.level")), components)
+
+	return nil
+}
+
+// webhookSecretPattern matches the secrets Telegram accepts, or none
+var webhookSecretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{0,256}$`)
+
+// productIDPattern matches product and entitlement names
+var productIDPattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// validate fills environment dependent defaults and rejects combinations
+// of settings that cannot work together
+func validate(config *Config) error {
+	switch config.Auth.RefreshTokenMode {
+	case "body", "cookie":
+	default:
+		return fmt.Errorf("unknown refresh token mode %q", config.Auth.RefreshTokenMode)
+	}
+
+	switch config.Auth.Cookie.SameSite {
+	case "Strict", "Lax", "None":
+	default:
+		return fmt.Errorf("unknown cookie SameSite mode %q", config.Auth.Cookie.SameSite)
+	}
+
+	if len(config.Auth.Cookie.Paths) == 0 {
+		return fmt.Errorf("at least one cookie path is required")
+	}
+	for _, path := range config.Auth.Cookie.Paths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("cookie path %q must start with /", path)
+		}
+	}
+
+	// Browsers drop SameSite=None cookies that are not Secure
+	if config.Auth.Cookie.SameSite == "None" && !config.Auth.Cookie.Secure {
+		return fmt.Errorf("SameSite=None cookies must be Secure")
+	}
+
+	switch config.Env {
+	case "development":
+		// Local frontends run on arbitrary ports
+		if config.CORS.AllowOrigins == "" {
+			config.CORS.AllowOrigins = "*"
+		}
+	case "production":
+		if config.CORS.AllowOrigins == "" || config.CORS.AllowOrigins == "*" {
+			return fmt.Errorf("explicit CORS origins are required in production")
+		}
+	default:
+		return fmt.Errorf("unknown environment %q", config.Env)
+	}
+
+	if _, err := config.Proxy.TrustedPrefixes(); err != nil {
+		return err
+	}
+
+	if _, err := componentLevels(config.Logger.ComponentLevels); err != nil {
+		return err
+	}
+	if config.Logger.Control.Port == "" {
+		return fmt.Errorf("log control port is required")
+	}
+	// Scrapers reach the metrics listener, it must not serve /loglevel or
+	// the health reports
+	if config.Logger.Control.Port == config.Metrics.Port {
+		return fmt.Errorf("log control port must differ from the metrics port")
+	}
+
+	// A zero stall timeout would fail the liveness probe on every run
+	if config.Health.StallTimeout <= 0 {
+		return fmt.Errorf("health stall timeout must be positive")
+	}
+
+	if config.Telegram.RateLimit <= 0 || config.Telegram.ChatRateLimit <= 0 || config.Telegram.GroupRateLimit <= 0 {
+		return fmt.Errorf("telegram rate limits must be positive")
+	}
+
+	// Telegram only accepts these characters in webhook secrets
+	if !webhookSecretPattern.MatchString(config.Telegram.Webhook.Secret) {
+		return fmt.Errorf("telegram webhook secret must be up to 256 characters of A-Z, a-z, 0-9, _ and -")
+	}
+	if config.Telegram.Webhook.Secret != "" && config.Telegram.BotToken == "" {
+		return fmt.Errorf("telegram webhook requires a bot token")
+	}
+	if config.Telegram.Webhook.URL != "" && config.Telegram.Webhook.Secret == "" {
+		return fmt.Errorf("telegram webhook URL requires a webhook secret")
+	}
+
+	if config.Logins.IPv4PrefixLength < 0 || config.Logins.IPv4PrefixLength > 32 {
+		return fmt.Errorf("logins IPv4 prefix length must be between 0 and 32")
+	}
+	if config.Logins.IPv6PrefixLength < 0 || config.Logins.IPv6PrefixLength > 128 {
+		return fmt.Errorf("logins IPv6 prefix length must be between 0 and 128")
+	}
+
+	if config.Broadcast.RateLimit <= 0 || config.Broadcast.BatchSize <= 0 ||
+		config.Broadcast.PollInterval <= 0 || config.Broadcast.LeaseTimeout <= 0 {
+		return fmt.Errorf("broadcast rate limit, batch size, poll interval and lease timeout must be positive")
+	}
+
+	if err := validateRateLimit(config.RateLimit); err != nil {
+		return err
+	}
+
+	if err := validateWorkerPool(config.WorkerPool); err != nil {
+		return err
+	}
+
+	if err := validateProducts(config.Payments); err != nil {
+		return err
+	}
+
+	// Browsers refuse credentialed responses with a wildcard origin
+	if config.CORS.AllowCredentials && strings.Contains(config.CORS.AllowOrigins, "*") {
+		return fmt.Errorf("CORS credentials require explicit allowed origins")
+	}
+
+	return nil
+}
+
+// validateRateLimit rejects policies that could not be enforced. They are
+// only checked when rate limiting is enabled.
+func validateRateLimit(cfg RateLimitConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	for name, policy := range cfg.Policies {
+		if policy.Limit <= 0 || policy.Period <= 0 {
+			return fmt.Errorf("rate limit policy %q limit and period must be positive", name)
+		}
+		if policy.Burst < 0 {
+			return fmt.Errorf("rate limit policy %q burst must not be negative", name)
+		}
+		switch policy.KeyBy {
+		case "", "ip", "user", "route":
+		default:
+			return fmt.Errorf("rate limit policy %q has unknown key %q", name, policy.KeyBy)
+		}
+	}
+
+	for _, name := range routeRateLimitPolicies {
+		if _, ok := cfg.Policies[name]; !ok {
+			return fmt.Errorf("rate limit policy %q is not configured", name)
+		}
+	}
+
+	// The auth policy guards the sign in routes, no user is known yet
+	if cfg.Policies[RateLimitPolicyAuth].KeyBy == "user" {
+		return fmt.Errorf("rate limit policy %q runs before authentication and cannot be keyed by user", RateLimitPolicyAuth)
+	}
+
+	return nil
+}
+
+// validateWorkerPool rejects lanes the worker pool would silently
+// misbehave with, e.g. a lane without a queue drops every task
+func validateWorkerPool(cfg WorkerPoolConfig) error {
+	if cfg.Workers < 1 {
+		return fmt.Errorf("worker pool needs at least one worker")
+	}
+
+	names := make(map[string]bool, len(cfg.Lanes))
+	for _, lane := range cfg.Lanes {
+		if lane.Name == "" {
+			return fmt.Errorf("worker pool lane name is required")
+		}
+		if names[lane.Name] {
+			return fmt.Errorf("duplicate worker pool lane %q", lane.Name)
+		}
+		names[lane.Name] = true
+
+		if lane.QueueSize < 1 {
+			return fmt.Errorf("worker pool lane %q queue size must be positive", lane.Name)
+		}
+		if lane.MaxConcurrency < 0 {
+			return fmt.Errorf("worker pool lane %q max concurrency must not be negative", lane.Name)
+		}
+		if lane.RateLimit < 0 || lane.RateBurst < 0 {
+			return fmt.Errorf("worker pool lane %q rate limit and burst must not be negative", lane.Name)
+		}
+	}
+
+	return nil
+}
+
+// validateProducts checks the product catalog against what Telegram
+// accepts in an invoice
+func validateProducts(cfg PaymentsConfig) error {
+	if cfg.InvoiceTTL <= 0 {
+		return fmt.Errorf("payments invoice TTL must be positive")
+	}
+
+	ids := make(map[string]bool, len(cfg.Products))
+	for _, product := range cfg.Products {
+		if !productIDPattern.MatchString(product.ID) {
+			return fmt.Errorf("product id %q must be up to 64 characters of a-z, 0-9, _ and -", product.ID)
+		}
+		if ids[product.ID] {
+			return fmt.Errorf("duplicate product id %q", product.ID)
+		}
+		ids[product.ID] = true
+
+		if n := utf8.RuneCountInString(product.Title); n == 0 || n > 32 {
+			return fmt.Errorf("product %q title must be 1 to 32 characters", product.ID)
+		}
+		if n := utf8.RuneCountInString(product.Description); n == 0 || n > 255 {
+			return fmt.Errorf("product %q description must be 1 to 255 characters", product.ID)
+		}
+		if product.Price <= 0 {
+			return fmt.Errorf("product %q price must be positive", product.ID)
+		}
+		if !productIDPattern.MatchString(product.Entitlement) {
+			return fmt.Errorf("product %q entitlement must be up to 64 characters of a-z, 0-9, _ and -", product.ID)
+		}
+		if product.Duration < 0 {
+			return fmt.Errorf("product %q duration must not be negative", product.ID)
+		}
+	}
+
+	return nil
+}
+
+// initLogger creates and configures a new Zap logger together with the
+// controller of its levels
+func initLogger(cfg LoggerConfig) (*zap.Logger, *logcontrol.Controller, error) {
 	// Convert log level string to zapcore.Level
 	level := getLogLevel(cfg.Level)
 
+	// Already validated
+	components, _ := componentLevels(cfg.ComponentLevels)
+
+	var sampling logcontrol.Sampling
+	if cfg.Sampling.Enabled {
+		sampling = logcontrol.Sampling{
+			Tick:       cfg.Sampling.Tick,
+			Initial:    cfg.Sampling.Initial,
+			Thereafter: cfg.Sampling.Thereafter,
+			Components: cfg.Sampling.Components,
+		}
+	}
+	logLevels := logcontrol.NewController(level, components, sampling)
+
 	// Default to JSON in production, console in development
 	encoding := cfg.Encoding
 	if encoding == "" {
@@ -127,7 +707,8 @@ func initLogger(cfg LoggerConfig) (*zap.Logger, error) {
 
 	// Configure logger
 	config := zap.Config{
-		Level:            zap.NewAtomicLevelAt(level),
+		// Levels are enforced by the controller, the core accepts everything
+		Level:            zap.NewAtomicLevelAt(zapcore.DebugLevel),
 		Encoding:         encoding,
 		OutputPaths:      []string{getOutputPath(cfg.OutputPath)},
 		ErrorOutputPaths: []string{getOutputPath(cfg.OutputPath)},
@@ -147,7 +728,28 @@ func initLogger(cfg LoggerConfig) (*zap.Logger, error) {
 		},
 	}
 
-	return config.Build()
+	// Every entry goes through the redaction core, whatever logged it
+	logger, err := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return logLevels.Wrap(redact.NewCore(core, cfg.RedactKeys))
+	}))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return logger, logLevels, nil
+}
+
+// componentLevels parses the per component log levels
+func componentLevels(levels map[string]string) (map[string]zapcore.Level, error) {
+	parsed := make(map[string]zapcore.Level, len(levels))
+	for component, levelStr := range levels {
+		level, err := zapcore.ParseLevel(levelStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level for component %s: %v", component, err)
+		}
+		parsed[component] = level
+	}
+	return parsed, nil
 }
 
 // getLogLevel converts string log level to zapcore.Level
@@ -183,20 +785,85 @@ func getOutputPath(path string) string {
 // bindEnvs binds each configuration key to its corresponding environment variable
 func bindEnvs() {
 	envBindings := map[string]string{
-		"postgres.url":      "DATABASE_URL",
-		"server.port":       "APP_SERVER_PORT",
-		"server.host":       "APP_SERVER_HOST",
-		"jwt.accessSecret":  "APP_JWT_ACCESSSECRET",
-		"jwt.refreshSecret": "APP_JWT_REFRESHSECRET",
-		"jwt.accessTTL":     "APP_JWT_ACCESSTTL",
-		"jwt.refreshTTL":    "APP_JWT_REFRESHTTL",
-		"redis.url":         "REDIS_URL",
-		"redis.password":    "REDIS_PASSWORD",
-		"redis.db":          "REDIS_DB",
-		"logger.level":      "APP_LOGGER_LEVEL",
-		"logger.encoding":   "APP_LOGGER_ENCODING",
-		"logger.outputpath": "APP_LOGGER_OUTPUTPATH",
-		"telegram.bottoken": "TELEGRAM_BOT_TOKEN",
+		"postgres.url":                    "DATABASE_URL",
+		"server.port":                     "APP_SERVER_PORT",
+		"server.host":                     "APP_SERVER_HOST",
+		"jwt.accessSecret":                "APP_JWT_ACCESSSECRET",
+		"jwt.refreshSecret":               "APP_JWT_REFRESHSECRET",
+		"jwt.accessTTL":                   "APP_JWT_ACCESSTTL",
+		"jwt.refreshTTL":                  "APP_JWT_REFRESHTTL",
+		"redis.url":                       "REDIS_URL",
+		"redis.password":                  "REDIS_PASSWORD",
+		"redis.db":                        "REDIS_DB",
+		"logger.level":                    "APP_LOGGER_LEVEL",
+		"logger.encoding":                 "APP_LOGGER_ENCODING",
+		"logger.outputpath":               "APP_LOGGER_OUTPUTPATH",
+		"logger.redactkeys":               "APP_LOGGER_REDACTKEYS",
+		"logger.sampling.enabled":         "APP_LOGGER_SAMPLING_ENABLED",
+		"logger.sampling.tick":            "APP_LOGGER_SAMPLING_TICK",
+		"logger.sampling.initial":         "APP_LOGGER_SAMPLING_INITIAL",
+		"logger.sampling.thereafter":      "APP_LOGGER_SAMPLING_THEREAFTER",
+		"logger.sampling.components":      "APP_LOGGER_SAMPLING_COMPONENTS",
+		"logger.control.host":             "APP_LOGGER_CONTROL_HOST",
+		"logger.control.port":             "APP_LOGGER_CONTROL_PORT",
+		"telegram.bottoken":               "TELEGRAM_BOT_TOKEN",
+		"telegram.apiurl":                 "APP_TELEGRAM_APIURL",
+		"telegram.timeout":                "APP_TELEGRAM_TIMEOUT",
+		"telegram.maxretries":             "APP_TELEGRAM_MAXRETRIES",
+		"telegram.ratelimit":              "APP_TELEGRAM_RATELIMIT",
+		"telegram.chatratelimit":          "APP_TELEGRAM_CHATRATELIMIT",
+		"telegram.groupratelimit":         "APP_TELEGRAM_GROUPRATELIMIT",
+		"telegram.webappurl":              "APP_TELEGRAM_WEBAPPURL",
+		"telegram.miniapplink":            "APP_TELEGRAM_MINIAPPLINK",
+		"telegram.webhook.secret":         "APP_TELEGRAM_WEBHOOK_SECRET",
+		"telegram.webhook.url":            "APP_TELEGRAM_WEBHOOK_URL",
+		"telegram.webhook.dedupebackend":  "APP_TELEGRAM_WEBHOOK_DEDUPEBACKEND",
+		"telegram.webhook.dedupettl":      "APP_TELEGRAM_WEBHOOK_DEDUPETTL",
+		"workerpool.workers":              "APP_WORKERPOOL_WORKERS",
+		"ratelimit.enabled":               "APP_RATELIMIT_ENABLED",
+		"ratelimit.backend":               "APP_RATELIMIT_BACKEND",
+		"metrics.enabled":                 "APP_METRICS_ENABLED",
+		"metrics.host":                    "APP_METRICS_HOST",
+		"metrics.port":                    "APP_METRICS_PORT",
+		"metrics.path":                    "APP_METRICS_PATH",
+		"tracing.enabled":                 "APP_TRACING_ENABLED",
+		"tracing.exporter":                "APP_TRACING_EXPORTER",
+		"tracing.endpoint":                "APP_TRACING_ENDPOINT",
+		"tracing.insecure":                "APP_TRACING_INSECURE",
+		"tracing.servicename":             "OTEL_SERVICE_NAME",
+		"tracing.sampleratio":             "APP_TRACING_SAMPLERATIO",
+		"health.checktimeout":             "APP_HEALTH_CHECKTIMEOUT",
+		"health.cachettl":                 "APP_HEALTH_CACHETTL",
+		"health.queuesaturation":          "APP_HEALTH_QUEUESATURATION",
+		"health.stalltimeout":             "APP_HEALTH_STALLTIMEOUT",
+		"auth.refreshtokenmode":           "APP_AUTH_REFRESHTOKENMODE",
+		"auth.cookie.name":                "APP_AUTH_COOKIE_NAME",
+		"auth.cookie.csrfname":            "APP_AUTH_COOKIE_CSRFNAME",
+		"auth.cookie.domain":              "APP_AUTH_COOKIE_DOMAIN",
+		"auth.cookie.paths":               "APP_AUTH_COOKIE_PATHS",
+		"auth.cookie.secure":              "APP_AUTH_COOKIE_SECURE",
+		"auth.cookie.samesite":            "APP_AUTH_COOKIE_SAMESITE",
+		"cors.alloworigins":               "APP_CORS_ALLOWORIGINS",
+		"cors.allowtelegram":              "APP_CORS_ALLOWTELEGRAM",
+		"cors.allowcredentials":           "APP_CORS_ALLOWCREDENTIALS",
+		"env":                             "APP_ENV",
+		"security.hstsmaxage":             "APP_SECURITY_HSTSMAXAGE",
+		"security.hstsincludesubdomains":  "APP_SECURITY_HSTSINCLUDESUBDOMAINS",
+		"security.allowtelegramembedding": "APP_SECURITY_ALLOWTELEGRAMEMBEDDING",
+		"proxy.trustedproxies":            "APP_PROXY_TRUSTEDPROXIES",
+		"idempotency.enabled":             "APP_IDEMPOTENCY_ENABLED",
+		"idempotency.backend":             "APP_IDEMPOTENCY_BACKEND",
+		"idempotency.ttl":                 "APP_IDEMPOTENCY_TTL",
+		"idempotency.lockttl":             "APP_IDEMPOTENCY_LOCKTTL",
+		"admin.telegramids":               "APP_ADMIN_TELEGRAMIDS",
+		"logins.ipv4prefixlength":         "APP_LOGINS_IPV4PREFIXLENGTH",
+		"logins.ipv6prefixlength":         "APP_LOGINS_IPV6PREFIXLENGTH",
+		"logins.newdevicealerts":          "APP_LOGINS_NEWDEVICEALERTS",
+		"broadcast.ratelimit":             "APP_BROADCAST_RATELIMIT",
+		"broadcast.batchsize":             "APP_BROADCAST_BATCHSIZE",
+		"broadcast.pollinterval":          "APP_BROADCAST_POLLINTERVAL",
+		"broadcast.leasetimeout":          "APP_BROADCAST_LEASETIMEOUT",
+		"payments.invoicettl":             "APP_PAYMENTS_INVOICETTL",
 	}
 
 	for configKey, envVar := range envBindings {
@@ -207,6 +874,33 @@ func bindEnvs() {
 	}
 }
 
+// bindJSONEnvs reads configuration keys whose values are passed as JSON.
+// Values are set through viper so they are decoded like any other key,
+// e.g. "1m" becomes a time.Duration.
+func bindJSONEnvs() error {
+	jsonBindings := map[string]string{
+		"workerpool.lanes":       "APP_WORKERPOOL_LANES",
+		"ratelimit.policies":     "APP_RATELIMIT_POLICIES",
+		"logger.componentlevels": "APP_LOGGER_COMPONENTLEVELS",
+		"payments.products":      "APP_PAYMENTS_PRODUCTS",
+	}
+
+	for configKey, envVar := range jsonBindings {
+		raw := os.Getenv(envVar)
+		if raw == "" {
+			continue
+		}
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return fmt.Errorf("error parsing %s: %v", envVar, err)
+		}
+		viper.Set(configKey, value)
+	}
+
+	return nil
+}
+
 // setDefaults sets default configuration values
 func setDefaults() {
 	// Server defaults
@@ -217,4 +911,116 @@ func setDefaults() {
 	viper.SetDefault("logger.level", "info")
 	viper.SetDefault("logger.encoding", "json")
 	viper.SetDefault("logger.outputpath", "stdout")
+	viper.SetDefault("logger.redactkeys", []string{"first_name", "last_name", "username", "phone_number", "photo_url"})
+	viper.SetDefault("logger.componentlevels", map[string]string{})
+
+	// Access log sampling, off unless request volume calls for it
+	viper.SetDefault("logger.sampling.enabled", false)
+	viper.SetDefault("logger.sampling.tick", "1s")
+	viper.SetDefault("logger.sampling.initial", 100)
+	viper.SetDefault("logger.sampling.thereafter", 100)
+	viper.SetDefault("logger.sampling.components", []string{"http_middleware"})
+	viper.SetDefault("logger.control.host", "127.0.0.1")
+	viper.SetDefault("logger.control.port", "9091")
+
+	// Telegram Bot API defaults
+	viper.SetDefault("telegram.apiurl", "https://api.telegram.org")
+	viper.SetDefault("telegram.timeout", "10s")
+	viper.SetDefault("telegram.maxretries", 3)
+	viper.SetDefault("telegram.ratelimit", 25)
+	viper.SetDefault("telegram.chatratelimit", 1)
+	viper.SetDefault("telegram.groupratelimit", 0.3)
+	viper.SetDefault("telegram.webappurl", "")
+	viper.SetDefault("telegram.miniapplink", "")
+	viper.SetDefault("telegram.webhook.secret", "")
+	viper.SetDefault("telegram.webhook.url", "")
+	viper.SetDefault("telegram.webhook.dedupebackend", "memory")
+	// Telegram keeps undelivered updates for 24 hours
+	viper.SetDefault("telegram.webhook.dedupettl", "24h")
+
+	// Worker pool defaults
+	viper.SetDefault("workerpool.workers", 10)
+	viper.SetDefault("workerpool.lanes", []map[string]interface{}{
+		{"name": "critical", "priority": 100, "queuesize": 100},
+		{"name": "default", "priority": 50, "queuesize": 100},
+		{"name": "bulk", "priority": 10, "queuesize": 1000, "maxconcurrency": 2, "ratelimit": 20, "rateburst": 20},
+	})
+
+	// Metrics defaults, bound to loopback so metrics stay internal unless
+	// explicitly exposed
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.host", "127.0.0.1")
+	viper.SetDefault("metrics.port", "9090")
+	viper.SetDefault("metrics.path", "/metrics")
+
+	// Tracing defaults, exporting to a local collector when enabled
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.exporter", "otlp")
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+	viper.SetDefault("tracing.insecure", true)
+	viper.SetDefault("tracing.servicename", "renfound")
+	viper.SetDefault("tracing.sampleratio", 1.0)
+
+	// Health check defaults
+	viper.SetDefault("health.checktimeout", "2s")
+	viper.SetDefault("health.cachettl", "5s")
+	viper.SetDefault("health.queuesaturation", 0.9)
+	viper.SetDefault("health.stalltimeout", "2m")
+
+	// Auth defaults, refresh tokens are returned in the response body unless
+	// cookie mode is enabled
+	viper.SetDefault("auth.refreshtokenmode", "body")
+	viper.SetDefault("auth.cookie.name", "refresh_token")
+	viper.SetDefault("auth.cookie.csrfname", "csrf_token")
+	viper.SetDefault("auth.cookie.paths", []string{"/api/v1/auth", "/api/auth"})
+	viper.SetDefault("auth.cookie.secure", true)
+	viper.SetDefault("auth.cookie.samesite", "Strict")
+
+	// Environment defaults
+	viper.SetDefault("env", "development")
+
+	// CORS defaults, origins must be set explicitly in production
+	viper.SetDefault("cors.alloworigins", "")
+	viper.SetDefault("cors.allowtelegram", true)
+	viper.SetDefault("cors.allowcredentials", false)
+
+	// Security header defaults
+	viper.SetDefault("security.hstsmaxage", "0s")
+	viper.SetDefault("security.hstsincludesubdomains", false)
+	viper.SetDefault("security.allowtelegramembedding", true)
+
+	// Proxy defaults, no proxy is trusted unless configured
+	viper.SetDefault("proxy.trustedproxies", []string{})
+
+	// Idempotency defaults
+	viper.SetDefault("idempotency.enabled", true)
+	viper.SetDefault("idempotency.backend", "postgres")
+	viper.SetDefault("idempotency.ttl", "24h")
+	viper.SetDefault("idempotency.lockttl", "1m")
+
+	// Admin defaults, nobody is an admin unless configured
+	viper.SetDefault("admin.telegramids", []int64{})
+
+	// Login history defaults
+	viper.SetDefault("logins.ipv4prefixlength", 24)
+	viper.SetDefault("logins.ipv6prefixlength", 48)
+	viper.SetDefault("logins.newdevicealerts", true)
+
+	// Broadcast defaults
+	viper.SetDefault("broadcast.ratelimit", 20)
+	viper.SetDefault("broadcast.batchsize", 20)
+	viper.SetDefault("broadcast.pollinterval", "5s")
+	viper.SetDefault("broadcast.leasetimeout", "10m")
+
+	// Payment defaults, nothing is for sale unless configured
+	viper.SetDefault("payments.invoicettl", "1h")
+	viper.SetDefault("payments.products", []map[string]interface{}{})
+
+	// Rate limit defaults
+	viper.SetDefault("ratelimit.enabled", true)
+	viper.SetDefault("ratelimit.backend", "memory")
+	viper.SetDefault("ratelimit.policies", map[string]interface{}{
+		RateLimitPolicyDefault: map[string]interface{}{"limit": 120, "period": "1m", "keyby": "ip"},
+		RateLimitPolicyAuth:    map[string]interface{}{"limit": 10, "period": "1m", "keyby": "ip"},
+	})
 }
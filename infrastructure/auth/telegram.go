@@ -9,12 +9,16 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	initdata "github.com/telegram-mini-apps/init-data-golang"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 
 	"renfound_v1/config"
 	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/utils/logctx"
 )
 
+var tracer = otel.Tracer("renfound_v1/infrastructure/auth")
+
 // TelegramAuth handles authentication with Telegram
 type TelegramAuth struct {
 	cfg    *config.AppConfig
@@ -48,16 +52,23 @@ func NewTelegramAuth(cfg *config.AppConfig) *TelegramAuth {
 
 // TelegramUser represents user data from Telegram init data
 type TelegramUser struct {
-	ID        int64  `json:"id"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name,omitempty"`
-	Username  string `json:"username,omitempty"`
-	PhotoURL  string `json:"photo_url,omitempty"`
-	AuthDate  int64  `json:"auth_date"`
+	ID           int64  `json:"id"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	PhotoURL     string `json:"photo_url,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+	AuthDate     int64  `json:"auth_date"`
+	// StartParam is the startapp parameter of the link the Mini App was
+	// opened with, empty when there was none
+	StartParam string `json:"start_param,omitempty"`
 }
 
 // ValidateInitData validates Telegram init data and returns user information
 func (a *TelegramAuth) ValidateInitData(ctx context.Context, initData string) (*TelegramUser, error) {
+	_, span := tracer.Start(ctx, "TelegramAuth.ValidateInitData")
+	defer span.End()
+
 	// Use bot token from config
 	botToken := a.cfg.Config.JWT.AccessSecret // Using JWT access secret as bot token for simplicity
 
@@ -65,48 +76,58 @@ func (a *TelegramAuth) ValidateInitData(ctx context.Context, initData string) (*
 	// Allow a 24 hour expiration time
 	err := initdata.Validate(initData, botToken, 24*time.Hour)
 	if err != nil {
-		a.logger.Error("Failed to validate init data", zap.Error(err))
-		return nil, models.ErrInvalidInitData
+		logctx.Logger(ctx, a.logger).Error("Failed to validate init data", zap.Error(err))
+		switch {
+		case errors.Is(err, initdata.ErrSignInvalid), errors.Is(err, initdata.ErrSignMissing):
+			return nil, models.ErrInvalidSignature
+		case errors.Is(err, initdata.ErrExpired):
+			return nil, models.ErrExpiredToken
+		default:
+			return nil, models.ErrInvalidInitData
+		}
 	}
 
 	// Parse the init data after validation
 	data, err := initdata.Parse(initData)
 	if err != nil {
-		a.logger.Error("Failed to parse init data", zap.Error(err))
+		logctx.Logger(ctx, a.logger).Error("Failed to parse init data", zap.Error(err))
 		return nil, models.ErrInvalidInitData
 	}
 
 	// Extract user information - checking for nil first
 	if data.User.ID == 0 {
-		a.logger.Warn("No user data or invalid user ID in init data")
+		logctx.Logger(ctx, a.logger).Warn("No user data or invalid user ID in init data")
 		return nil, models.ErrInvalidInitData
 	}
 	// Now we know the User struct fields, we can safely access them
 	telegramUser := &TelegramUser{
-		ID:        data.User.ID,
-		FirstName: data.User.FirstName,
-		LastName:  data.User.LastName,
-		Username:  data.User.Username,
-		PhotoURL:  data.User.PhotoURL,
-		AuthDate:  data.AuthDate().Unix(),
+		ID:           data.User.ID,
+		FirstName:    data.User.FirstName,
+		LastName:     data.User.LastName,
+		Username:     data.User.Username,
+		PhotoURL:     data.User.PhotoURL,
+		LanguageCode: data.User.LanguageCode,
+		AuthDate:     data.AuthDate().Unix(),
+		StartParam:   data.StartParam,
 	}
 
-	a.logger.Info("Successfully validated Telegram init data",
+	logctx.Logger(ctx, a.logger).Info("Successfully validated Telegram init data",
 		zap.Int64("telegram_id", telegramUser.ID),
-		zap.String("first_name", telegramUser.FirstName),
 		zap.Int64("auth_date", telegramUser.AuthDate))
 
 	return telegramUser, nil
 }
 
 // GenerateTokens generates JWT tokens for a user
-func (a *TelegramAuth) GenerateTokens(userID uuid.UUID, telegramID int64) (*models.Tokens, error) {
+func (a *TelegramAuth) GenerateTokens(ctx context.Context, userID uuid.UUID, telegramID int64, languageCode string) (*models.Tokens, error) {
+	_, span := tracer.Start(ctx, "TelegramAuth.GenerateTokens")
+	defer span.End()
+
 	// Generate access token
-	accessToken, err := a.generateAccessToken(userID, telegramID)
+	accessToken, err := a.generateAccessToken(userID, telegramID, languageCode)
 	if err != nil {
-		a.logger.Error("Failed to generate access token",
+		logctx.Logger(ctx, a.logger).Error("Failed to generate access token",
 			zap.Error(err),
-			zap.String("user_id", userID.String()),
 			zap.Int64("telegram_id", telegramID))
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
@@ -114,15 +135,11 @@ func (a *TelegramAuth) GenerateTokens(userID uuid.UUID, telegramID int64) (*mode
 	// Generate refresh token
 	refreshToken, err := a.generateRefreshToken(userID)
 	if err != nil {
-		a.logger.Error("Failed to generate refresh token",
-			zap.Error(err),
-			zap.String("user_id", userID.String()))
+		logctx.Logger(ctx, a.logger).Error("Failed to generate refresh token", zap.Error(err))
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	a.logger.Debug("Generated tokens successfully",
-		zap.String("user_id", userID.String()),
-		zap.Int64("telegram_id", telegramID))
+	logctx.Logger(ctx, a.logger).Debug("Generated tokens successfully", zap.Int64("telegram_id", telegramID))
 
 	return &models.Tokens{
 		AccessToken:  accessToken,
@@ -169,20 +186,25 @@ func (a *TelegramAuth) ValidateAccessToken(tokenString string) (*models.Claims,
 
 	telegramID := int64(telegramIDFloat)
 
+	// Optional, tokens issued before it was added don't carry it
+	languageCode, _ := claims["language_code"].(string)
+
 	return &models.Claims{
-		UserID:     userID,
-		TelegramID: telegramID,
+		UserID:       userID,
+		TelegramID:   telegramID,
+		LanguageCode: languageCode,
 	}, nil
 }
 
 // generateAccessToken generates a JWT access token
-func (a *TelegramAuth) generateAccessToken(userID uuid.UUID, telegramID int64) (string, error) {
+func (a *TelegramAuth) generateAccessToken(userID uuid.UUID, telegramID int64, languageCode string) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id":     userID.String(),
-		"telegram_id": telegramID,
-		"exp":         time.Now().Add(a.cfg.Config.JWT.AccessTTL).Unix(),
-		"iat":         time.Now().Unix(),
-		"type":        "access",
+		"user_id":       userID.String(),
+		"telegram_id":   telegramID,
+		"language_code": languageCode,
+		"exp":           time.Now().Add(a.cfg.Config.JWT.AccessTTL).Unix(),
+		"iat":           time.Now().Unix(),
+		"type":          "access",
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -0,0 +1,54 @@
+package dedupe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval controls how often expired keys are evicted
+const sweepInterval = time.Minute
+
+// MemoryStore keeps keys in process memory. It is meant for single node
+// deployments and tests; replicas don't see each other's keys.
+type MemoryStore struct {
+	mu        sync.Mutex
+	keys      map[string]time.Time // expiry per key
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys:      make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) FirstSeen(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if expires, ok := s.keys[key]; ok && now.Before(expires) {
+		return false, nil
+	}
+
+	s.keys[key] = now.Add(ttl)
+	return true, nil
+}
+
+// sweep evicts expired keys, at most once per sweepInterval
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, expires := range s.keys {
+		if !now.Before(expires) {
+			delete(s.keys, key)
+		}
+	}
+}
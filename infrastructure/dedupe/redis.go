@@ -0,0 +1,28 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "dedupe:"
+
+// RedisStore keeps keys in Redis, shared by all replicas
+type RedisStore struct {
+	client goredis.Cmdable
+}
+
+func NewRedisStore(client goredis.Cmdable) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) FirstSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	created, err := s.client.SetNX(ctx, redisKeyPrefix+key, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark key as seen: %w", err)
+	}
+	return created, nil
+}
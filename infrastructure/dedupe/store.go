@@ -0,0 +1,14 @@
+// Package dedupe remembers which events were already seen so events
+// delivered more than once are handled once
+package dedupe
+
+import (
+	"context"
+	"time"
+)
+
+// Store records seen keys
+type Store interface {
+	// FirstSeen marks key as seen for ttl, reporting whether it was new
+	FirstSeen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
@@ -0,0 +1,118 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// sweepInterval controls how often expired records are deleted
+const sweepInterval = 10 * time.Minute
+
+// PostgresStore keeps records in the idempotency_keys table
+type PostgresStore struct {
+	pool *pgxpool.Pool
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{
+		pool:      pool,
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *PostgresStore) Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (*Record, bool, error) {
+	s.sweep(ctx)
+
+	now := time.Now()
+
+	// An expired record is taken over as if the key were new
+	query := `
+		INSERT INTO idempotency_keys (key, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status = NULL, headers = NULL, body = NULL,
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < $3
+		RETURNING key
+	`
+
+	var claimedKey string
+	err := s.pool.QueryRow(ctx, query, key, fingerprint, now, now.Add(lockTTL)).Scan(&claimedKey)
+	if err == nil {
+		return nil, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	var (
+		record  Record
+		status  *int
+		headers []byte
+		body    []byte
+	)
+	query = `SELECT fingerprint, status, headers, body FROM idempotency_keys WHERE key = $1`
+	if err := s.pool.QueryRow(ctx, query, key).Scan(&record.Fingerprint, &status, &headers, &body); err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	if status != nil {
+		record.Response = &Response{Status: *status, Body: body}
+		if len(headers) > 0 {
+			if err := json.Unmarshal(headers, &record.Response.Headers); err != nil {
+				return nil, false, fmt.Errorf("failed to decode idempotency headers: %w", err)
+			}
+		}
+	}
+
+	return &record, false, nil
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, key, fingerprint string, response *Response, ttl time.Duration) error {
+	headers, err := json.Marshal(response.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency headers: %w", err)
+	}
+
+	query := `
+		UPDATE idempotency_keys
+		SET status = $3, headers = $4, body = $5, expires_at = $6
+		WHERE key = $1 AND fingerprint = $2
+	`
+
+	if _, err := s.pool.Exec(ctx, query, key, fingerprint, response.Status, headers, response.Body, time.Now().Add(ttl)); err != nil {
+		return fmt.Errorf("failed to store idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	if _, err := s.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND status IS NULL`, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// sweep deletes expired records at most once per sweepInterval. Failures
+// are ignored, expired records are taken over by Reserve anyway.
+func (s *PostgresStore) sweep(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = time.Now()
+	s.mu.Unlock()
+
+	_, _ = s.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at < $1`, time.Now())
+}
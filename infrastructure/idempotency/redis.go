@@ -0,0 +1,75 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "idempotency:"
+
+// RedisStore keeps records in Redis, shared by all replicas
+type RedisStore struct {
+	client goredis.Cmdable
+}
+
+func NewRedisStore(client goredis.Cmdable) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (*Record, bool, error) {
+	pending, err := json.Marshal(Record{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+
+	// The key may expire between SET NX and GET, so try twice
+	for attempt := 0; attempt < 2; attempt++ {
+		claimed, err := s.client.SetNX(ctx, redisKeyPrefix+key, pending, lockTTL).Result()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+		if claimed {
+			return nil, true, nil
+		}
+
+		raw, err := s.client.Get(ctx, redisKeyPrefix+key).Bytes()
+		if errors.Is(err, goredis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get idempotency record: %w", err)
+		}
+
+		var record Record
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return nil, false, fmt.Errorf("failed to decode idempotency record: %w", err)
+		}
+		return &record, false, nil
+	}
+
+	return nil, false, fmt.Errorf("failed to reserve idempotency key: key churned")
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key, fingerprint string, response *Response, ttl time.Duration) error {
+	raw, err := json.Marshal(Record{Fingerprint: fingerprint, Response: response})
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+
+	if err := s.client.Set(ctx, redisKeyPrefix+key, raw, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, redisKeyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
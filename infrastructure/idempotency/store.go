@@ -0,0 +1,33 @@
+// Package idempotency stores the responses of requests sent with an
+// Idempotency-Key so retries can be answered without repeating side effects
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+// Response is a stored HTTP response
+type Response struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    []byte              `json:"body"`
+}
+
+// Record is the state of a key. Response is nil while the request that
+// claimed the key is still running.
+type Record struct {
+	Fingerprint string    `json:"fingerprint"`
+	Response    *Response `json:"response,omitempty"`
+}
+
+// Store persists idempotency records. Keys are already scoped to the caller.
+type Store interface {
+	// Reserve claims key for a new request for lockTTL. If the key is already
+	// known, its record is returned and claimed is false.
+	Reserve(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (record *Record, claimed bool, err error)
+	// Complete stores the response of the request that claimed key
+	Complete(ctx context.Context, key, fingerprint string, response *Response, ttl time.Duration) error
+	// Release forgets key so the request can be retried
+	Release(ctx context.Context, key string) error
+}
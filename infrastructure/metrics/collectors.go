@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"renfound_v1/internal/utils/async"
+)
+
+// dbPoolCollector reads pgxpool statistics on every scrape
+type dbPoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	acquiredConns        *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	idleConns            *prometheus.Desc
+	maxConns             *prometheus.Desc
+	totalConns           *prometheus.Desc
+	newConnsCount        *prometheus.Desc
+	lifetimeDestroyCount *prometheus.Desc
+	idleDestroyCount     *prometheus.Desc
+}
+
+func newDBPoolCollector(pool *pgxpool.Pool) *dbPoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return &dbPoolCollector{
+		pool:                 pool,
+		acquireCount:         desc("acquire_total", "Cumulative count of successful connection acquires."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		acquiredConns:        desc("acquired_connections", "Number of currently acquired connections."),
+		canceledAcquireCount: desc("canceled_acquire_total", "Cumulative count of acquires canceled by a context."),
+		constructingConns:    desc("constructing_connections", "Number of connections being constructed."),
+		emptyAcquireCount:    desc("empty_acquire_total", "Cumulative count of acquires that waited for a connection."),
+		idleConns:            desc("idle_connections", "Number of currently idle connections."),
+		maxConns:             desc("max_connections", "Maximum size of the pool."),
+		totalConns:           desc("total_connections", "Total number of connections in the pool."),
+		newConnsCount:        desc("new_connections_total", "Cumulative count of new connections opened."),
+		lifetimeDestroyCount: desc("max_lifetime_destroy_total", "Cumulative count of connections closed due to max lifetime."),
+		idleDestroyCount:     desc("max_idle_destroy_total", "Cumulative count of connections closed due to max idle time."),
+	}
+}
+
+func (c *dbPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *dbPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.newConnsCount, prometheus.CounterValue, float64(stat.NewConnsCount()))
+	ch <- prometheus.MustNewConstMetric(c.lifetimeDestroyCount, prometheus.CounterValue, float64(stat.MaxLifetimeDestroyCount()))
+	ch <- prometheus.MustNewConstMetric(c.idleDestroyCount, prometheus.CounterValue, float64(stat.MaxIdleDestroyCount()))
+}
+
+// workerPoolCollector reads worker pool statistics on every scrape
+type workerPoolCollector struct {
+	pool *async.WorkerPool
+
+	workers       *prometheus.Desc
+	activeWorkers *prometheus.Desc
+	queueDepth    *prometheus.Desc
+	queueCapacity *prometheus.Desc
+	running       *prometheus.Desc
+	tasks         *prometheus.Desc
+}
+
+func newWorkerPoolCollector(pool *async.WorkerPool) *workerPoolCollector {
+	fqName := func(name string) string {
+		return prometheus.BuildFQName(namespace, "worker_pool", name)
+	}
+
+	return &workerPoolCollector{
+		pool:          pool,
+		workers:       prometheus.NewDesc(fqName("workers"), "Number of workers in the pool.", nil, nil),
+		activeWorkers: prometheus.NewDesc(fqName("active_workers"), "Number of workers currently running a task.", nil, nil),
+		queueDepth:    prometheus.NewDesc(fqName("queue_depth"), "Number of queued tasks per lane.", []string{"lane"}, nil),
+		queueCapacity: prometheus.NewDesc(fqName("queue_capacity"), "Queue capacity per lane.", []string{"lane"}, nil),
+		running:       prometheus.NewDesc(fqName("running_tasks"), "Number of running tasks per lane.", []string{"lane"}, nil),
+		tasks:         prometheus.NewDesc(fqName("tasks_total"), "Tasks by lane and final state.", []string{"lane", "state"}, nil),
+	}
+}
+
+func (c *workerPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *workerPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.pool.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.workers, prometheus.GaugeValue, float64(stats.Workers))
+	ch <- prometheus.MustNewConstMetric(c.activeWorkers, prometheus.GaugeValue, float64(stats.ActiveWorkers))
+
+	for name, lane := range stats.Lanes {
+		ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(lane.QueueDepth), name)
+		ch <- prometheus.MustNewConstMetric(c.queueCapacity, prometheus.GaugeValue, float64(lane.QueueCapacity), name)
+		ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, float64(lane.Running), name)
+		ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.CounterValue, float64(lane.Completed), name, "completed")
+		ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.CounterValue, float64(lane.Dropped), name, "dropped")
+		ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.CounterValue, float64(lane.Panicked), name, "panicked")
+		ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.CounterValue, float64(lane.Abandoned), name, "abandoned")
+	}
+}
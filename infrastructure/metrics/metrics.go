@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"renfound_v1/internal/utils/async"
+)
+
+const namespace = "renfound"
+
+// Auth outcomes recorded by ObserveAuth
+const (
+	AuthSuccess          = "success"
+	AuthInvalidSignature = "invalid_signature"
+	AuthInvalidInitData  = "invalid_init_data"
+	AuthInvalidToken     = "invalid_token"
+	AuthExpired          = "expired"
+	AuthReplay           = "replay"
+	AuthInternalError    = "internal_error"
+)
+
+// Metrics holds the application Prometheus collectors. A nil *Metrics is
+// valid and records nothing, so metrics can be disabled without nil checks.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	authOutcomes *prometheus.CounterVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		authOutcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "auth",
+			Name:      "attempts_total",
+			Help:      "Authentication attempts by flow and outcome.",
+		}, []string{"flow", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.authOutcomes,
+	)
+
+	return m
+}
+
+// ObserveHTTPRequest records a finished HTTP request. Route must be the
+// route pattern, not the raw path, to keep label cardinality bounded.
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	statusLabel := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, statusLabel).Inc()
+	m.httpDuration.WithLabelValues(method, route, statusLabel).Observe(duration.Seconds())
+}
+
+// ObserveAuth records the outcome of an authentication flow
+func (m *Metrics) ObserveAuth(flow, outcome string) {
+	if m == nil {
+		return
+	}
+
+	m.authOutcomes.WithLabelValues(flow, outcome).Inc()
+}
+
+// RegisterDBPool exports connection pool statistics
+func (m *Metrics) RegisterDBPool(pool *pgxpool.Pool) {
+	if m == nil {
+		return
+	}
+
+	m.registry.MustRegister(newDBPoolCollector(pool))
+}
+
+// RegisterWorkerPool exports worker pool queue and task statistics
+func (m *Metrics) RegisterWorkerPool(pool *async.WorkerPool) {
+	if m == nil {
+		return
+	}
+
+	m.registry.MustRegister(newWorkerPoolCollector(pool))
+}
+
+// Handler returns the HTTP handler serving the metrics in Prometheus format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/utils/logctx"
+)
+
+type AuditRepositoryImpl struct {
+	db     *Database
+	logger *zap.Logger
+}
+
+func NewAuditRepository(db *Database, logger *zap.Logger) repository.AuditRepository {
+	return &AuditRepositoryImpl{
+		db:     db,
+		logger: logger.With(zap.String("component", "audit_repository")),
+	}
+}
+
+func (r AuditRepositoryImpl) Create(ctx context.Context, event *models.AuditEvent) error {
+	ctx, span := tracer.Start(ctx, "AuditRepository.Create")
+	defer span.End()
+
+	query := `
+		INSERT INTO audit_events (id, actor_id, action, target_id, outcome, reason, ip_address, user_agent, request_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10)
+	`
+
+	_, err := r.db.Pool.Exec(ctx, query,
+		event.ID,
+		event.ActorID,
+		event.Action,
+		event.TargetID,
+		event.Outcome,
+		event.Reason,
+		event.IPAddress,
+		event.UserAgent,
+		event.RequestID,
+		event.CreatedAt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to create audit event", zap.Error(err), zap.String("action", event.Action))
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+
+	return nil
+}
+
+func (r AuditRepositoryImpl) List(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, error) {
+	ctx, span := tracer.Start(ctx, "AuditRepository.List")
+	defer span.End()
+
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, values ...interface{}) {
+		for _, v := range values {
+			args = append(args, v)
+			condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(args)), 1)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if filter.ActorID != nil {
+		where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.TargetID != nil {
+		where("target_id = ?", *filter.TargetID)
+	}
+	if filter.Action != "" {
+		where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		where("outcome = ?", filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < ?", filter.To)
+	}
+	if filter.Before != nil {
+		where("(created_at, id) < (?, ?)", filter.Before.CreatedAt, filter.Before.ID)
+	}
+
+	query := `
+		SELECT id, actor_id, action, target_id, outcome, COALESCE(reason, ''), COALESCE(ip_address, ''),
+			COALESCE(user_agent, ''), COALESCE(request_id, ''), created_at
+		FROM audit_events`
+	if len(conditions) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf("\n\t\tORDER BY created_at DESC, id DESC\n\t\tLIMIT $%d", len(args))
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list audit events", zap.Error(err))
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*models.AuditEvent, 0, filter.Limit)
+	for rows.Next() {
+		event := &models.AuditEvent{}
+		if err := rows.Scan(
+			&event.ID,
+			&event.ActorID,
+			&event.Action,
+			&event.TargetID,
+			&event.Outcome,
+			&event.Reason,
+			&event.IPAddress,
+			&event.UserAgent,
+			&event.RequestID,
+			&event.CreatedAt,
+		); err != nil {
+			logctx.Logger(ctx, r.logger).Error("Failed to scan audit event", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list audit events", zap.Error(err))
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	return events, nil
+}
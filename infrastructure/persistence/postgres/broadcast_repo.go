@@ -0,0 +1,410 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/utils/logctx"
+)
+
+// broadcastSelect selects broadcasts with their recipient counts, to be
+// completed with a WHERE clause and GROUP BY b.id
+const broadcastSelect = `
+		SELECT b.id, b.text, b.segment, b.status, b.created_by, b.created_at, b.updated_at, b.completed_at,
+			COUNT(r.user_id),
+			COUNT(r.user_id) FILTER (WHERE r.status IN ('pending', 'sending')),
+			COUNT(r.user_id) FILTER (WHERE r.status = 'sent'),
+			COUNT(r.user_id) FILTER (WHERE r.status = 'failed'),
+			COUNT(r.user_id) FILTER (WHERE r.status = 'skipped'),
+			COUNT(r.user_id) FILTER (WHERE r.status = 'cancelled')
+		FROM broadcasts b
+		LEFT JOIN broadcast_recipients r ON r.broadcast_id = b.id`
+
+type BroadcastRepositoryImpl struct {
+	db     *Database
+	logger *zap.Logger
+}
+
+func NewBroadcastRepository(db *Database, logger *zap.Logger) repository.BroadcastRepository {
+	return &BroadcastRepositoryImpl{
+		db:     db,
+		logger: logger.With(zap.String("component", "broadcast_repository")),
+	}
+}
+
+func (r BroadcastRepositoryImpl) Create(ctx context.Context, broadcast *models.Broadcast) error {
+	ctx, span := tracer.Start(ctx, "BroadcastRepository.Create")
+	defer span.End()
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO broadcasts (id, text, segment, status, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = tx.Exec(ctx, query,
+		broadcast.ID,
+		broadcast.Text,
+		broadcast.Segment,
+		broadcast.Status,
+		broadcast.CreatedBy,
+		broadcast.CreatedAt,
+		broadcast.UpdatedAt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to create broadcast", zap.Error(err))
+		return fmt.Errorf("failed to create broadcast: %w", err)
+	}
+
+	query = `
+		INSERT INTO broadcast_recipients (broadcast_id, user_id, status)
+		SELECT $1, u.id, CASE WHEN u.bot_reachable THEN 'pending' ELSE 'skipped' END
+		FROM users u
+		WHERE ($2::text = '' OR u.language_code = $2::text)
+			AND ($3::int = 0 OR EXISTS (
+				SELECT 1 FROM login_history l
+				WHERE l.user_id = u.id AND l.created_at >= NOW() - make_interval(days => $3::int)))
+	`
+
+	if _, err := tx.Exec(ctx, query, broadcast.ID, broadcast.Segment.LanguageCode, broadcast.Segment.ActiveWithinDays); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to add broadcast recipients", zap.Error(err))
+		return fmt.Errorf("failed to add broadcast recipients: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to commit broadcast", zap.Error(err))
+		return fmt.Errorf("failed to commit broadcast: %w", err)
+	}
+
+	return nil
+}
+
+func (r BroadcastRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*models.Broadcast, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastRepository.GetByID")
+	defer span.End()
+
+	query := broadcastSelect + `
+		WHERE b.id = $1
+		GROUP BY b.id`
+
+	broadcast, err := scanBroadcast(r.db.Pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrBroadcastNotFound
+		}
+		logctx.Logger(ctx, r.logger).Error("Failed to get broadcast", zap.Error(err), zap.String("broadcast_id", id.String()))
+		return nil, fmt.Errorf("failed to get broadcast: %w", err)
+	}
+
+	return broadcast, nil
+}
+
+func (r BroadcastRepositoryImpl) GetStatus(ctx context.Context, id uuid.UUID) (string, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastRepository.GetStatus")
+	defer span.End()
+
+	var status string
+	if err := r.db.Pool.QueryRow(ctx, `SELECT status FROM broadcasts WHERE id = $1`, id).Scan(&status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", models.ErrBroadcastNotFound
+		}
+		logctx.Logger(ctx, r.logger).Error("Failed to get broadcast status", zap.Error(err), zap.String("broadcast_id", id.String()))
+		return "", fmt.Errorf("failed to get broadcast status: %w", err)
+	}
+
+	return status, nil
+}
+
+func (r BroadcastRepositoryImpl) List(ctx context.Context, limit int) ([]*models.Broadcast, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastRepository.List")
+	defer span.End()
+
+	query := broadcastSelect + `
+		GROUP BY b.id
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $1`
+
+	rows, err := r.db.Pool.Query(ctx, query, limit)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list broadcasts", zap.Error(err))
+		return nil, fmt.Errorf("failed to list broadcasts: %w", err)
+	}
+	defer rows.Close()
+
+	broadcasts := make([]*models.Broadcast, 0, limit)
+	for rows.Next() {
+		broadcast, err := scanBroadcast(rows)
+		if err != nil {
+			logctx.Logger(ctx, r.logger).Error("Failed to scan broadcast", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan broadcast: %w", err)
+		}
+		broadcasts = append(broadcasts, broadcast)
+	}
+	if err := rows.Err(); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list broadcasts", zap.Error(err))
+		return nil, fmt.Errorf("failed to list broadcasts: %w", err)
+	}
+
+	return broadcasts, nil
+}
+
+func (r BroadcastRepositoryImpl) SetStatus(ctx context.Context, id uuid.UUID, from []string, to string) error {
+	ctx, span := tracer.Start(ctx, "BroadcastRepository.SetStatus")
+	defer span.End()
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE broadcasts
+		SET status = $2, updated_at = NOW()
+		WHERE id = $1 AND status = ANY($3)
+	`
+
+	result, err := tx.Exec(ctx, query, id, to, from)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to set broadcast status", zap.Error(err), zap.String("broadcast_id", id.String()))
+		return fmt.Errorf("failed to set broadcast status: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM broadcasts WHERE id = $1)`, id).Scan(&exists); err != nil {
+			logctx.Logger(ctx, r.logger).Error("Failed to check broadcast", zap.Error(err), zap.String("broadcast_id", id.String()))
+			return fmt.Errorf("failed to check broadcast: %w", err)
+		}
+		if !exists {
+			return models.ErrBroadcastNotFound
+		}
+		return models.ErrBroadcastStatusConflict
+	}
+
+	// Recipients still leased are left to their instance, which records
+	// the outcome or releases them as cancelled
+	if to == models.BroadcastStatusCancelled {
+		query = `
+			UPDATE broadcast_recipients
+			SET status = 'cancelled', locked_until = NULL, updated_at = NOW()
+			WHERE broadcast_id = $1
+				AND (status = 'pending' OR (status = 'sending' AND locked_until < NOW()))
+		`
+
+		if _, err := tx.Exec(ctx, query, id); err != nil {
+			logctx.Logger(ctx, r.logger).Error("Failed to cancel broadcast recipients", zap.Error(err), zap.String("broadcast_id", id.String()))
+			return fmt.Errorf("failed to cancel broadcast recipients: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to commit broadcast status", zap.Error(err))
+		return fmt.Errorf("failed to commit broadcast status: %w", err)
+	}
+
+	return nil
+}
+
+func (r BroadcastRepositoryImpl) Complete(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "BroadcastRepository.Complete")
+	defer span.End()
+
+	// Recipients of cancelled broadcasts whose instance stopped before
+	// recording them are never claimed again
+	query := `
+		UPDATE broadcast_recipients r
+		SET status = 'cancelled', locked_until = NULL, updated_at = NOW()
+		FROM broadcasts b
+		WHERE b.id = r.broadcast_id AND b.status = 'cancelled'
+			AND r.status = 'sending' AND r.locked_until < NOW()
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to cancel abandoned broadcast recipients", zap.Error(err))
+		return fmt.Errorf("failed to cancel abandoned broadcast recipients: %w", err)
+	}
+
+	query = `
+		UPDATE broadcasts b
+		SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+		WHERE b.status = 'running' AND NOT EXISTS (
+			SELECT 1 FROM broadcast_recipients r
+			WHERE r.broadcast_id = b.id AND r.status IN ('pending', 'sending'))
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to complete broadcasts", zap.Error(err))
+		return fmt.Errorf("failed to complete broadcasts: %w", err)
+	}
+
+	return nil
+}
+
+func (r BroadcastRepositoryImpl) ListRecipients(ctx context.Context, broadcastID uuid.UUID, status string, after *uuid.UUID, limit int) ([]*models.BroadcastRecipient, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastRepository.ListRecipients")
+	defer span.End()
+
+	query := `
+		SELECT user_id, status, COALESCE(error, ''), sent_at, updated_at
+		FROM broadcast_recipients
+		WHERE broadcast_id = $1
+			AND ($2::text = '' OR status = $2::text)
+			AND ($3::uuid IS NULL OR user_id > $3::uuid)
+		ORDER BY user_id
+		LIMIT $4
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, broadcastID, status, after, limit)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list broadcast recipients", zap.Error(err), zap.String("broadcast_id", broadcastID.String()))
+		return nil, fmt.Errorf("failed to list broadcast recipients: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := make([]*models.BroadcastRecipient, 0, limit)
+	for rows.Next() {
+		recipient := &models.BroadcastRecipient{}
+		if err := rows.Scan(
+			&recipient.UserID,
+			&recipient.Status,
+			&recipient.Error,
+			&recipient.SentAt,
+			&recipient.UpdatedAt,
+		); err != nil {
+			logctx.Logger(ctx, r.logger).Error("Failed to scan broadcast recipient", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan broadcast recipient: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	if err := rows.Err(); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list broadcast recipients", zap.Error(err))
+		return nil, fmt.Errorf("failed to list broadcast recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+func (r BroadcastRepositoryImpl) ClaimRecipients(ctx context.Context, limit int, lease time.Duration) ([]*models.BroadcastDelivery, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastRepository.ClaimRecipients")
+	defer span.End()
+
+	// SKIP LOCKED lets several instances claim at the same time without
+	// waiting on each other or claiming the same recipients
+	query := `
+		WITH claimable AS (
+			SELECT r.broadcast_id, r.user_id
+			FROM broadcast_recipients r
+			JOIN broadcasts b ON b.id = r.broadcast_id
+			WHERE b.status = 'running'
+				AND (r.status = 'pending' OR (r.status = 'sending' AND r.locked_until < NOW()))
+			ORDER BY b.created_at, r.user_id
+			LIMIT $1
+			FOR UPDATE OF r SKIP LOCKED
+		)
+		UPDATE broadcast_recipients r
+		SET status = 'sending', locked_until = NOW() + make_interval(secs => $2), updated_at = NOW()
+		FROM claimable c, broadcasts b, users u
+		WHERE r.broadcast_id = c.broadcast_id AND r.user_id = c.user_id
+			AND b.id = r.broadcast_id AND u.id = r.user_id
+		RETURNING r.broadcast_id, r.user_id, u.telegram_id, u.bot_reachable, b.text
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to claim broadcast recipients", zap.Error(err))
+		return nil, fmt.Errorf("failed to claim broadcast recipients: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.BroadcastDelivery, 0, limit)
+	for rows.Next() {
+		delivery := &models.BroadcastDelivery{}
+		if err := rows.Scan(
+			&delivery.BroadcastID,
+			&delivery.UserID,
+			&delivery.TelegramID,
+			&delivery.BotReachable,
+			&delivery.Text,
+		); err != nil {
+			logctx.Logger(ctx, r.logger).Error("Failed to scan broadcast delivery", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan broadcast delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to claim broadcast recipients", zap.Error(err))
+		return nil, fmt.Errorf("failed to claim broadcast recipients: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r BroadcastRepositoryImpl) SetRecipientStatus(ctx context.Context, broadcastID, userID uuid.UUID, status, errorMessage string) error {
+	ctx, span := tracer.Start(ctx, "BroadcastRepository.SetRecipientStatus")
+	defer span.End()
+
+	// Releasing a recipient of a cancelled broadcast cancels it, it would
+	// stay pending forever otherwise
+	query := `
+		UPDATE broadcast_recipients
+		SET status = CASE
+				WHEN $3::text = 'pending' AND EXISTS (
+					SELECT 1 FROM broadcasts b WHERE b.id = $1 AND b.status = 'cancelled')
+				THEN 'cancelled'
+				ELSE $3::text
+			END,
+			error = NULLIF($4, ''),
+			locked_until = NULL,
+			sent_at = CASE WHEN $3::text = 'sent' THEN NOW() END,
+			updated_at = NOW()
+		WHERE broadcast_id = $1 AND user_id = $2 AND status = 'sending'
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, broadcastID, userID, status, errorMessage); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to set broadcast recipient status", zap.Error(err),
+			zap.String("broadcast_id", broadcastID.String()),
+			zap.String("user_id", userID.String()))
+		return fmt.Errorf("failed to set broadcast recipient status: %w", err)
+	}
+
+	return nil
+}
+
+// scanBroadcast scans a row selected with broadcastSelect
+func scanBroadcast(row pgx.Row) (*models.Broadcast, error) {
+	broadcast := &models.Broadcast{}
+	err := row.Scan(
+		&broadcast.ID,
+		&broadcast.Text,
+		&broadcast.Segment,
+		&broadcast.Status,
+		&broadcast.CreatedBy,
+		&broadcast.CreatedAt,
+		&broadcast.UpdatedAt,
+		&broadcast.CompletedAt,
+		&broadcast.Stats.Total,
+		&broadcast.Stats.Pending,
+		&broadcast.Stats.Sent,
+		&broadcast.Stats.Failed,
+		&broadcast.Stats.Skipped,
+		&broadcast.Stats.Cancelled,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return broadcast, nil
+}
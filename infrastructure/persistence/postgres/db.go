@@ -2,7 +2,9 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 	"renfound_v1/config"
@@ -30,6 +32,9 @@ func NewDatabase(cfg *config.AppConfig) (*Database, error) {
 	poolConfig.MaxConnIdleTime = 30 * time.Minute
 	poolConfig.HealthCheckPeriod = time.Minute
 
+	// Trace every query as a child span of the calling context
+	poolConfig.ConnConfig.Tracer = queryTracer{}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -58,3 +63,19 @@ func (db *Database) Close() {
 		db.logger.Info("Db connection pool closed")
 	}
 }
+
+// MigrationVersion returns the schema version recorded by golang-migrate
+func (db *Database) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var version int64
+	var dirty bool
+
+	err := db.Pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to read migration version: %w", err)
+	}
+
+	return uint(version), dirty, nil
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/utils/logctx"
+)
+
+type LoginRepositoryImpl struct {
+	db     *Database
+	logger *zap.Logger
+}
+
+func NewLoginRepository(db *Database, logger *zap.Logger) repository.LoginRepository {
+	return &LoginRepositoryImpl{
+		db:     db,
+		logger: logger.With(zap.String("component", "login_repository")),
+	}
+}
+
+func (r LoginRepositoryImpl) TouchDevice(ctx context.Context, device *models.Device) (bool, error) {
+	ctx, span := tracer.Start(ctx, "LoginRepository.TouchDevice")
+	defer span.End()
+
+	// xmax is only zero for freshly inserted rows, which tells new devices
+	// apart from updated ones in a single statement
+	query := `
+		INSERT INTO user_devices (user_id, fingerprint, user_agent, ip_address, first_seen_at, last_seen_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6)
+		ON CONFLICT (user_id, fingerprint) DO UPDATE
+		SET user_agent = EXCLUDED.user_agent, ip_address = EXCLUDED.ip_address, last_seen_at = EXCLUDED.last_seen_at
+		RETURNING xmax = 0
+	`
+
+	var created bool
+	err := r.db.Pool.QueryRow(ctx, query,
+		device.UserID,
+		device.Fingerprint,
+		device.UserAgent,
+		device.IPAddress,
+		device.FirstSeenAt,
+		device.LastSeenAt).Scan(&created)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to store device", zap.Error(err))
+		return false, fmt.Errorf("failed to store device: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r LoginRepositoryImpl) CountDevices(ctx context.Context, userID uuid.UUID) (int, error) {
+	ctx, span := tracer.Start(ctx, "LoginRepository.CountDevices")
+	defer span.End()
+
+	query := `SELECT COUNT(*) FROM user_devices WHERE user_id = $1`
+
+	var count int
+	if err := r.db.Pool.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to count devices", zap.Error(err))
+		return 0, fmt.Errorf("failed to count devices: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r LoginRepositoryImpl) CreateLogin(ctx context.Context, login *models.Login) error {
+	ctx, span := tracer.Start(ctx, "LoginRepository.CreateLogin")
+	defer span.End()
+
+	query := `
+		INSERT INTO login_history (id, user_id, method, device_id, user_agent, ip_address, new_device, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+	`
+
+	_, err := r.db.Pool.Exec(ctx, query,
+		login.ID,
+		login.UserID,
+		login.Method,
+		login.DeviceID,
+		login.UserAgent,
+		login.IPAddress,
+		login.NewDevice,
+		login.CreatedAt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to create login", zap.Error(err), zap.String("method", login.Method))
+		return fmt.Errorf("failed to create login: %w", err)
+	}
+
+	return nil
+}
+
+func (r LoginRepositoryImpl) ListLogins(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Login, error) {
+	ctx, span := tracer.Start(ctx, "LoginRepository.ListLogins")
+	defer span.End()
+
+	query := `
+		SELECT id, user_id, method, device_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), new_device, created_at
+		FROM login_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, limit)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list logins", zap.Error(err))
+		return nil, fmt.Errorf("failed to list logins: %w", err)
+	}
+	defer rows.Close()
+
+	logins := make([]*models.Login, 0, limit)
+	for rows.Next() {
+		login := &models.Login{}
+		if err := rows.Scan(
+			&login.ID,
+			&login.UserID,
+			&login.Method,
+			&login.DeviceID,
+			&login.UserAgent,
+			&login.IPAddress,
+			&login.NewDevice,
+			&login.CreatedAt,
+		); err != nil {
+			logctx.Logger(ctx, r.logger).Error("Failed to scan login", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan login: %w", err)
+		}
+		logins = append(logins, login)
+	}
+	if err := rows.Err(); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list logins", zap.Error(err))
+		return nil, fmt.Errorf("failed to list logins: %w", err)
+	}
+
+	return logins, nil
+}
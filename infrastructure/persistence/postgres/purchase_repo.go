@@ -0,0 +1,253 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/utils/logctx"
+)
+
+// purchaseColumns are the columns scanned by scanPurchase
+const purchaseColumns = `
+	id, user_id, telegram_id, product_id, entitlement, amount, currency, duration_seconds, status,
+	COALESCE(charge_id, ''), created_at, paid_at, expires_at, refunded_at
+`
+
+type PurchaseRepositoryImpl struct {
+	db     *Database
+	logger *zap.Logger
+}
+
+func NewPurchaseRepository(db *Database, logger *zap.Logger) repository.PurchaseRepository {
+	return &PurchaseRepositoryImpl{
+		db:     db,
+		logger: logger.With(zap.String("component", "purchase_repository")),
+	}
+}
+
+func (r PurchaseRepositoryImpl) Create(ctx context.Context, purchase *models.Purchase) error {
+	ctx, span := tracer.Start(ctx, "PurchaseRepository.Create")
+	defer span.End()
+
+	query := `
+		INSERT INTO purchases (id, user_id, telegram_id, product_id, entitlement, amount, currency, duration_seconds, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.Pool.Exec(ctx, query,
+		purchase.ID,
+		purchase.UserID,
+		purchase.TelegramID,
+		purchase.ProductID,
+		purchase.Entitlement,
+		purchase.Amount,
+		purchase.Currency,
+		purchase.DurationSeconds,
+		purchase.Status,
+		purchase.CreatedAt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to create purchase", zap.Error(err), zap.String("purchase_id", purchase.ID.String()))
+		return fmt.Errorf("failed to create purchase: %w", err)
+	}
+
+	return nil
+}
+
+func (r PurchaseRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*models.Purchase, error) {
+	ctx, span := tracer.Start(ctx, "PurchaseRepository.GetByID")
+	defer span.End()
+
+	query := `SELECT ` + purchaseColumns + ` FROM purchases WHERE id = $1`
+
+	purchase, err := scanPurchase(r.db.Pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrPurchaseNotFound
+		}
+		logctx.Logger(ctx, r.logger).Error("Failed to get purchase", zap.Error(err), zap.String("purchase_id", id.String()))
+		return nil, fmt.Errorf("failed to get purchase: %w", err)
+	}
+
+	return purchase, nil
+}
+
+func (r PurchaseRepositoryImpl) GetByChargeID(ctx context.Context, chargeID string) (*models.Purchase, error) {
+	ctx, span := tracer.Start(ctx, "PurchaseRepository.GetByChargeID")
+	defer span.End()
+
+	query := `SELECT ` + purchaseColumns + ` FROM purchases WHERE charge_id = $1`
+
+	purchase, err := scanPurchase(r.db.Pool.QueryRow(ctx, query, chargeID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrPurchaseNotFound
+		}
+		logctx.Logger(ctx, r.logger).Error("Failed to get purchase by charge", zap.Error(err), zap.String("charge_id", chargeID))
+		return nil, fmt.Errorf("failed to get purchase by charge: %w", err)
+	}
+
+	return purchase, nil
+}
+
+func (r PurchaseRepositoryImpl) MarkPaid(ctx context.Context, id uuid.UUID, chargeID string, paidAt time.Time) (*models.Purchase, error) {
+	ctx, span := tracer.Start(ctx, "PurchaseRepository.MarkPaid")
+	defer span.End()
+
+	// The entitlement is extended from the latest expiry of the same one
+	query := `
+		UPDATE purchases p
+		SET status = 'paid',
+			charge_id = $2,
+			paid_at = $3,
+			expires_at = CASE WHEN p.duration_seconds = 0 THEN NULL ELSE GREATEST($3, COALESCE((
+				SELECT MAX(o.expires_at)
+				FROM purchases o
+				WHERE o.telegram_id = p.telegram_id AND o.entitlement = p.entitlement AND o.status = 'paid'
+			), $3)) + p.duration_seconds * INTERVAL '1 second' END
+		WHERE id = $1 AND status = 'pending'
+		RETURNING ` + purchaseColumns
+
+	purchase, err := scanPurchase(r.db.Pool.QueryRow(ctx, query, id, chargeID, paidAt))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if _, err := r.GetByID(ctx, id); err != nil {
+				return nil, err
+			}
+			return nil, models.ErrConflict
+		}
+		logctx.Logger(ctx, r.logger).Error("Failed to mark purchase paid", zap.Error(err), zap.String("purchase_id", id.String()))
+		return nil, fmt.Errorf("failed to mark purchase paid: %w", err)
+	}
+
+	return purchase, nil
+}
+
+func (r PurchaseRepositoryImpl) MarkRefunded(ctx context.Context, id uuid.UUID, refundedAt time.Time) (*models.Purchase, error) {
+	ctx, span := tracer.Start(ctx, "PurchaseRepository.MarkRefunded")
+	defer span.End()
+
+	query := `
+		UPDATE purchases
+		SET status = 'refunded', refunded_at = $2
+		WHERE id = $1 AND status = 'paid'
+		RETURNING ` + purchaseColumns
+
+	purchase, err := scanPurchase(r.db.Pool.QueryRow(ctx, query, id, refundedAt))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if _, err := r.GetByID(ctx, id); err != nil {
+				return nil, err
+			}
+			return nil, models.ErrPurchaseNotRefundable
+		}
+		logctx.Logger(ctx, r.logger).Error("Failed to mark purchase refunded", zap.Error(err), zap.String("purchase_id", id.String()))
+		return nil, fmt.Errorf("failed to mark purchase refunded: %w", err)
+	}
+
+	return purchase, nil
+}
+
+func (r PurchaseRepositoryImpl) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Purchase, error) {
+	ctx, span := tracer.Start(ctx, "PurchaseRepository.ListByUser")
+	defer span.End()
+
+	query := `
+		SELECT ` + purchaseColumns + `
+		FROM purchases
+		WHERE telegram_id = (SELECT telegram_id FROM users WHERE id = $1) AND status <> 'pending'
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, limit)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list purchases", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to list purchases: %w", err)
+	}
+	defer rows.Close()
+
+	purchases := make([]*models.Purchase, 0, limit)
+	for rows.Next() {
+		purchase, err := scanPurchase(rows)
+		if err != nil {
+			logctx.Logger(ctx, r.logger).Error("Failed to scan purchase", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan purchase: %w", err)
+		}
+		purchases = append(purchases, purchase)
+	}
+	if err := rows.Err(); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list purchases", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to list purchases: %w", err)
+	}
+
+	return purchases, nil
+}
+
+func (r PurchaseRepositoryImpl) ListEntitlements(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Entitlement, error) {
+	ctx, span := tracer.Start(ctx, "PurchaseRepository.ListEntitlements")
+	defer span.End()
+
+	query := `
+		SELECT entitlement, CASE WHEN bool_or(expires_at IS NULL) THEN NULL ELSE MAX(expires_at) END
+		FROM purchases
+		WHERE telegram_id = (SELECT telegram_id FROM users WHERE id = $1)
+			AND status = 'paid'
+			AND (expires_at IS NULL OR expires_at > $2)
+		GROUP BY entitlement
+		ORDER BY entitlement
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, now)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list entitlements", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to list entitlements: %w", err)
+	}
+	defer rows.Close()
+
+	var entitlements []*models.Entitlement
+	for rows.Next() {
+		entitlement := &models.Entitlement{}
+		if err := rows.Scan(&entitlement.Name, &entitlement.ExpiresAt); err != nil {
+			logctx.Logger(ctx, r.logger).Error("Failed to scan entitlement", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan entitlement: %w", err)
+		}
+		entitlements = append(entitlements, entitlement)
+	}
+	if err := rows.Err(); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list entitlements", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to list entitlements: %w", err)
+	}
+
+	return entitlements, nil
+}
+
+// scanPurchase scans the purchaseColumns of a row
+func scanPurchase(row pgx.Row) (*models.Purchase, error) {
+	purchase := &models.Purchase{}
+	err := row.Scan(
+		&purchase.ID,
+		&purchase.UserID,
+		&purchase.TelegramID,
+		&purchase.ProductID,
+		&purchase.Entitlement,
+		&purchase.Amount,
+		&purchase.Currency,
+		&purchase.DurationSeconds,
+		&purchase.Status,
+		&purchase.ChargeID,
+		&purchase.CreatedAt,
+		&purchase.PaidAt,
+		&purchase.ExpiresAt,
+		&purchase.RefundedAt)
+	if err != nil {
+		return nil, err
+	}
+	return purchase, nil
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/utils/logctx"
+)
+
+// uniqueViolation is the PostgreSQL error code of a unique constraint
+// violation
+const uniqueViolation = "23505"
+
+type ReferralRepositoryImpl struct {
+	db     *Database
+	logger *zap.Logger
+}
+
+func NewReferralRepository(db *Database, logger *zap.Logger) repository.ReferralRepository {
+	return &ReferralRepositoryImpl{
+		db:     db,
+		logger: logger.With(zap.String("component", "referral_repository")),
+	}
+}
+
+func (r ReferralRepositoryImpl) EnsureCode(ctx context.Context, userID uuid.UUID, code string) (string, error) {
+	ctx, span := tracer.Start(ctx, "ReferralRepository.EnsureCode")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET referral_code = COALESCE(referral_code, $2)
+		WHERE id = $1
+		RETURNING referral_code
+	`
+
+	var current string
+	if err := r.db.Pool.QueryRow(ctx, query, userID, code).Scan(&current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", models.ErrUserNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return "", models.ErrConflict
+		}
+		logctx.Logger(ctx, r.logger).Error("Failed to set referral code", zap.Error(err), zap.String("user_id", userID.String()))
+		return "", fmt.Errorf("failed to set referral code: %w", err)
+	}
+
+	return current, nil
+}
+
+func (r ReferralRepositoryImpl) GetUserIDByCode(ctx context.Context, code string) (uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "ReferralRepository.GetUserIDByCode")
+	defer span.End()
+
+	query := `SELECT id FROM users WHERE referral_code = $1`
+
+	var userID uuid.UUID
+	if err := r.db.Pool.QueryRow(ctx, query, code).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, models.ErrUserNotFound
+		}
+		logctx.Logger(ctx, r.logger).Error("Failed to get user by referral code", zap.Error(err))
+		return uuid.Nil, fmt.Errorf("failed to get user by referral code: %w", err)
+	}
+
+	return userID, nil
+}
+
+func (r ReferralRepositoryImpl) Create(ctx context.Context, referral *models.Referral) (bool, error) {
+	ctx, span := tracer.Start(ctx, "ReferralRepository.Create")
+	defer span.End()
+
+	query := `
+		INSERT INTO referrals (telegram_id, user_id, referrer_id, campaign, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		ON CONFLICT (telegram_id) DO NOTHING
+	`
+
+	result, err := r.db.Pool.Exec(ctx, query,
+		referral.TelegramID,
+		referral.UserID,
+		referral.ReferrerID,
+		referral.Campaign,
+		referral.CreatedAt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to create referral", zap.Error(err), zap.String("user_id", referral.UserID.String()))
+		return false, fmt.Errorf("failed to create referral: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+func (r ReferralRepositoryImpl) CountByReferrer(ctx context.Context, referrerID uuid.UUID) (int, *time.Time, error) {
+	ctx, span := tracer.Start(ctx, "ReferralRepository.CountByReferrer")
+	defer span.End()
+
+	query := `SELECT COUNT(*), MAX(created_at) FROM referrals WHERE referrer_id = $1`
+
+	var count int
+	var last *time.Time
+	if err := r.db.Pool.QueryRow(ctx, query, referrerID).Scan(&count, &last); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to count referrals", zap.Error(err), zap.String("user_id", referrerID.String()))
+		return 0, nil, fmt.Errorf("failed to count referrals: %w", err)
+	}
+
+	return count, last, nil
+}
+
+func (r ReferralRepositoryImpl) ListCampaignStats(ctx context.Context, limit int) ([]*models.CampaignStats, error) {
+	ctx, span := tracer.Start(ctx, "ReferralRepository.ListCampaignStats")
+	defer span.End()
+
+	query := `
+		SELECT campaign, COUNT(*), MAX(created_at)
+		FROM referrals
+		WHERE campaign IS NOT NULL
+		GROUP BY campaign
+		ORDER BY COUNT(*) DESC, campaign
+		LIMIT $1
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, limit)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list campaign stats", zap.Error(err))
+		return nil, fmt.Errorf("failed to list campaign stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]*models.CampaignStats, 0, limit)
+	for rows.Next() {
+		campaign := &models.CampaignStats{}
+		if err := rows.Scan(&campaign.Campaign, &campaign.Signups, &campaign.LastSignupAt); err != nil {
+			logctx.Logger(ctx, r.logger).Error("Failed to scan campaign stats", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan campaign stats: %w", err)
+		}
+		stats = append(stats, campaign)
+	}
+	if err := rows.Err(); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to list campaign stats", zap.Error(err))
+		return nil, fmt.Errorf("failed to list campaign stats: %w", err)
+	}
+
+	return stats, nil
+}
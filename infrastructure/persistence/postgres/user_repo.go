@@ -9,6 +9,7 @@ import (
 	"go.uber.org/zap"
 	"renfound_v1/internal/domain/models"
 	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/utils/logctx"
 )
 
 type UserRepositoryImpl struct {
@@ -24,9 +25,12 @@ func NewUserRepository(db *Database, logger *zap.Logger) repository.UserReposito
 }
 
 func (r UserRepositoryImpl) Create(ctx context.Context, user *models.User) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.Create")
+	defer span.End()
+
 	query := `
-		INSERT INTO users (id, telegram_id, username, first_name, last_name, photo_url, auth_date, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO users (id, telegram_id, username, first_name, last_name, photo_url, language_code, auth_date, bot_reachable, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 
 	_, err := r.db.Pool.Exec(ctx, query,
@@ -36,11 +40,13 @@ func (r UserRepositoryImpl) Create(ctx context.Context, user *models.User) error
 		user.FirstName,
 		user.LastName,
 		user.PhotoURL,
+		user.LanguageCode,
 		user.AuthDate,
+		user.BotReachable,
 		user.CreatedAt,
 		user.UpdatedAt)
 	if err != nil {
-		r.logger.Error("Failed to create user", zap.Error(err), zap.Int64("telegram_id", user.TelegramID))
+		logctx.Logger(ctx, r.logger).Error("Failed to create user", zap.Error(err), zap.Int64("telegram_id", user.TelegramID))
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -49,8 +55,11 @@ func (r UserRepositoryImpl) Create(ctx context.Context, user *models.User) error
 }
 
 func (r UserRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.GetByID")
+	defer span.End()
+
 	query := `
-		SELECT id, telegram_id, username, first_name, last_name, photo_url, auth_date, created_at, updated_at
+		SELECT id, telegram_id, username, first_name, last_name, photo_url, language_code, auth_date, bot_reachable, created_at, updated_at
 		FROM users
 		WHERE id = $1`
 
@@ -63,7 +72,9 @@ func (r UserRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*models.
 		&user.FirstName,
 		&user.LastName,
 		&user.PhotoURL,
+		&user.LanguageCode,
 		&user.AuthDate,
+		&user.BotReachable,
 		&user.CreatedAt,
 		&user.UpdatedAt)
 
@@ -71,7 +82,7 @@ func (r UserRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*models.
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, models.ErrUserNotFound
 		}
-		r.logger.Error("Failed to get user by ID", zap.Error(err), zap.String("user_id", id.String()))
+		logctx.Logger(ctx, r.logger).Error("Failed to get user by ID", zap.Error(err), zap.String("user_id", id.String()))
 		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
 
@@ -79,8 +90,11 @@ func (r UserRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*models.
 }
 
 func (r UserRepositoryImpl) GetByTelegramID(ctx context.Context, telegramID int64) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.GetByTelegramID")
+	defer span.End()
+
 	query := `
-		SELECT id, telegram_id, username, first_name, last_name, photo_url, auth_date, created_at, updated_at
+		SELECT id, telegram_id, username, first_name, last_name, photo_url, language_code, auth_date, bot_reachable, created_at, updated_at
 		FROM users
 		WHERE telegram_id = $1
 	`
@@ -93,7 +107,9 @@ func (r UserRepositoryImpl) GetByTelegramID(ctx context.Context, telegramID int6
 		&user.FirstName,
 		&user.LastName,
 		&user.PhotoURL,
+		&user.LanguageCode,
 		&user.AuthDate,
+		&user.BotReachable,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -101,7 +117,7 @@ func (r UserRepositoryImpl) GetByTelegramID(ctx context.Context, telegramID int6
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, models.ErrUserNotFound
 		}
-		r.logger.Error("Failed to get user by Telegram ID", zap.Error(err), zap.Int64("telegram_id", telegramID))
+		logctx.Logger(ctx, r.logger).Error("Failed to get user by Telegram ID", zap.Error(err), zap.Int64("telegram_id", telegramID))
 		return nil, fmt.Errorf("failed to get user by Telegram ID: %w", err)
 	}
 
@@ -109,10 +125,13 @@ func (r UserRepositoryImpl) GetByTelegramID(ctx context.Context, telegramID int6
 }
 
 func (r UserRepositoryImpl) Update(ctx context.Context, user *models.User) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.Update")
+	defer span.End()
+
 	query := `
 		UPDATE users
-		SET username = $1, first_name = $2, last_name = $3, photo_url = $4, auth_date = $5, updated_at = NOW()
-		WHERE id = $6
+		SET username = $1, first_name = $2, last_name = $3, photo_url = $4, language_code = $5, auth_date = $6, updated_at = NOW()
+		WHERE id = $7
 	`
 
 	result, err := r.db.Pool.Exec(ctx, query,
@@ -120,11 +139,12 @@ func (r UserRepositoryImpl) Update(ctx context.Context, user *models.User) error
 		user.FirstName,
 		user.LastName,
 		user.PhotoURL,
+		user.LanguageCode,
 		user.AuthDate,
 		user.ID,
 	)
 	if err != nil {
-		r.logger.Error("Failed to update user", zap.Error(err), zap.String("user_id", user.ID.String()))
+		logctx.Logger(ctx, r.logger).Error("Failed to update user", zap.Error(err), zap.String("user_id", user.ID.String()))
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
@@ -135,12 +155,33 @@ func (r UserRepositoryImpl) Update(ctx context.Context, user *models.User) error
 	return nil
 }
 
+func (r UserRepositoryImpl) SetBotReachable(ctx context.Context, telegramID int64, reachable bool) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.SetBotReachable")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET bot_reachable = $1, updated_at = NOW()
+		WHERE telegram_id = $2 AND bot_reachable <> $1
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, reachable, telegramID); err != nil {
+		logctx.Logger(ctx, r.logger).Error("Failed to set bot reachability", zap.Error(err), zap.Int64("telegram_id", telegramID))
+		return fmt.Errorf("failed to set bot reachability: %w", err)
+	}
+
+	return nil
+}
+
 func (r UserRepositoryImpl) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.Delete")
+	defer span.End()
+
 	query := `DELETE FROM users WHERE id = $1`
 
 	result, err := r.db.Pool.Exec(ctx, query, id)
 	if err != nil {
-		r.logger.Error("Failed to delete user", zap.Error(err), zap.String("user_id", id.String()))
+		logctx.Logger(ctx, r.logger).Error("Failed to delete user", zap.Error(err), zap.String("user_id", id.String()))
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
@@ -152,6 +193,9 @@ func (r UserRepositoryImpl) Delete(ctx context.Context, id uuid.UUID) error {
 }
 
 func (r UserRepositoryImpl) CreateSession(ctx context.Context, session *models.Session) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.CreateSession")
+	defer span.End()
+
 	query := `
 		INSERT INTO sessions (id, user_id, refresh_token, user_agent, ip_address, expires_at, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
@@ -168,7 +212,7 @@ func (r UserRepositoryImpl) CreateSession(ctx context.Context, session *models.S
 		session.UpdatedAt,
 	)
 	if err != nil {
-		r.logger.Error("Failed to create session", zap.Error(err), zap.String("user_id", session.UserID.String()))
+		logctx.Logger(ctx, r.logger).Error("Failed to create session", zap.Error(err), zap.String("user_id", session.UserID.String()))
 		return fmt.Errorf("failed to create session: %w", err)
 	}
 
@@ -176,6 +220,9 @@ func (r UserRepositoryImpl) CreateSession(ctx context.Context, session *models.S
 }
 
 func (r UserRepositoryImpl) GetSessionByToken(ctx context.Context, refreshToken string) (*models.Session, error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.GetSessionByToken")
+	defer span.End()
+
 	query := `
 		SELECT id, user_id, refresh_token, user_agent, ip_address, expires_at, created_at, updated_at
 		FROM sessions
@@ -197,7 +244,7 @@ func (r UserRepositoryImpl) GetSessionByToken(ctx context.Context, refreshToken
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, models.ErrSessionNotFound
 		}
-		r.logger.Error("Failed to get session by token", zap.Error(err))
+		logctx.Logger(ctx, r.logger).Error("Failed to get session by token", zap.Error(err))
 		return nil, fmt.Errorf("failed to get session by token: %w", err)
 	}
 
@@ -205,11 +252,14 @@ func (r UserRepositoryImpl) GetSessionByToken(ctx context.Context, refreshToken
 }
 
 func (r UserRepositoryImpl) DeleteSession(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.DeleteSession")
+	defer span.End()
+
 	query := `DELETE FROM sessions WHERE id = $1`
 
 	result, err := r.db.Pool.Exec(ctx, query, id)
 	if err != nil {
-		r.logger.Error("Failed to delete session", zap.Error(err), zap.String("session_id", id.String()))
+		logctx.Logger(ctx, r.logger).Error("Failed to delete session", zap.Error(err), zap.String("session_id", id.String()))
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 
@@ -221,11 +271,14 @@ func (r UserRepositoryImpl) DeleteSession(ctx context.Context, id uuid.UUID) err
 }
 
 func (r UserRepositoryImpl) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "UserRepository.DeleteUserSessions")
+	defer span.End()
+
 	query := `DELETE FROM sessions WHERE user_id = $1`
 
 	_, err := r.db.Pool.Exec(ctx, query, userID)
 	if err != nil {
-		r.logger.Error("Failed to delete user sessions", zap.Error(err), zap.String("user_id", userID.String()))
+		logctx.Logger(ctx, r.logger).Error("Failed to delete user sessions", zap.Error(err), zap.String("user_id", userID.String()))
 		return fmt.Errorf("failed to delete user sessions: %w", err)
 	}
 
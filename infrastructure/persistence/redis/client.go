@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+)
+
+type Client struct {
+	*goredis.Client
+	logger *zap.Logger
+}
+
+func NewClient(cfg *config.AppConfig) (*Client, error) {
+	logger := cfg.Logger.With(zap.String("component", "redis"))
+	redisCfg := cfg.Config.Redis
+
+	// Accept both redis:// URLs and plain host:port addresses
+	var opts *goredis.Options
+	if strings.Contains(redisCfg.URL, "://") {
+		parsed, err := goredis.ParseURL(redisCfg.URL)
+		if err != nil {
+			logger.Error("Failed to parse redis URL", zap.Error(err))
+			return nil, fmt.Errorf("failed to parse redis URL: %w", err)
+		}
+		opts = parsed
+	} else {
+		opts = &goredis.Options{Addr: redisCfg.URL}
+	}
+
+	if redisCfg.Password != "" {
+		opts.Password = redisCfg.Password
+	}
+	if redisCfg.DB != 0 {
+		opts.DB = redisCfg.DB
+	}
+
+	client := goredis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		logger.Error("Failed to ping redis", zap.Error(err))
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+
+	logger.Info("Connected successfully to redis")
+
+	return &Client{
+		Client: client,
+		logger: logger,
+	}, nil
+}
+
+func (c *Client) Close() {
+	if c.Client != nil {
+		if err := c.Client.Close(); err != nil {
+			c.logger.Error("Failed to close redis client", zap.Error(err))
+			return
+		}
+		c.logger.Info("Redis client closed")
+	}
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: Rate tokens are added every Period, and up
+// to Burst tokens can be spent at once. Burst defaults to Rate.
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+// perSecond returns the refill rate in tokens per second
+func (l Limit) perSecond() float64 {
+	return float64(l.Rate) / l.Period.Seconds()
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+// Result is the outcome of a single Allow call
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // time until the bucket is full again
+	RetryAfter time.Duration // time until the next request is allowed, 0 if allowed
+}
+
+// Limiter decides whether a request identified by key may proceed
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (*Result, error)
+}
+
+// newResult builds a Result from the number of tokens left in the bucket
+func newResult(allowed bool, tokens float64, limit Limit) *Result {
+	rate := limit.perSecond()
+	burst := limit.burst()
+
+	result := &Result{
+		Allowed:    allowed,
+		Limit:      burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: secondsToDuration((float64(burst) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+
+	return result
+}
+
+func secondsToDuration(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval controls how often idle buckets are evicted
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket refills completely and can be evicted
+}
+
+// MemoryLimiter keeps token buckets in process memory. It is meant for single
+// node deployments and tests; limits are not shared between replicas.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	rate := limit.perSecond()
+	burst := float64(limit.burst())
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(secondsToDuration((burst - b.tokens) / rate))
+
+	return newResult(allowed, b.tokens, limit), nil
+}
+
+// sweep drops buckets that have refilled completely, since a fresh bucket
+// behaves the same. Must be called with l.mu held.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
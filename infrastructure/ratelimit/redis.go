@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills and takes a token atomically. Redis server time is
+// used so replicas with skewed clocks share consistent buckets.
+var tokenBucketScript = goredis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisLimiter keeps token buckets in Redis so limits are shared by all replicas
+type RedisLimiter struct {
+	client goredis.Scripter
+	prefix string
+}
+
+func NewRedisLimiter(client goredis.Scripter) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		prefix: "ratelimit:",
+	}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	res, err := tokenBucketScript.Run(ctx, l.client, []string{l.prefix + key},
+		limit.perSecond(),
+		limit.burst(),
+	).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+
+	if len(res) != 2 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", res)
+	}
+
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse remaining tokens: %w", err)
+	}
+
+	return newResult(allowed == 1, tokens, limit), nil
+}
@@ -0,0 +1,212 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/internal/utils/logctx"
+)
+
+var tracer = otel.Tracer("renfound_v1/infrastructure/telegram")
+
+// maxResponseSize caps the Bot API responses read into memory
+const maxResponseSize = 1 << 20
+
+// Client calls the Telegram Bot API. Messages are paced to stay within
+// Telegram's rate limits, and requests rejected with 429 are retried after
+// the delay Telegram asks for.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	limiter    *limiter
+	logger     *zap.Logger
+}
+
+func NewClient(cfg *config.AppConfig) *Client {
+	telegramCfg := cfg.Config.Telegram
+
+	return &Client{
+		baseURL:    strings.TrimRight(telegramCfg.APIURL, "/") + "/bot" + telegramCfg.BotToken + "/",
+		httpClient: &http.Client{Timeout: telegramCfg.Timeout},
+		maxRetries: telegramCfg.MaxRetries,
+		limiter:    newLimiter(telegramCfg.RateLimit, telegramCfg.ChatRateLimit, telegramCfg.GroupRateLimit),
+		logger:     cfg.Logger.With(zap.String("component", "telegram_client")),
+	}
+}
+
+// SendMessage sends a text message
+func (c *Client) SendMessage(ctx context.Context, params SendMessageParams) (*Message, error) {
+	var message Message
+	if err := c.call(ctx, "sendMessage", params.ChatID, params, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// SendPhoto sends a photo
+func (c *Client) SendPhoto(ctx context.Context, params SendPhotoParams) (*Message, error) {
+	var message Message
+	if err := c.call(ctx, "sendPhoto", params.ChatID, params, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// AnswerWebAppQuery sends a message on behalf of the user who opened the
+// Mini App, answering the query the Mini App received
+func (c *Client) AnswerWebAppQuery(ctx context.Context, webAppQueryID string, result InlineQueryResultArticle) (*SentWebAppMessage, error) {
+	result.Type = "article"
+
+	var sent SentWebAppMessage
+	params := answerWebAppQueryParams{WebAppQueryID: webAppQueryID, Result: result}
+	if err := c.call(ctx, "answerWebAppQuery", 0, params, &sent); err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// CreateInvoiceLink creates a link to an invoice, opened by the Mini App
+// with Telegram.WebApp.openInvoice. Invoices are in Stars unless another
+// currency is set.
+func (c *Client) CreateInvoiceLink(ctx context.Context, params CreateInvoiceLinkParams) (string, error) {
+	if params.Currency == "" {
+		params.Currency = CurrencyStars
+	}
+
+	var link string
+	if err := c.call(ctx, "createInvoiceLink", 0, params, &link); err != nil {
+		return "", err
+	}
+	return link, nil
+}
+
+// AnswerPreCheckoutQuery confirms or rejects an order. errorMessage is
+// shown to the user when the order is rejected.
+func (c *Client) AnswerPreCheckoutQuery(ctx context.Context, queryID string, ok bool, errorMessage string) error {
+	params := answerPreCheckoutQueryParams{PreCheckoutQueryID: queryID, OK: ok}
+	if !ok {
+		params.ErrorMessage = errorMessage
+	}
+	return c.call(ctx, "answerPreCheckoutQuery", 0, params, nil)
+}
+
+// RefundStarPayment returns the Stars of a successful payment to the user
+func (c *Client) RefundStarPayment(ctx context.Context, userID int64, chargeID string) error {
+	params := refundStarPaymentParams{UserID: userID, TelegramPaymentChargeID: chargeID}
+	return c.call(ctx, "refundStarPayment", 0, params, nil)
+}
+
+// SetWebhook makes Telegram deliver updates to params.URL
+func (c *Client) SetWebhook(ctx context.Context, params SetWebhookParams) error {
+	return c.call(ctx, "setWebhook", 0, params, nil)
+}
+
+// Notify sends a plain text message to a user
+func (c *Client) Notify(ctx context.Context, telegramID int64, text string) error {
+	_, err := c.SendMessage(ctx, SendMessageParams{ChatID: telegramID, Text: text})
+	return err
+}
+
+// call invokes a Bot API method, waiting for the rate limits of chatID
+// first and retrying while Telegram answers 429
+func (c *Client) call(ctx context.Context, method string, chatID int64, params, result interface{}) error {
+	ctx, span := tracer.Start(ctx, "Telegram."+method)
+	defer span.End()
+	span.SetAttributes(attribute.String("telegram.method", method))
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s parameters: %w", method, err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx, chatID); err != nil {
+			return err
+		}
+
+		err = c.do(ctx, method, body, result)
+
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests || attempt >= c.maxRetries {
+			break
+		}
+
+		logctx.Logger(ctx, c.logger).Warn("Telegram rate limit hit, retrying",
+			zap.String("method", method),
+			zap.Duration("retry_after", apiErr.RetryAfter),
+			zap.Int("attempt", attempt+1))
+
+		if err := sleep(ctx, apiErr.RetryAfter); err != nil {
+			return err
+		}
+	}
+
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// do sends a single request
+func (c *Client) do(ctx context.Context, method string, body []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// The URL holds the bot token, keep it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var envelope response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&envelope); err != nil {
+		return fmt.Errorf("telegram %s: failed to decode %d response: %w", method, resp.StatusCode, err)
+	}
+
+	if !envelope.OK {
+		apiErr := &Error{
+			Method:      method,
+			Code:        envelope.ErrorCode,
+			Description: envelope.Description,
+		}
+		if apiErr.Code == 0 {
+			apiErr.Code = resp.StatusCode
+		}
+		if envelope.Parameters != nil {
+			apiErr.RetryAfter = time.Duration(envelope.Parameters.RetryAfter) * time.Second
+		}
+		return apiErr
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(envelope.Result, result); err != nil {
+			return fmt.Errorf("telegram %s: failed to decode result: %w", method, err)
+		}
+	}
+
+	return nil
+}
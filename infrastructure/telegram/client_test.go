@@ -0,0 +1,326 @@
+package telegram_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/infrastructure/telegram/telegramtest"
+)
+
+const testToken = "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsawq"
+
+// newClient returns a client of a fresh fake Bot API, with rates high
+// enough not to slow tests down unless cfg changes them
+func newClient(t *testing.T, configure func(cfg *config.TelegramConfig)) (*telegram.Client, *telegramtest.Server) {
+	t.Helper()
+
+	srv := telegramtest.NewServer(testToken)
+	t.Cleanup(srv.Close)
+
+	telegramCfg := config.TelegramConfig{
+		BotToken:       testToken,
+		APIURL:         srv.URL,
+		Timeout:        5 * time.Second,
+		MaxRetries:     2,
+		RateLimit:      1000,
+		ChatRateLimit:  1000,
+		GroupRateLimit: 1000,
+	}
+	if configure != nil {
+		configure(&telegramCfg)
+	}
+
+	cfg := &config.AppConfig{
+		Config: &config.Config{Telegram: telegramCfg},
+		Logger: zap.NewNop(),
+	}
+	return telegram.NewClient(cfg), srv
+}
+
+func TestSendMessage(t *testing.T) {
+	client, srv := newClient(t, nil)
+
+	message, err := client.SendMessage(context.Background(), telegram.SendMessageParams{
+		ChatID: 42,
+		Text:   "Welcome!",
+		ReplyMarkup: telegram.Keyboard([]telegram.InlineKeyboardButton{
+			telegram.WebAppButton("Open app", "https://app.example.com"),
+		}),
+	})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if message.Chat.ID != 42 || message.Text != "Welcome!" || message.MessageID == 0 {
+		t.Errorf("unexpected message %+v", message)
+	}
+
+	requests := srv.Requests("sendMessage")
+	if len(requests) != 1 {
+		t.Fatalf("got %d sendMessage requests, want 1", len(requests))
+	}
+	if url := webAppURL(t, requests[0]); url != "https://app.example.com" {
+		t.Errorf("web_app url = %q", url)
+	}
+}
+
+func TestSendPhoto(t *testing.T) {
+	client, srv := newClient(t, nil)
+
+	message, err := client.SendPhoto(context.Background(), telegram.SendPhotoParams{
+		ChatID:  42,
+		Photo:   "https://cdn.example.com/photo.jpg",
+		Caption: "New feature",
+		ReplyMarkup: telegram.Keyboard([]telegram.InlineKeyboardButton{
+			telegram.WebAppButton("Try it", "https://app.example.com/feature"),
+		}),
+	})
+	if err != nil {
+		t.Fatalf("SendPhoto: %v", err)
+	}
+	if message.Caption != "New feature" {
+		t.Errorf("caption = %q", message.Caption)
+	}
+
+	requests := srv.Requests("sendPhoto")
+	if len(requests) != 1 {
+		t.Fatalf("got %d sendPhoto requests, want 1", len(requests))
+	}
+	if photo := requests[0].Params["photo"]; photo != "https://cdn.example.com/photo.jpg" {
+		t.Errorf("photo = %v", photo)
+	}
+	if url := webAppURL(t, requests[0]); url != "https://app.example.com/feature" {
+		t.Errorf("web_app url = %q", url)
+	}
+}
+
+func TestAnswerWebAppQuery(t *testing.T) {
+	client, srv := newClient(t, nil)
+
+	sent, err := client.AnswerWebAppQuery(context.Background(), "query-1", telegram.InlineQueryResultArticle{
+		ID:    "result-1",
+		Title: "Shared",
+		InputMessageContent: telegram.InputTextMessageContent{
+			MessageText: "Look at this",
+		},
+	})
+	if err != nil {
+		t.Fatalf("AnswerWebAppQuery: %v", err)
+	}
+	if sent.InlineMessageID == "" {
+		t.Error("inline message id is empty")
+	}
+
+	requests := srv.Requests("answerWebAppQuery")
+	if len(requests) != 1 {
+		t.Fatalf("got %d answerWebAppQuery requests, want 1", len(requests))
+	}
+	if id := requests[0].Params["web_app_query_id"]; id != "query-1" {
+		t.Errorf("web_app_query_id = %v", id)
+	}
+	result, _ := requests[0].Params["result"].(map[string]interface{})
+	if result["type"] != "article" {
+		t.Errorf("result type = %v, want article", result["type"])
+	}
+}
+
+func TestRetryAfterTooManyRequests(t *testing.T) {
+	client, srv := newClient(t, nil)
+	srv.Fail("sendMessage", telegramtest.Response{
+		Code:        http.StatusTooManyRequests,
+		Description: "Too Many Requests: retry after 1",
+		RetryAfter:  1,
+	})
+
+	start := time.Now()
+	if _, err := client.SendMessage(context.Background(), telegram.SendMessageParams{ChatID: 42, Text: "hi"}); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, before retry_after", elapsed)
+	}
+	if n := len(srv.Requests("sendMessage")); n != 2 {
+		t.Errorf("got %d sendMessage requests, want 2", n)
+	}
+}
+
+func TestGiveUpAfterMaxRetries(t *testing.T) {
+	client, srv := newClient(t, func(cfg *config.TelegramConfig) {
+		cfg.MaxRetries = 2
+	})
+	tooMany := telegramtest.Response{Code: http.StatusTooManyRequests, Description: "Too Many Requests: retry after 0"}
+	srv.Fail("sendMessage", tooMany, tooMany, tooMany, tooMany)
+
+	_, err := client.SendMessage(context.Background(), telegram.SendMessageParams{ChatID: 42, Text: "hi"})
+	if !errors.Is(err, telegram.ErrTooManyRequests) {
+		t.Fatalf("err = %v, want ErrTooManyRequests", err)
+	}
+	if n := len(srv.Requests("sendMessage")); n != 3 {
+		t.Errorf("got %d sendMessage requests, want 1 plus 2 retries", n)
+	}
+}
+
+func TestForbidden(t *testing.T) {
+	client, srv := newClient(t, nil)
+	srv.Block(42)
+
+	_, err := client.SendMessage(context.Background(), telegram.SendMessageParams{ChatID: 42, Text: "hi"})
+	if !errors.Is(err, telegram.ErrForbidden) {
+		t.Fatalf("err = %v, want ErrForbidden", err)
+	}
+
+	var apiErr *telegram.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden || apiErr.Method != "sendMessage" {
+		t.Errorf("err = %#v", err)
+	}
+
+	// Forbidden is final, it is not retried
+	if n := len(srv.Requests("sendMessage")); n != 1 {
+		t.Errorf("got %d sendMessage requests, want 1", n)
+	}
+
+	srv.Unblock(42)
+	if _, err := client.SendMessage(context.Background(), telegram.SendMessageParams{ChatID: 42, Text: "hi"}); err != nil {
+		t.Errorf("SendMessage after unblock: %v", err)
+	}
+}
+
+func TestPacesMessagesPerChat(t *testing.T) {
+	client, _ := newClient(t, func(cfg *config.TelegramConfig) {
+		cfg.ChatRateLimit = 10 // one message every 100ms
+	})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.SendMessage(ctx, telegram.SendMessageParams{ChatID: 42, Text: "hi"}); err != nil {
+			t.Fatalf("SendMessage: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("3 messages to one chat took %v, want at least 200ms", elapsed)
+	}
+
+	// Other chats have their own pace
+	start = time.Now()
+	for chatID := int64(100); chatID < 103; chatID++ {
+		if _, err := client.SendMessage(ctx, telegram.SendMessageParams{ChatID: chatID, Text: "hi"}); err != nil {
+			t.Fatalf("SendMessage: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("3 messages to different chats took %v, want no per chat wait", elapsed)
+	}
+}
+
+func TestPacesMessagesGlobally(t *testing.T) {
+	client, _ := newClient(t, func(cfg *config.TelegramConfig) {
+		cfg.RateLimit = 5 // bursts of 5, then one message every 200ms
+	})
+	ctx := context.Background()
+
+	start := time.Now()
+	for chatID := int64(1); chatID <= 7; chatID++ {
+		if _, err := client.SendMessage(ctx, telegram.SendMessageParams{ChatID: chatID, Text: "hi"}); err != nil {
+			t.Fatalf("SendMessage: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("7 messages took %v, want the 2 past the burst to wait 400ms", elapsed)
+	}
+}
+
+func TestPacingStopsWithContext(t *testing.T) {
+	client, _ := newClient(t, func(cfg *config.TelegramConfig) {
+		cfg.ChatRateLimit = 0.1 // one message every 10s
+	})
+
+	if _, err := client.SendMessage(context.Background(), telegram.SendMessageParams{ChatID: 42, Text: "hi"}); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.SendMessage(ctx, telegram.SendMessageParams{ChatID: 42, Text: "hi"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestErrorsDoNotLeakToken(t *testing.T) {
+	ctx := context.Background()
+	params := telegram.SendMessageParams{ChatID: 42, Text: "hi"}
+
+	t.Run("unreachable server", func(t *testing.T) {
+		client, srv := newClient(t, nil)
+		srv.Close()
+
+		_, err := client.SendMessage(ctx, params)
+		if err == nil {
+			t.Fatal("SendMessage succeeded against a closed server")
+		}
+		assertNoToken(t, err)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client, srv := newClient(t, func(cfg *config.TelegramConfig) {
+			cfg.Timeout = 50 * time.Millisecond
+		})
+		srv.Handle("sendMessage", func(telegramtest.Request) telegramtest.Response {
+			time.Sleep(200 * time.Millisecond)
+			return telegramtest.Response{Result: map[string]interface{}{}}
+		})
+
+		_, err := client.SendMessage(ctx, params)
+		if err == nil {
+			t.Fatal("SendMessage succeeded past its timeout")
+		}
+		assertNoToken(t, err)
+	})
+
+	t.Run("api error", func(t *testing.T) {
+		client, srv := newClient(t, nil)
+		srv.Fail("sendMessage", telegramtest.Response{Code: http.StatusBadRequest, Description: "Bad Request: chat not found"})
+
+		_, err := client.SendMessage(ctx, params)
+		if !errors.Is(err, telegram.ErrBadRequest) {
+			t.Fatalf("err = %v, want ErrBadRequest", err)
+		}
+		assertNoToken(t, err)
+	})
+}
+
+func assertNoToken(t *testing.T, err error) {
+	t.Helper()
+
+	secret := testToken[strings.Index(testToken, ":")+1:]
+	if strings.Contains(err.Error(), secret) {
+		t.Errorf("error leaks the bot token: %v", err)
+	}
+}
+
+// webAppURL returns the web_app URL of the first button of a request's
+// inline keyboard
+func webAppURL(t *testing.T, req telegramtest.Request) string {
+	t.Helper()
+
+	markup, _ := req.Params["reply_markup"].(map[string]interface{})
+	rows, _ := markup["inline_keyboard"].([]interface{})
+	if len(rows) == 0 {
+		t.Fatalf("request has no inline keyboard: %v", req.Params)
+	}
+	buttons, _ := rows[0].([]interface{})
+	if len(buttons) == 0 {
+		t.Fatalf("keyboard has no buttons: %v", rows)
+	}
+	button, _ := buttons[0].(map[string]interface{})
+	webApp, _ := button["web_app"].(map[string]interface{})
+	url, _ := webApp["url"].(string)
+	return url
+}
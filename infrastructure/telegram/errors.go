@@ -0,0 +1,45 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors matched by the errors returned from the Bot API
+var (
+	ErrBadRequest      = errors.New("telegram: bad request")
+	ErrUnauthorized    = errors.New("telegram: invalid bot token")
+	ErrForbidden       = errors.New("telegram: forbidden") // e.g. the user blocked the bot
+	ErrTooManyRequests = errors.New("telegram: too many requests")
+)
+
+// Error is an error response of the Bot API. It matches the sentinel of
+// its code with errors.Is.
+type Error struct {
+	Method      string
+	Code        int
+	Description string
+	// RetryAfter is how long Telegram asks to wait, set on 429 responses
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("telegram %s: %d %s", e.Method, e.Code, e.Description)
+}
+
+func (e *Error) Unwrap() error {
+	switch e.Code {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxIdleChats bounds the per chat state kept between sweeps
+const maxIdleChats = 10000
+
+// pacer spaces events interval apart, allowing bursts of up to burst
+// events. It keeps the earliest time the next event could run without a
+// burst, which is all the state a token bucket needs.
+type pacer struct {
+	interval time.Duration
+	burst    int
+	next     time.Time
+}
+
+func newPacer(rate float64, burst int) *pacer {
+	if burst < 1 {
+		burst = 1
+	}
+	return &pacer{interval: time.Duration(float64(time.Second) / rate), burst: burst}
+}
+
+// reserve books a slot and returns how long to wait for it
+func (p *pacer) reserve(now time.Time) time.Duration {
+	next := p.next
+	if next.Before(now) {
+		next = now
+	}
+
+	at := next.Add(-time.Duration(p.burst-1) * p.interval)
+	if at.Before(now) {
+		at = now
+	}
+
+	p.next = next.Add(p.interval)
+	return at.Sub(now)
+}
+
+// limiter keeps outgoing messages below the global and per chat limits
+type limiter struct {
+	mu        sync.Mutex
+	global    *pacer
+	chats     map[int64]*pacer
+	chatRate  float64
+	groupRate float64
+}
+
+func newLimiter(rate, chatRate, groupRate float64) *limiter {
+	return &limiter{
+		global:    newPacer(rate, int(rate)),
+		chats:     make(map[int64]*pacer),
+		chatRate:  chatRate,
+		groupRate: groupRate,
+	}
+}
+
+// wait blocks until a message may be sent to chatID. A chatID of 0 only
+// counts against the global limit.
+func (l *limiter) wait(ctx context.Context, chatID int64) error {
+	if chatID != 0 {
+		l.mu.Lock()
+		delay := l.chat(chatID).reserve(time.Now())
+		l.mu.Unlock()
+
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	delay := l.global.reserve(time.Now())
+	l.mu.Unlock()
+
+	return sleep(ctx, delay)
+}
+
+// chat returns the pacer of a chat. Must be called with mu held.
+func (l *limiter) chat(chatID int64) *pacer {
+	p, ok := l.chats[chatID]
+	if ok {
+		return p
+	}
+
+	if len(l.chats) >= maxIdleChats {
+		l.sweep(time.Now())
+	}
+
+	// Group and channel IDs are negative
+	rate := l.chatRate
+	if chatID < 0 {
+		rate = l.groupRate
+	}
+	p = newPacer(rate, 1)
+	l.chats[chatID] = p
+	return p
+}
+
+// sweep forgets chats that could send right away, their state is the same
+// as a new chat's
+func (l *limiter) sweep(now time.Time) {
+	for id, p := range l.chats {
+		if !p.next.After(now) {
+			delete(l.chats, id)
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
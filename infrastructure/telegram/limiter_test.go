@@ -0,0 +1,68 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPacerSpacesEvents(t *testing.T) {
+	p := newPacer(10, 1)
+	now := time.Now()
+
+	for i, want := range []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		if got := p.reserve(now); got != want {
+			t.Errorf("reservation %d waits %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestPacerAllowsBursts(t *testing.T) {
+	p := newPacer(10, 3)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if got := p.reserve(now); got != 0 {
+			t.Errorf("reservation %d within the burst waits %v", i, got)
+		}
+	}
+	if got := p.reserve(now); got != 100*time.Millisecond {
+		t.Errorf("reservation past the burst waits %v, want 100ms", got)
+	}
+}
+
+func TestPacerRefills(t *testing.T) {
+	p := newPacer(10, 1)
+	now := time.Now()
+
+	p.reserve(now)
+	if got := p.reserve(now.Add(time.Second)); got != 0 {
+		t.Errorf("reservation after idling waits %v", got)
+	}
+}
+
+func TestLimiterUsesGroupRate(t *testing.T) {
+	l := newLimiter(30, 1, 0.5)
+
+	if got := l.chat(42).interval; got != time.Second {
+		t.Errorf("private chat interval = %v, want 1s", got)
+	}
+	if got := l.chat(-100123).interval; got != 2*time.Second {
+		t.Errorf("group interval = %v, want 2s", got)
+	}
+}
+
+func TestLimiterSweepsIdleChats(t *testing.T) {
+	l := newLimiter(30, 1, 1)
+	now := time.Now()
+
+	l.chat(1).reserve(now)
+	l.chat(2)
+	l.sweep(now)
+
+	if _, ok := l.chats[1]; !ok {
+		t.Error("chat waiting for its next slot was swept")
+	}
+	if _, ok := l.chats[2]; ok {
+		t.Error("idle chat was kept")
+	}
+}
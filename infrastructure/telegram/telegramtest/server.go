@@ -0,0 +1,349 @@
+// Package telegramtest provides a local fake of the Telegram Bot API for
+// running the bot code without reaching Telegram
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"renfound_v1/infrastructure/telegram"
+)
+
+// Request is a Bot API call received by the server
+type Request struct {
+	Method string
+	Params map[string]interface{}
+}
+
+// ChatID returns the chat_id parameter, 0 if there is none
+func (r Request) ChatID() int64 {
+	id, _ := r.Params["chat_id"].(float64)
+	return int64(id)
+}
+
+// Response is the answer to a Bot API call. A zero Code means success.
+type Response struct {
+	Result      interface{}
+	Code        int
+	Description string
+	RetryAfter  int // seconds, sent with 429 responses
+}
+
+// HandlerFunc answers calls of a Bot API method
+type HandlerFunc func(req Request) Response
+
+// Invoice is an invoice created with createInvoiceLink
+type Invoice struct {
+	Link        string
+	Title       string
+	Description string
+	Payload     string
+	Currency    string
+	Amount      int // total of the prices
+}
+
+// charge is a payment made with Pay
+type charge struct {
+	userID   int64
+	refunded bool
+}
+
+// Server is a fake Bot API. Messages are accepted for every chat unless
+// the chat blocked the bot or a failure was queued for the method.
+// Payments are made with PreCheckout and Pay, which return the updates
+// Telegram would send to the bot.
+type Server struct {
+	*httptest.Server
+	token string
+
+	mu            sync.Mutex
+	requests      []Request
+	handlers      map[string]HandlerFunc
+	failures      map[string][]Response
+	blocked       map[int64]bool
+	nextMessageID int64
+	nextUpdateID  int64
+	invoices      []Invoice
+	charges       map[string]*charge
+}
+
+// NewServer starts a fake Bot API accepting token. Point the client's
+// API URL at its URL field and Close it when done.
+func NewServer(token string) *Server {
+	s := &Server{
+		token:         token,
+		handlers:      make(map[string]HandlerFunc),
+		failures:      make(map[string][]Response),
+		blocked:       make(map[int64]bool),
+		nextMessageID: 1,
+		nextUpdateID:  1,
+		charges:       make(map[string]*charge),
+	}
+	s.handlers["sendMessage"] = s.sendMessage
+	s.handlers["sendPhoto"] = s.sendMessage
+	s.handlers["answerWebAppQuery"] = func(Request) Response {
+		return Response{Result: map[string]string{"inline_message_id": "fake-inline-message"}}
+	}
+	s.handlers["setWebhook"] = func(Request) Response {
+		return Response{Result: true}
+	}
+	s.handlers["createInvoiceLink"] = s.createInvoiceLink
+	s.handlers["answerPreCheckoutQuery"] = func(Request) Response {
+		return Response{Result: true}
+	}
+	s.handlers["refundStarPayment"] = s.refundStarPayment
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Handle replaces the answer of a method
+func (s *Server) Handle(method string, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = handler
+}
+
+// Fail queues failure responses, returned by the next calls of method
+// before it is handled normally again
+func (s *Server) Fail(method string, failures ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = append(s.failures[method], failures...)
+}
+
+// Block makes the chat reject messages like a user who blocked the bot
+func (s *Server) Block(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocked[chatID] = true
+}
+
+// Unblock accepts messages to the chat again
+func (s *Server) Unblock(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blocked, chatID)
+}
+
+// Requests returns the calls of method received so far, all calls if
+// method is empty
+func (s *Server) Requests(method string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Request
+	for _, req := range s.requests {
+		if method == "" || req.Method == method {
+			result = append(result, req)
+		}
+	}
+	return result
+}
+
+// Invoices returns the invoices created so far
+func (s *Server) Invoices() []Invoice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Invoice(nil), s.invoices...)
+}
+
+// PreCheckout returns the pre-checkout query sent when user userID
+// confirms the order of invoice
+func (s *Server) PreCheckout(userID int64, invoice Invoice) *telegram.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update := &telegram.Update{
+		UpdateID: s.nextUpdateID,
+		PreCheckoutQuery: &telegram.PreCheckoutQuery{
+			ID:             fmt.Sprintf("fake-pre-checkout-%d", s.nextUpdateID),
+			From:           telegram.User{ID: userID, FirstName: "Test"},
+			Currency:       invoice.Currency,
+			TotalAmount:    invoice.Amount,
+			InvoicePayload: invoice.Payload,
+		},
+	}
+	s.nextUpdateID++
+	return update
+}
+
+// Pay charges user userID for invoice and returns the successful payment
+// message sent to the bot. The charge can be refunded afterwards.
+func (s *Server) Pay(userID int64, invoice Invoice) *telegram.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chargeID := fmt.Sprintf("fake-charge-%d", s.nextUpdateID)
+	s.charges[chargeID] = &charge{userID: userID}
+
+	update := &telegram.Update{
+		UpdateID: s.nextUpdateID,
+		Message: &telegram.Message{
+			MessageID: s.nextMessageID,
+			From:      &telegram.User{ID: userID, FirstName: "Test"},
+			Chat:      telegram.Chat{ID: userID, Type: chatType(userID)},
+			Date:      time.Now().Unix(),
+			SuccessfulPayment: &telegram.SuccessfulPayment{
+				Currency:                invoice.Currency,
+				TotalAmount:             invoice.Amount,
+				InvoicePayload:          invoice.Payload,
+				TelegramPaymentChargeID: chargeID,
+			},
+		},
+	}
+	s.nextUpdateID++
+	s.nextMessageID++
+	return update
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || !strings.HasPrefix(r.URL.Path, "/bot") {
+		writeResponse(w, Response{Code: http.StatusNotFound, Description: "Not Found"})
+		return
+	}
+	if token != s.token {
+		writeResponse(w, Response{Code: http.StatusUnauthorized, Description: "Unauthorized"})
+		return
+	}
+
+	req := Request{Method: method, Params: make(map[string]interface{})}
+	if err := json.NewDecoder(r.Body).Decode(&req.Params); err != nil {
+		writeResponse(w, Response{Code: http.StatusBadRequest, Description: "Bad Request: invalid JSON"})
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	var resp Response
+	var handled bool
+	if queued := s.failures[method]; len(queued) > 0 {
+		resp, handled = queued[0], true
+		s.failures[method] = queued[1:]
+	}
+	handler, known := s.handlers[method]
+	s.mu.Unlock()
+
+	switch {
+	case handled:
+	case !known:
+		resp = Response{Code: http.StatusNotFound, Description: "Not Found: method not found"}
+	default:
+		resp = handler(req)
+	}
+
+	writeResponse(w, resp)
+}
+
+// sendMessage accepts a message unless the chat blocked the bot
+func (s *Server) sendMessage(req Request) Response {
+	chatID := req.ChatID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.blocked[chatID] {
+		return Response{Code: http.StatusForbidden, Description: "Forbidden: bot was blocked by the user"}
+	}
+
+	message := map[string]interface{}{
+		"message_id": s.nextMessageID,
+		"date":       time.Now().Unix(),
+		"chat":       map[string]interface{}{"id": chatID, "type": chatType(chatID)},
+	}
+	if text, ok := req.Params["text"]; ok {
+		message["text"] = text
+	}
+	if caption, ok := req.Params["caption"]; ok {
+		message["caption"] = caption
+	}
+	s.nextMessageID++
+
+	return Response{Result: message}
+}
+
+// createInvoiceLink records the invoice and returns a link to it
+func (s *Server) createInvoiceLink(req Request) Response {
+	invoice := Invoice{}
+	invoice.Title, _ = req.Params["title"].(string)
+	invoice.Description, _ = req.Params["description"].(string)
+	invoice.Payload, _ = req.Params["payload"].(string)
+	invoice.Currency, _ = req.Params["currency"].(string)
+
+	prices, _ := req.Params["prices"].([]interface{})
+	for _, price := range prices {
+		if price, ok := price.(map[string]interface{}); ok {
+			amount, _ := price["amount"].(float64)
+			invoice.Amount += int(amount)
+		}
+	}
+
+	// Stars invoices take exactly one price
+	if invoice.Currency == telegram.CurrencyStars && len(prices) != 1 {
+		return Response{Code: http.StatusBadRequest, Description: "Bad Request: STARS_INVOICE_INVALID"}
+	}
+	if invoice.Title == "" || invoice.Payload == "" || invoice.Amount <= 0 {
+		return Response{Code: http.StatusBadRequest, Description: "Bad Request: invalid invoice"}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invoice.Link = fmt.Sprintf("https://t.me/$fake-invoice-%d", len(s.invoices)+1)
+	s.invoices = append(s.invoices, invoice)
+	return Response{Result: invoice.Link}
+}
+
+// refundStarPayment refunds a charge made with Pay, once
+func (s *Server) refundStarPayment(req Request) Response {
+	userID, _ := req.Params["user_id"].(float64)
+	chargeID, _ := req.Params["telegram_payment_charge_id"].(string)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.charges[chargeID]
+	switch {
+	case !ok || c.userID != int64(userID):
+		return Response{Code: http.StatusBadRequest, Description: "Bad Request: CHARGE_NOT_FOUND"}
+	case c.refunded:
+		return Response{Code: http.StatusBadRequest, Description: "Bad Request: CHARGE_ALREADY_REFUNDED"}
+	}
+
+	c.refunded = true
+	return Response{Result: true}
+}
+
+func chatType(chatID int64) string {
+	if chatID < 0 {
+		return "supergroup"
+	}
+	return "private"
+}
+
+// writeResponse writes the Bot API envelope. Telegram reports errors with
+// the matching HTTP status as well as in the body.
+func writeResponse(w http.ResponseWriter, resp Response) {
+	body := map[string]interface{}{"ok": resp.Code == 0}
+	status := http.StatusOK
+	if resp.Code == 0 {
+		body["result"] = resp.Result
+	} else {
+		status = resp.Code
+		body["error_code"] = resp.Code
+		body["description"] = resp.Description
+		if resp.RetryAfter > 0 {
+			body["parameters"] = map[string]int{"retry_after": resp.RetryAfter}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,297 @@
+package telegram
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Types mirror the Bot API objects, with only the fields the service uses.
+// See https://core.telegram.org/bots/api#available-types
+
+// User is a Telegram user or bot
+type User struct {
+	ID           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+// Chat is a private chat, group or channel
+type Chat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"` // "private", "group", "supergroup" or "channel"
+	Title    string `json:"title,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Message is a message sent by or to the bot
+type Message struct {
+	MessageID  int64       `json:"message_id"`
+	From       *User       `json:"from,omitempty"`
+	Chat       Chat        `json:"chat"`
+	Date       int64       `json:"date"`
+	Text       string      `json:"text,omitempty"`
+	Caption    string      `json:"caption,omitempty"`
+	WebAppData *WebAppData `json:"web_app_data,omitempty"`
+
+	SuccessfulPayment *SuccessfulPayment `json:"successful_payment,omitempty"`
+	RefundedPayment   *RefundedPayment   `json:"refunded_payment,omitempty"`
+}
+
+// Command splits a bot command such as "/start ref_42" into its name and
+// arguments. The name is empty if the message is not a command. Commands
+// addressed to a bot by username, like "/start@renfound_bot", lose the
+// username.
+func (m *Message) Command() (name, args string) {
+	if !strings.HasPrefix(m.Text, "/") {
+		return "", ""
+	}
+
+	command, args, _ := strings.Cut(m.Text[1:], " ")
+	name, _, _ = strings.Cut(command, "@")
+	return name, strings.TrimSpace(args)
+}
+
+// WebAppData is data a Mini App sent to the bot with sendData
+type WebAppData struct {
+	Data       string `json:"data"`
+	ButtonText string `json:"button_text"`
+}
+
+// ChatMember is the status of a user in a chat. For a private chat with
+// the bot it tells whether the user blocked the bot.
+type ChatMember struct {
+	Status string `json:"status"` // "member", "kicked", ...
+	User   User   `json:"user"`
+}
+
+// Chat member statuses
+const (
+	ChatMemberMember = "member"
+	ChatMemberKicked = "kicked"
+)
+
+// ChatMemberUpdated reports a change of a chat member status
+type ChatMemberUpdated struct {
+	Chat          Chat       `json:"chat"`
+	From          User       `json:"from"`
+	Date          int64      `json:"date"`
+	OldChatMember ChatMember `json:"old_chat_member"`
+	NewChatMember ChatMember `json:"new_chat_member"`
+}
+
+// CallbackQuery is a press of an inline keyboard button with callback data
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+// CurrencyStars is the currency of payments in Telegram Stars
+const CurrencyStars = "XTR"
+
+// PreCheckoutQuery asks the bot to confirm an order before the user is
+// charged. It must be answered within 10 seconds.
+type PreCheckoutQuery struct {
+	ID             string `json:"id"`
+	From           User   `json:"from"`
+	Currency       string `json:"currency"`
+	TotalAmount    int    `json:"total_amount"`
+	InvoicePayload string `json:"invoice_payload"`
+}
+
+// SuccessfulPayment is the service message of a completed payment
+type SuccessfulPayment struct {
+	Currency                string `json:"currency"`
+	TotalAmount             int    `json:"total_amount"`
+	InvoicePayload          string `json:"invoice_payload"`
+	TelegramPaymentChargeID string `json:"telegram_payment_charge_id"`
+	ProviderPaymentChargeID string `json:"provider_payment_charge_id"`
+}
+
+// RefundedPayment is the service message of a refunded payment
+type RefundedPayment struct {
+	Currency                string `json:"currency"`
+	TotalAmount             int    `json:"total_amount"`
+	InvoicePayload          string `json:"invoice_payload"`
+	TelegramPaymentChargeID string `json:"telegram_payment_charge_id"`
+}
+
+// Update is an incoming update. At most one of the optional fields is set.
+type Update struct {
+	UpdateID      int64              `json:"update_id"`
+	Message       *Message           `json:"message,omitempty"`
+	MyChatMember  *ChatMemberUpdated `json:"my_chat_member,omitempty"`
+	CallbackQuery *CallbackQuery     `json:"callback_query,omitempty"`
+
+	PreCheckoutQuery *PreCheckoutQuery `json:"pre_checkout_query,omitempty"`
+}
+
+// Update kinds, see Update.Kind
+const (
+	UpdateMessage       = "message"
+	UpdateWebAppData    = "web_app_data"
+	UpdateMyChatMember  = "my_chat_member"
+	UpdateCallbackQuery = "callback_query"
+
+	UpdatePreCheckoutQuery  = "pre_checkout_query"
+	UpdateSuccessfulPayment = "successful_payment"
+	UpdateRefundedPayment   = "refunded_payment"
+)
+
+// Kind names the kind of update, empty for kinds the service doesn't
+// handle. Messages carrying Mini App data or payments are their own kinds.
+func (u *Update) Kind() string {
+	switch {
+	case u.Message != nil && u.Message.WebAppData != nil:
+		return UpdateWebAppData
+	case u.Message != nil && u.Message.SuccessfulPayment != nil:
+		return UpdateSuccessfulPayment
+	case u.Message != nil && u.Message.RefundedPayment != nil:
+		return UpdateRefundedPayment
+	case u.Message != nil:
+		return UpdateMessage
+	case u.MyChatMember != nil:
+		return UpdateMyChatMember
+	case u.CallbackQuery != nil:
+		return UpdateCallbackQuery
+	case u.PreCheckoutQuery != nil:
+		return UpdatePreCheckoutQuery
+	default:
+		return ""
+	}
+}
+
+// WebAppInfo names the Mini App opened by a button
+type WebAppInfo struct {
+	URL string `json:"url"`
+}
+
+// InlineKeyboardButton is a button below a message. Exactly one of the
+// optional fields must be set.
+type InlineKeyboardButton struct {
+	Text         string      `json:"text"`
+	URL          string      `json:"url,omitempty"`
+	CallbackData string      `json:"callback_data,omitempty"`
+	WebApp       *WebAppInfo `json:"web_app,omitempty"`
+}
+
+// InlineKeyboardMarkup is the keyboard below a message, as rows of buttons
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// WebAppButton returns a button opening the Mini App at url
+func WebAppButton(text, url string) InlineKeyboardButton {
+	return InlineKeyboardButton{Text: text, WebApp: &WebAppInfo{URL: url}}
+}
+
+// URLButton returns a button opening url in the browser
+func URLButton(text, url string) InlineKeyboardButton {
+	return InlineKeyboardButton{Text: text, URL: url}
+}
+
+// Keyboard returns a keyboard with one row per argument
+func Keyboard(rows ...[]InlineKeyboardButton) *InlineKeyboardMarkup {
+	return &InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// SendMessageParams are the parameters of sendMessage
+type SendMessageParams struct {
+	ChatID              int64                 `json:"chat_id"`
+	Text                string                `json:"text"`
+	ParseMode           string                `json:"parse_mode,omitempty"` // "HTML" or "MarkdownV2"
+	DisableNotification bool                  `json:"disable_notification,omitempty"`
+	ReplyMarkup         *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// SendPhotoParams are the parameters of sendPhoto. Photo is a file_id of
+// a photo already on Telegram's servers or an HTTP URL Telegram downloads.
+type SendPhotoParams struct {
+	ChatID              int64                 `json:"chat_id"`
+	Photo               string                `json:"photo"`
+	Caption             string                `json:"caption,omitempty"`
+	ParseMode           string                `json:"parse_mode,omitempty"`
+	DisableNotification bool                  `json:"disable_notification,omitempty"`
+	ReplyMarkup         *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// InputTextMessageContent is the text of a message sent on behalf of the
+// user
+type InputTextMessageContent struct {
+	MessageText string `json:"message_text"`
+	ParseMode   string `json:"parse_mode,omitempty"`
+}
+
+// InlineQueryResultArticle is a result sent through answerWebAppQuery
+type InlineQueryResultArticle struct {
+	Type                string                  `json:"type"` // always "article"
+	ID                  string                  `json:"id"`
+	Title               string                  `json:"title"`
+	InputMessageContent InputTextMessageContent `json:"input_message_content"`
+	ReplyMarkup         *InlineKeyboardMarkup   `json:"reply_markup,omitempty"`
+	Description         string                  `json:"description,omitempty"`
+}
+
+// SentWebAppMessage is the result of answerWebAppQuery
+type SentWebAppMessage struct {
+	InlineMessageID string `json:"inline_message_id,omitempty"`
+}
+
+// SetWebhookParams are the parameters of setWebhook
+type SetWebhookParams struct {
+	URL            string   `json:"url"`
+	SecretToken    string   `json:"secret_token,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}
+
+// LabeledPrice is a line of an invoice, in the smallest units of the
+// currency. Stars have no smaller unit.
+type LabeledPrice struct {
+	Label  string `json:"label"`
+	Amount int    `json:"amount"`
+}
+
+// CreateInvoiceLinkParams are the parameters of createInvoiceLink. Payload
+// comes back in the pre-checkout query and the successful payment, it is
+// not shown to the user.
+type CreateInvoiceLinkParams struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Payload     string         `json:"payload"`
+	Currency    string         `json:"currency"`
+	Prices      []LabeledPrice `json:"prices"`
+}
+
+type answerPreCheckoutQueryParams struct {
+	PreCheckoutQueryID string `json:"pre_checkout_query_id"`
+	OK                 bool   `json:"ok"`
+	ErrorMessage       string `json:"error_message,omitempty"`
+}
+
+type refundStarPaymentParams struct {
+	UserID                  int64  `json:"user_id"`
+	TelegramPaymentChargeID string `json:"telegram_payment_charge_id"`
+}
+
+type answerWebAppQueryParams struct {
+	WebAppQueryID string                   `json:"web_app_query_id"`
+	Result        InlineQueryResultArticle `json:"result"`
+}
+
+// response is the envelope of every Bot API response
+type response struct {
+	OK          bool                `json:"ok"`
+	Result      json.RawMessage     `json:"result,omitempty"`
+	ErrorCode   int                 `json:"error_code,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Parameters  *responseParameters `json:"parameters,omitempty"`
+}
+
+type responseParameters struct {
+	RetryAfter      int   `json:"retry_after,omitempty"`
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+}
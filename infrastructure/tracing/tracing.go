@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+)
+
+// Provider owns the global tracer provider and flushes it on shutdown
+type Provider struct {
+	tp     *sdktrace.TracerProvider
+	logger *zap.Logger
+}
+
+// NewProvider configures the global OpenTelemetry tracer provider and
+// propagator. When tracing is disabled the global no-op provider is kept,
+// so spans created by the application cost next to nothing.
+func NewProvider(cfg *config.AppConfig) (*Provider, error) {
+	logger := cfg.Logger.With(zap.String("component", "tracing"))
+	tracingCfg := cfg.Config.Tracing
+
+	// Propagate incoming trace context even when we don't export spans
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !tracingCfg.Enabled {
+		logger.Info("Tracing disabled")
+		return &Provider{logger: logger}, nil
+	}
+
+	exporter, err := newExporter(tracingCfg)
+	if err != nil {
+		logger.Error("Failed to create trace exporter", zap.Error(err))
+		return nil, err
+	}
+
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(tracingCfg.ServiceName),
+	)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tracingCfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("OpenTelemetry error", zap.Error(err))
+	}))
+
+	logger.Info("Tracing enabled",
+		zap.String("exporter", tracingCfg.Exporter),
+		zap.String("endpoint", tracingCfg.Endpoint),
+		zap.Float64("sample_ratio", tracingCfg.SampleRatio))
+
+	return &Provider{
+		tp:     tp,
+		logger: logger,
+	}, nil
+}
+
+// newExporter creates the span exporter selected in config
+func newExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "", "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", cfg.Exporter)
+	}
+}
+
+// Shutdown flushes pending spans and stops the exporter
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.tp == nil {
+		return nil
+	}
+
+	p.logger.Info("Flushing traces")
+	return p.tp.Shutdown(ctx)
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"go.uber.org/zap"
 	"os"
 	"os/signal"
@@ -10,10 +11,27 @@ import (
 
 	"renfound_v1/config"
 	"renfound_v1/infrastructure/auth"
+	"renfound_v1/infrastructure/dedupe"
+	"renfound_v1/infrastructure/idempotency"
+	"renfound_v1/infrastructure/metrics"
 	"renfound_v1/infrastructure/persistence/postgres"
+	"renfound_v1/infrastructure/persistence/redis"
+	"renfound_v1/infrastructure/ratelimit"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/infrastructure/tracing"
+	"renfound_v1/internal/delivery/bot"
+	"renfound_v1/internal/delivery/http/ops"
 	"renfound_v1/internal/delivery/http/router"
+	"renfound_v1/internal/health"
+	"renfound_v1/internal/usecase/audit"
+	"renfound_v1/internal/usecase/broadcast"
+	"renfound_v1/internal/usecase/login"
+	"renfound_v1/internal/usecase/notification"
+	"renfound_v1/internal/usecase/payment"
+	"renfound_v1/internal/usecase/referral"
 	"renfound_v1/internal/usecase/user"
 	"renfound_v1/internal/utils/async"
+	"renfound_v1/migrations"
 )
 
 // App represents the application
@@ -21,8 +39,18 @@ type App struct {
 	cfg        *config.AppConfig
 	router     *router.Router
 	db         *postgres.Database
+	redis      *redis.Client
+	opsServer  *ops.Server
+	tracing    *tracing.Provider
 	workerPool *async.WorkerPool
+	bot        *telegram.Client  // nil without a bot token
+	broadcasts broadcast.Service // nil without a bot token
 	logger     *zap.Logger
+
+	// stopBroadcasts ends broadcast delivery, broadcastsDone is closed
+	// once it has released its claims
+	stopBroadcasts context.CancelFunc
+	broadcastsDone chan struct{}
 }
 
 // NewApp creates a new application
@@ -35,6 +63,12 @@ func NewApp() (*App, error) {
 
 	logger := cfg.Logger
 
+	// Set up tracing before anything starts creating spans
+	tracingProvider, err := tracing.NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create database connection
 	db, err := postgres.NewDatabase(cfg)
 	if err != nil {
@@ -42,27 +76,110 @@ func NewApp() (*App, error) {
 		return nil, err
 	}
 
+	// Create redis connection when configured
+	var redisClient *redis.Client
+	if cfg.Config.Redis.URL != "" {
+		redisClient, err = redis.NewClient(cfg)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	// Create rate limiter
+	limiter, err := newRateLimiter(cfg.Config.RateLimit, redisClient)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Create idempotency store
+	idempotencyStore, err := newIdempotencyStore(cfg.Config.Idempotency, db, redisClient)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	// Create worker pool for async operations
-	workerPool := async.NewWorkerPool(10, 100, logger)
+	workerPool := async.NewWorkerPool(cfg.Config.WorkerPool.Workers, workerLanes(cfg.Config.WorkerPool), logger)
+
+	// Create metrics, served on the internal ops listener
+	var appMetrics *metrics.Metrics
+	if cfg.Config.Metrics.Enabled {
+		appMetrics = metrics.NewMetrics()
+		appMetrics.RegisterDBPool(db.Pool)
+		appMetrics.RegisterWorkerPool(workerPool)
+	}
+
+	// Create health checks
+	healthRegistry := newHealthRegistry(cfg, db, redisClient, workerPool)
+	opsServer := ops.NewServer(cfg, appMetrics, healthRegistry)
 
 	// Create repositories
 	userRepo := postgres.NewUserRepository(db, logger)
+	auditRepo := postgres.NewAuditRepository(db, logger)
+	loginRepo := postgres.NewLoginRepository(db, logger)
+	broadcastRepo := postgres.NewBroadcastRepository(db, logger)
+	referralRepo := postgres.NewReferralRepository(db, logger)
+	purchaseRepo := postgres.NewPurchaseRepository(db, logger)
 
 	// Create auth service
 	telegramAuth := auth.NewTelegramAuth(cfg)
 
+	// Create the bot client when there is a bot to talk to
+	var botClient *telegram.Client
+	var notificationService notification.Service
+	if cfg.Config.Telegram.BotToken != "" {
+		botClient = telegram.NewClient(cfg)
+		notificationService = notification.NewService(cfg, userRepo, botClient)
+	} else {
+		logger.Warn("Telegram bot token not set, bot features are disabled")
+	}
+
 	// Create services
-	userService := user.NewService(cfg, userRepo, telegramAuth, workerPool)
+	auditService := audit.NewService(cfg, auditRepo, workerPool)
+	var notifier login.Notifier
+	var broadcastService broadcast.Service
+	if notificationService != nil {
+		notifier = notificationService
+		// Replicas share the broadcast rate through redis, so together they
+		// stay below the bot wide limit of Telegram
+		var broadcastLimiter ratelimit.Limiter
+		if redisClient != nil {
+			broadcastLimiter = ratelimit.NewRedisLimiter(redisClient)
+		}
+		broadcastService = broadcast.NewService(cfg, broadcastRepo, notificationService, auditService, broadcastLimiter)
+	}
+	// Payments are confirmed and completed through webhook updates
+	var paymentService payment.Service
+	if botClient != nil && cfg.Config.Telegram.Webhook.Secret != "" {
+		paymentService = payment.NewService(cfg, purchaseRepo, userRepo, botClient, auditService)
+	}
+	loginService := login.NewService(cfg, loginRepo, notifier, workerPool)
+	referralService := referral.NewService(cfg, referralRepo)
+	userService := user.NewService(cfg, userRepo, telegramAuth, auditService, loginService, referralService, workerPool, appMetrics)
+
+	// Create bot update dispatcher
+	dispatcher, err := newDispatcher(cfg, botClient, userService, paymentService, redisClient, workerPool)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
 
 	// Create router
-	r := router.NewRouter(cfg, userService, telegramAuth)
+	r := router.NewRouter(cfg, userService, auditService, loginService, referralService, telegramAuth, limiter, idempotencyStore, appMetrics, healthRegistry, dispatcher, broadcastService, paymentService)
 	r.SetupRoutes()
 
 	return &App{
 		cfg:        cfg,
 		router:     r,
 		db:         db,
+		redis:      redisClient,
+		opsServer:  opsServer,
+		tracing:    tracingProvider,
 		workerPool: workerPool,
+		bot:        botClient,
+		broadcasts: broadcastService,
 		logger:     logger,
 	}, nil
 }
@@ -76,6 +193,35 @@ func (a *App) Run() error {
 		}
 	}()
 
+	// Start internal ops server
+	go func() {
+		if err := a.opsServer.Start(); err != nil {
+			a.logger.Error("Failed to start ops server", zap.Error(err))
+		}
+	}()
+
+	// Point Telegram at the webhook
+	if a.bot != nil && a.cfg.Config.Telegram.Webhook.URL != "" {
+		go a.registerWebhook()
+	}
+
+	// Deliver broadcasts in the background
+	if a.broadcasts != nil {
+		var ctx context.Context
+		ctx, a.stopBroadcasts = context.WithCancel(context.Background())
+		a.broadcastsDone = make(chan struct{})
+		go func() {
+			defer close(a.broadcastsDone)
+			a.broadcasts.Run(ctx)
+		}()
+	}
+
+	// SIGHUP reloads the log levels
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go a.reloadLogLevels(hup)
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
@@ -83,22 +229,192 @@ func (a *App) Run() error {
 
 	a.logger.Info("Shutting down application...")
 
-	// Shutdown worker pool
-	a.workerPool.Shutdown(false)
-
 	// Create a deadline for graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Shutdown the server with context
+	// Stop accepting HTTP requests first so in-flight handlers can still
+	// submit tasks to the worker pool
 	if err := a.router.Shutdown(ctx); err != nil {
-		a.logger.Fatal("Server forced to shutdown", zap.Error(err))
-		return err
+		a.logger.Error("Server forced to shutdown", zap.Error(err))
+	}
+
+	// Stop broadcasting, unsent recipients are picked up after restart
+	if a.stopBroadcasts != nil {
+		a.stopBroadcasts()
+		select {
+		case <-a.broadcastsDone:
+		case <-ctx.Done():
+			a.logger.Warn("Broadcast delivery did not stop in time")
+		}
+	}
+
+	// Drain queued tasks within the remaining deadline
+	if abandoned, err := a.workerPool.Shutdown(ctx); err != nil {
+		a.logger.Warn("Worker pool did not drain in time",
+			zap.Int("abandoned_tasks", abandoned),
+			zap.Error(err))
+	}
+
+	// Metrics are still served while draining, stop the ops server last
+	if err := a.opsServer.Shutdown(ctx); err != nil {
+		a.logger.Error("Ops server forced to shutdown", zap.Error(err))
+	}
+
+	// Flush spans recorded while draining
+	if err := a.tracing.Shutdown(ctx); err != nil {
+		a.logger.Error("Failed to flush traces", zap.Error(err))
 	}
 
 	// Close database connection
 	a.db.Close()
 
+	// Close redis connection
+	if a.redis != nil {
+		a.redis.Close()
+	}
+
 	a.logger.Info("Application gracefully stopped")
 	return nil
 }
+
+// registerWebhook tells Telegram where to deliver bot updates
+func (a *App) registerWebhook() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	webhookCfg := a.cfg.Config.Telegram.Webhook
+	err := a.bot.SetWebhook(ctx, telegram.SetWebhookParams{
+		URL:         webhookCfg.URL,
+		SecretToken: webhookCfg.Secret,
+		AllowedUpdates: []string{
+			telegram.UpdateMessage,
+			telegram.UpdateMyChatMember,
+			telegram.UpdateCallbackQuery,
+			telegram.UpdatePreCheckoutQuery,
+		},
+	})
+	if err != nil {
+		a.logger.Error("Failed to register Telegram webhook", zap.Error(err))
+		return
+	}
+	a.logger.Info("Telegram webhook registered", zap.String("url", webhookCfg.URL))
+}
+
+// reloadLogLevels reapplies the configured log levels on every signal,
+// undoing changes made through the ops server
+func (a *App) reloadLogLevels(signals <-chan os.Signal) {
+	for range signals {
+		if err := a.cfg.ReloadLogLevels(); err != nil {
+			a.logger.Error("Failed to reload log levels", zap.Error(err))
+			continue
+		}
+		a.logger.Warn("Log levels reloaded", zap.String("level", a.cfg.LogLevels.Level().String()))
+	}
+}
+
+// workerLanes converts the configured lanes to worker pool lane settings
+func workerLanes(cfg config.WorkerPoolConfig) []async.LaneConfig {
+	lanes := make([]async.LaneConfig, 0, len(cfg.Lanes))
+	for _, l := range cfg.Lanes {
+		lanes = append(lanes, async.LaneConfig{
+			Name:           l.Name,
+			Priority:       l.Priority,
+			QueueSize:      l.QueueSize,
+			MaxConcurrency: l.MaxConcurrency,
+			RateLimit:      l.RateLimit,
+			RateBurst:      l.RateBurst,
+		})
+	}
+	return lanes
+}
+
+// newRateLimiter creates the rate limiter backend selected in config
+func newRateLimiter(cfg config.RateLimitConfig, redisClient *redis.Client) (ratelimit.Limiter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	switch cfg.Backend {
+	case "", "memory":
+		return ratelimit.NewMemoryLimiter(), nil
+	case "redis":
+		if redisClient == nil {
+			return nil, fmt.Errorf("redis rate limit backend requires redis.url to be set")
+		}
+		return ratelimit.NewRedisLimiter(redisClient), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend: %s", cfg.Backend)
+	}
+}
+
+// newIdempotencyStore creates the idempotency store backend selected in config
+func newIdempotencyStore(cfg config.IdempotencyConfig, db *postgres.Database, redisClient *redis.Client) (idempotency.Store, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	switch cfg.Backend {
+	case "", "postgres":
+		return idempotency.NewPostgresStore(db.Pool), nil
+	case "redis":
+		if redisClient == nil {
+			return nil, fmt.Errorf("redis idempotency backend requires redis.url to be set")
+		}
+		return idempotency.NewRedisStore(redisClient), nil
+	default:
+		return nil, fmt.Errorf("unknown idempotency backend: %s", cfg.Backend)
+	}
+}
+
+// newDispatcher creates the bot update dispatcher with the bot's handlers.
+// The webhook is disabled without a bot or a webhook secret.
+func newDispatcher(cfg *config.AppConfig, botClient *telegram.Client, userService user.Service, paymentService payment.Service, redisClient *redis.Client, workerPool *async.WorkerPool) (*bot.Dispatcher, error) {
+	webhookCfg := cfg.Config.Telegram.Webhook
+	if botClient == nil || webhookCfg.Secret == "" {
+		return nil, nil
+	}
+
+	var store dedupe.Store
+	switch webhookCfg.DedupeBackend {
+	case "", "memory":
+		store = dedupe.NewMemoryStore()
+	case "redis":
+		if redisClient == nil {
+			return nil, fmt.Errorf("redis dedupe backend requires redis.url to be set")
+		}
+		store = dedupe.NewRedisStore(redisClient)
+	default:
+		return nil, fmt.Errorf("unknown dedupe backend: %s", webhookCfg.DedupeBackend)
+	}
+
+	dispatcher := bot.NewDispatcher(cfg, store, workerPool)
+	bot.NewHandlers(cfg, botClient, userService).Register(dispatcher)
+	if paymentService != nil {
+		bot.NewPaymentHandlers(cfg, paymentService).Register(dispatcher)
+	}
+	return dispatcher, nil
+}
+
+// newHealthRegistry registers the readiness checks of every dependency and
+// the liveness checks of the process
+func newHealthRegistry(cfg *config.AppConfig, db *postgres.Database, redisClient *redis.Client, workerPool *async.WorkerPool) *health.Registry {
+	healthCfg := cfg.Config.Health
+	registry := health.NewRegistry(healthCfg.CheckTimeout, healthCfg.CacheTTL, cfg.Logger)
+
+	registry.Register("postgres", health.PostgresCheck(db), health.Options{})
+	registry.Register("migrations", health.MigrationCheck(db, migrations.LatestVersion()), health.Options{
+		// The schema only changes on deploy
+		CacheTTL: time.Minute,
+	})
+	registry.Register("worker_pool", health.WorkerPoolCheck(workerPool, healthCfg.QueueSaturation), health.Options{})
+	registry.Register("worker_pool_stall", health.WorkerPoolStallCheck(workerPool, healthCfg.StallTimeout), health.Options{
+		Kind: health.Liveness,
+	})
+
+	if redisClient != nil {
+		registry.Register("redis", health.RedisCheck(redisClient), health.Options{})
+	}
+
+	return registry
+}
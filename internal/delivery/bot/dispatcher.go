@@ -0,0 +1,121 @@
+// Package bot handles the updates Telegram delivers to the bot
+package bot
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/dedupe"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/internal/utils/async"
+	"renfound_v1/internal/utils/logctx"
+)
+
+var tracer = otel.Tracer("renfound_v1/internal/delivery/bot")
+
+// HandlerFunc handles an update
+type HandlerFunc func(ctx context.Context, update *telegram.Update) error
+
+// Dispatcher routes updates to the handlers registered for them. Commands
+// go to their command handler, other updates to the handlers of their
+// kind. Handlers must be registered before updates are submitted.
+type Dispatcher struct {
+	commands   map[string]HandlerFunc
+	handlers   map[string][]HandlerFunc
+	store      dedupe.Store
+	dedupeTTL  time.Duration
+	workerPool *async.WorkerPool
+	logger     *zap.Logger
+}
+
+func NewDispatcher(cfg *config.AppConfig, store dedupe.Store, workerPool *async.WorkerPool) *Dispatcher {
+	return &Dispatcher{
+		commands:   make(map[string]HandlerFunc),
+		handlers:   make(map[string][]HandlerFunc),
+		store:      store,
+		dedupeTTL:  cfg.Config.Telegram.Webhook.DedupeTTL,
+		workerPool: workerPool,
+		logger:     cfg.Logger.With(zap.String("component", "bot_dispatcher")),
+	}
+}
+
+// Command registers the handler of a command, named without the slash
+func (d *Dispatcher) Command(name string, handler HandlerFunc) {
+	d.commands[name] = handler
+}
+
+// On registers a handler for a kind of update, see telegram.Update.Kind.
+// Handlers of a kind run in registration order.
+func (d *Dispatcher) On(kind string, handler HandlerFunc) {
+	d.handlers[kind] = append(d.handlers[kind], handler)
+}
+
+// Submit queues the update for dispatch, reporting false when the worker
+// pool is full. Updates may be handled out of order.
+func (d *Dispatcher) Submit(ctx context.Context, update *telegram.Update) bool {
+	return d.workerPool.SubmitContext(ctx, lane(update), func(taskCtx context.Context) {
+		d.Dispatch(taskCtx, update)
+	})
+}
+
+// lane returns the worker pool lane of an update. Payments go ahead of
+// other updates, Telegram cancels an order whose pre-checkout query is not
+// answered within 10 seconds.
+func lane(update *telegram.Update) string {
+	switch update.Kind() {
+	case telegram.UpdatePreCheckoutQuery, telegram.UpdateSuccessfulPayment, telegram.UpdateRefundedPayment:
+		return async.LaneCritical
+	default:
+		return async.LaneDefault
+	}
+}
+
+// Dispatch runs the handlers of the update unless it was seen before.
+// Handler errors are logged, Telegram is not told about them.
+func (d *Dispatcher) Dispatch(ctx context.Context, update *telegram.Update) {
+	ctx, span := tracer.Start(ctx, "Bot.Dispatch")
+	defer span.End()
+
+	kind := update.Kind()
+	span.SetAttributes(
+		attribute.Int64("telegram.update_id", update.UpdateID),
+		attribute.String("telegram.update_kind", kind))
+	logger := logctx.Logger(ctx, d.logger).With(
+		zap.Int64("update_id", update.UpdateID),
+		zap.String("update_kind", kind))
+
+	// Telegram redelivers updates it got no answer for. Handling an update
+	// twice is preferred to losing it when the store is down.
+	first, err := d.store.FirstSeen(ctx, "telegram_update:"+strconv.FormatInt(update.UpdateID, 10), d.dedupeTTL)
+	if err != nil {
+		logger.Warn("Failed to deduplicate update", zap.Error(err))
+	} else if !first {
+		logger.Debug("Skipping duplicate update")
+		return
+	}
+
+	handlers := d.handlers[kind]
+	if kind == telegram.UpdateMessage {
+		if name, _ := update.Message.Command(); name != "" {
+			if handler, ok := d.commands[name]; ok {
+				handlers = []HandlerFunc{handler}
+			}
+		}
+	}
+
+	if len(handlers) == 0 {
+		logger.Debug("No handler for update")
+		return
+	}
+
+	for _, handler := range handlers {
+		if err := handler(ctx, update); err != nil {
+			logger.Error("Failed to handle update", zap.Error(err))
+		}
+	}
+}
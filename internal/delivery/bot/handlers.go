@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/internal/i18n"
+	"renfound_v1/internal/usecase/user"
+	"renfound_v1/internal/utils/logctx"
+)
+
+// Handlers are the bot's own update handlers
+type Handlers struct {
+	client      *telegram.Client
+	userService user.Service
+	webAppURL   string
+	logger      *zap.Logger
+}
+
+func NewHandlers(cfg *config.AppConfig, client *telegram.Client, userService user.Service) *Handlers {
+	return &Handlers{
+		client:      client,
+		userService: userService,
+		webAppURL:   cfg.Config.Telegram.WebAppURL,
+		logger:      cfg.Logger.With(zap.String("component", "bot_handlers")),
+	}
+}
+
+// Register adds the handlers to the dispatcher
+func (h *Handlers) Register(d *Dispatcher) {
+	d.Command("start", h.Start)
+	d.On(telegram.UpdateWebAppData, h.WebAppData)
+	d.On(telegram.UpdateMyChatMember, h.MyChatMember)
+}
+
+// Start greets the user with a button opening the Mini App. Deep links
+// such as t.me/bot?start=payload arrive as the command argument.
+func (h *Handlers) Start(ctx context.Context, update *telegram.Update) error {
+	message := update.Message
+	_, payload := message.Command()
+
+	locale := i18n.DefaultLocale
+	if message.From != nil {
+		if l, ok := i18n.Normalize(message.From.LanguageCode); ok {
+			locale = l
+		}
+
+		// Sending /start again is how users come back after blocking the bot
+		if err := h.userService.SetBotReachable(ctx, message.From.ID, true); err != nil {
+			return fmt.Errorf("failed to mark user reachable: %w", err)
+		}
+	}
+
+	if payload != "" {
+		logctx.Logger(ctx, h.logger).Info("Bot started from deep link", zap.String("payload", payload))
+	}
+
+	params := telegram.SendMessageParams{
+		ChatID: message.Chat.ID,
+		Text:   i18n.T(locale, "bot.welcome", "Welcome! Open the app to get started.", nil),
+	}
+	if h.webAppURL != "" {
+		params.ReplyMarkup = telegram.Keyboard([]telegram.InlineKeyboardButton{
+			telegram.WebAppButton(i18n.T(locale, "bot.open_app", "Open app", nil), h.webAppURL),
+		})
+	}
+
+	if _, err := h.client.SendMessage(ctx, params); err != nil {
+		if errors.Is(err, telegram.ErrForbidden) && message.From != nil {
+			// Blocked again before the welcome message went out
+			if err := h.userService.SetBotReachable(ctx, message.From.ID, false); err != nil {
+				return fmt.Errorf("failed to mark user unreachable: %w", err)
+			}
+			return nil
+		}
+		return fmt.Errorf("failed to send welcome message: %w", err)
+	}
+	return nil
+}
+
+// WebAppData receives data sent by the Mini App with Telegram.WebApp.sendData
+func (h *Handlers) WebAppData(ctx context.Context, update *telegram.Update) error {
+	data := update.Message.WebAppData
+
+	logctx.Logger(ctx, h.logger).Info("Mini App data received",
+		zap.String("button_text", data.ButtonText),
+		zap.Int("size", len(data.Data)))
+	return nil
+}
+
+// MyChatMember follows users stopping and restarting the bot. In a
+// private chat the bot is kicked when the user blocks it.
+func (h *Handlers) MyChatMember(ctx context.Context, update *telegram.Update) error {
+	member := update.MyChatMember
+
+	logctx.Logger(ctx, h.logger).Info("Bot chat member status changed",
+		zap.Int64("chat_id", member.Chat.ID),
+		zap.String("old_status", member.OldChatMember.Status),
+		zap.String("new_status", member.NewChatMember.Status))
+
+	if member.Chat.Type != "private" {
+		return nil
+	}
+
+	var reachable bool
+	switch member.NewChatMember.Status {
+	case telegram.ChatMemberKicked:
+		reachable = false
+	case telegram.ChatMemberMember:
+		reachable = true
+	default:
+		return nil
+	}
+
+	if err := h.userService.SetBotReachable(ctx, member.From.ID, reachable); err != nil {
+		return fmt.Errorf("failed to update bot reachability: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/internal/usecase/payment"
+	"renfound_v1/internal/utils/logctx"
+)
+
+// PaymentHandlers handle the updates of Telegram Stars payments
+type PaymentHandlers struct {
+	paymentService payment.Service
+	logger         *zap.Logger
+}
+
+func NewPaymentHandlers(cfg *config.AppConfig, paymentService payment.Service) *PaymentHandlers {
+	return &PaymentHandlers{
+		paymentService: paymentService,
+		logger:         cfg.Logger.With(zap.String("component", "bot_payment_handlers")),
+	}
+}
+
+// Register adds the handlers to the dispatcher
+func (h *PaymentHandlers) Register(d *Dispatcher) {
+	d.On(telegram.UpdatePreCheckoutQuery, h.PreCheckoutQuery)
+	d.On(telegram.UpdateSuccessfulPayment, h.SuccessfulPayment)
+	d.On(telegram.UpdateRefundedPayment, h.RefundedPayment)
+}
+
+// PreCheckoutQuery confirms or rejects an order before the user is charged
+func (h *PaymentHandlers) PreCheckoutQuery(ctx context.Context, update *telegram.Update) error {
+	if err := h.paymentService.PreCheckout(ctx, update.PreCheckoutQuery); err != nil {
+		return fmt.Errorf("failed to answer pre-checkout query: %w", err)
+	}
+	return nil
+}
+
+// SuccessfulPayment records a payment, granting what was bought
+func (h *PaymentHandlers) SuccessfulPayment(ctx context.Context, update *telegram.Update) error {
+	message := update.Message
+	if message.From == nil {
+		logctx.Logger(ctx, h.logger).Error("Payment without a payer",
+			zap.String("charge_id", message.SuccessfulPayment.TelegramPaymentChargeID))
+		return nil
+	}
+
+	if err := h.paymentService.CompletePayment(ctx, message.From.ID, message.SuccessfulPayment); err != nil {
+		return fmt.Errorf("failed to complete payment: %w", err)
+	}
+	return nil
+}
+
+// RefundedPayment records a refund, revoking what was bought
+func (h *PaymentHandlers) RefundedPayment(ctx context.Context, update *telegram.Update) error {
+	if err := h.paymentService.RecordRefund(ctx, update.Message.RefundedPayment); err != nil {
+		return fmt.Errorf("failed to record refund: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Package authcookie carries refresh tokens in HttpOnly cookies and issues
+// the matching double-submit CSRF tokens
+package authcookie
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"renfound_v1/config"
+)
+
+// CSRFHeader is the request header that must echo the CSRF cookie, and the
+// response header the token is returned in for cross-origin clients that
+// cannot read the cookie
+const CSRFHeader = "X-CSRF-Token"
+
+// Manager sets, reads and clears the auth cookies
+type Manager struct {
+	enabled bool
+	cookie  config.AuthCookieConfig
+	ttl     time.Duration
+	csrfKey []byte
+}
+
+func NewManager(cfg *config.Config) *Manager {
+	return &Manager{
+		enabled: cfg.Auth.RefreshTokenMode == "cookie",
+		cookie:  cfg.Auth.Cookie,
+		ttl:     cfg.JWT.RefreshTTL,
+		csrfKey: []byte(cfg.JWT.RefreshSecret),
+	}
+}
+
+// Enabled reports whether refresh tokens travel in cookies
+func (m *Manager) Enabled() bool {
+	return m.enabled
+}
+
+// SetRefreshToken stores the refresh token in an HttpOnly cookie scoped to
+// the auth routes, and issues a CSRF token bound to it
+func (m *Manager) SetRefreshToken(c *fiber.Ctx, refreshToken string) {
+	expires := time.Now().Add(m.ttl)
+	csrfToken := m.csrfToken(refreshToken)
+
+	for _, path := range m.cookie.Paths {
+		m.setCookie(c, &http.Cookie{
+			Name:     m.cookie.Name,
+			Value:    refreshToken,
+			Path:     path,
+			Expires:  expires,
+			HttpOnly: true,
+		})
+	}
+
+	// Readable by scripts so same-origin clients can echo it back
+	m.setCookie(c, &http.Cookie{
+		Name:    m.cookie.CSRFName,
+		Value:   csrfToken,
+		Path:    "/",
+		Expires: expires,
+	})
+	c.Set(CSRFHeader, csrfToken)
+}
+
+// RefreshToken returns the refresh token cookie, empty if absent or if
+// cookie mode is disabled
+func (m *Manager) RefreshToken(c *fiber.Ctx) string {
+	if !m.enabled {
+		return ""
+	}
+	return c.Cookies(m.cookie.Name)
+}
+
+// Clear expires both cookies
+func (m *Manager) Clear(c *fiber.Ctx) {
+	for _, path := range m.cookie.Paths {
+		m.setCookie(c, &http.Cookie{Name: m.cookie.Name, Path: path, Expires: time.Unix(0, 0), HttpOnly: true})
+	}
+	m.setCookie(c, &http.Cookie{Name: m.cookie.CSRFName, Path: "/", Expires: time.Unix(0, 0)})
+}
+
+// setCookie adds a Set-Cookie header with the configured attributes.
+// fiber.Ctx.Cookie keeps a single cookie per name, the refresh token
+// cookie is set once per path.
+func (m *Manager) setCookie(c *fiber.Ctx, cookie *http.Cookie) {
+	cookie.Domain = m.cookie.Domain
+	cookie.Secure = m.cookie.Secure
+	switch m.cookie.SameSite {
+	case "Strict":
+		cookie.SameSite = http.SameSiteStrictMode
+	case "Lax":
+		cookie.SameSite = http.SameSiteLaxMode
+	case "None":
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+	c.Response().Header.Add(fiber.HeaderSetCookie, cookie.String())
+}
+
+// VerifyCSRF checks the double-submit token: the header must match the CSRF
+// cookie, and both must be bound to the refresh token cookie
+func (m *Manager) VerifyCSRF(c *fiber.Ctx) bool {
+	header := c.Get(CSRFHeader)
+	if header == "" {
+		return false
+	}
+
+	expected := m.csrfToken(m.RefreshToken(c))
+	return subtle.ConstantTimeCompare([]byte(header), []byte(c.Cookies(m.cookie.CSRFName))) == 1 &&
+		subtle.ConstantTimeCompare([]byte(header), []byte(expected)) == 1
+}
+
+// csrfToken derives the CSRF token from the refresh token, so a token
+// planted by a sibling subdomain cannot pass verification
+func (m *Manager) csrfToken(refreshToken string) string {
+	mac := hmac.New(sha256.New, m.csrfKey)
+	mac.Write([]byte("csrf:" + refreshToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
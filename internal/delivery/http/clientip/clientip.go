@@ -0,0 +1,89 @@
+// Package clientip resolves the address of the client behind trusted
+// reverse proxies
+package clientip
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// localsKey is the fiber Locals key holding the resolved address
+const localsKey = "clientIP"
+
+// Resolver resolves client addresses. Forwarding headers are honored only on
+// connections from trusted proxies, since anyone else can forge them.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+func NewResolver(trusted []netip.Prefix) *Resolver {
+	return &Resolver{trusted: trusted}
+}
+
+// Resolve stores the client address for FromCtx
+func (r *Resolver) Resolve() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(localsKey, r.resolve(c))
+		return c.Next()
+	}
+}
+
+// resolve walks X-Forwarded-For from the right, skipping trusted proxies;
+// the first untrusted hop is the client. X-Real-IP is used when the proxy
+// does not send X-Forwarded-For.
+func (r *Resolver) resolve(c *fiber.Ctx) string {
+	peer, ok := netip.AddrFromSlice(c.Context().RemoteIP())
+	if !ok {
+		return c.IP()
+	}
+	peer = peer.Unmap()
+
+	if !r.isTrusted(peer) {
+		return peer.String()
+	}
+
+	if forwarded := c.Get(fiber.HeaderXForwardedFor); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		client := peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				// A malformed hop can't be trusted, neither can anything left of it
+				break
+			}
+			client = addr.Unmap()
+			if !r.isTrusted(client) {
+				break
+			}
+		}
+		return client.String()
+	}
+
+	if realIP := c.Get("X-Real-IP"); realIP != "" {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(realIP)); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+
+	return peer.String()
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromCtx returns the client address resolved by the Resolve middleware,
+// falling back to the connection address
+func FromCtx(c *fiber.Ctx) string {
+	if ip, ok := c.Locals(localsKey).(string); ok {
+		return ip
+	}
+	return c.IP()
+}
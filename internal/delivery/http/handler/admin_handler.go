@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"renfound_v1/internal/delivery/http/locale"
+	"renfound_v1/internal/delivery/http/problem"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/usecase/audit"
+	"renfound_v1/internal/usecase/referral"
+	"renfound_v1/internal/usecase/user"
+	"renfound_v1/internal/utils/validator"
+)
+
+type AdminHandler struct {
+	userService     user.Service
+	auditService    audit.Service
+	referralService referral.Service
+	validator       *validator.Validator
+	logger          *zap.Logger
+}
+
+func NewAdminHandler(
+	userService user.Service,
+	auditService audit.Service,
+	referralService referral.Service,
+	validator *validator.Validator,
+	logger *zap.Logger,
+) *AdminHandler {
+	return &AdminHandler{
+		userService:     userService,
+		auditService:    auditService,
+		referralService: referralService,
+		validator:       validator,
+		logger:          logger.With(zap.String("component", "admin_handler")),
+	}
+}
+
+// AuditEventsRequest filters the audit log
+type AuditEventsRequest struct {
+	ActorID  string `json:"actor_id" query:"actor_id" validate:"omitempty,uuid"`
+	TargetID string `json:"target_id" query:"target_id" validate:"omitempty,uuid"`
+	Action   string `json:"action" query:"action" validate:"omitempty,max=64"`
+	Outcome  string `json:"outcome" query:"outcome" validate:"omitempty,oneof=success failure"`
+	From     string `json:"from" query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To       string `json:"to" query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Cursor   string `json:"cursor" query:"cursor" validate:"omitempty,max=128"`
+	Limit    int    `json:"limit" query:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+// AuditEvents searches the audit log
+func (h *AdminHandler) AuditEvents(c *fiber.Ctx) error {
+	var req AuditEventsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return problem.Error(c, models.ErrBadRequest)
+	}
+
+	if validationErrors, err := h.validator.Validate(req); err != nil {
+		return problem.Error(c, models.ErrInternalServer)
+	} else if len(validationErrors) > 0 {
+		return problem.Validation(c, validationErrors)
+	}
+
+	// Formats were checked by the validator
+	query := audit.Query{
+		Action:  req.Action,
+		Outcome: req.Outcome,
+		Cursor:  req.Cursor,
+		Limit:   req.Limit,
+	}
+	if req.ActorID != "" {
+		id := uuid.MustParse(req.ActorID)
+		query.ActorID = &id
+	}
+	if req.TargetID != "" {
+		id := uuid.MustParse(req.TargetID)
+		query.TargetID = &id
+	}
+	if req.From != "" {
+		query.From, _ = time.Parse(time.RFC3339, req.From)
+	}
+	if req.To != "" {
+		query.To, _ = time.Parse(time.RFC3339, req.To)
+	}
+
+	page, err := h.auditService.Search(c.UserContext(), query)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+// LogoutAllUser revokes every session of a user
+func (h *AdminHandler) LogoutAllUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return problem.Error(c, models.ErrUserNotFound)
+	}
+
+	if err := h.userService.AdminLogoutAll(c.UserContext(), userID); err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(MessageResponse{
+		Success: true,
+		Message: locale.T(c, "message.user_logged_out_all", "All sessions of the user logged out successfully"),
+	})
+}
+
+// CampaignStatsRequest limits the number of campaigns listed
+type CampaignStatsRequest struct {
+	Limit int `json:"limit" query:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+// CampaignStatsResponse is the campaigns that brought in most signups
+type CampaignStatsResponse struct {
+	Campaigns []*models.CampaignStats `json:"campaigns"`
+}
+
+// CampaignStats lists the signups brought in per campaign
+func (h *AdminHandler) CampaignStats(c *fiber.Ctx) error {
+	var req CampaignStatsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return problem.Error(c, models.ErrBadRequest)
+	}
+
+	if validationErrors, err := h.validator.Validate(req); err != nil {
+		return problem.Error(c, models.ErrInternalServer)
+	} else if len(validationErrors) > 0 {
+		return problem.Validation(c, validationErrors)
+	}
+
+	campaigns, err := h.referralService.CampaignStats(c.UserContext(), req.Limit)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(CampaignStatsResponse{Campaigns: campaigns})
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"renfound_v1/internal/delivery/http/problem"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/usecase/broadcast"
+	"renfound_v1/internal/utils/validator"
+)
+
+// BroadcastHandler serves the admin API of broadcasts
+type BroadcastHandler struct {
+	broadcastService broadcast.Service
+	validator        *validator.Validator
+	logger           *zap.Logger
+}
+
+func NewBroadcastHandler(broadcastService broadcast.Service, validator *validator.Validator, logger *zap.Logger) *BroadcastHandler {
+	return &BroadcastHandler{
+		broadcastService: broadcastService,
+		validator:        validator,
+		logger:           logger.With(zap.String("component", "broadcast_handler")),
+	}
+}
+
+// CreateBroadcastRequest is a message and the segment of users it goes to.
+// Without filters it goes to every user.
+type CreateBroadcastRequest struct {
+	Text             string `json:"text" validate:"required,max=4096"`
+	LanguageCode     string `json:"language_code" validate:"omitempty,max=16"`
+	ActiveWithinDays int    `json:"active_within_days" validate:"omitempty,min=1,max=365"`
+}
+
+// CreateBroadcast starts a broadcast
+func (h *BroadcastHandler) CreateBroadcast(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_user_id")
+	}
+
+	var req CreateBroadcastRequest
+	if err := c.BodyParser(&req); err != nil {
+		return problem.ErrorWithDetail(c, models.ErrBadRequest, "detail.invalid_request_body")
+	}
+
+	if validationErrors, err := h.validator.Validate(req); err != nil {
+		return problem.Error(c, models.ErrInternalServer)
+	} else if len(validationErrors) > 0 {
+		return problem.Validation(c, validationErrors)
+	}
+
+	result, err := h.broadcastService.Create(c.UserContext(), userID, req.Text, models.BroadcastSegment{
+		LanguageCode:     req.LanguageCode,
+		ActiveWithinDays: req.ActiveWithinDays,
+	})
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// BroadcastsRequest limits the number of broadcasts listed
+type BroadcastsRequest struct {
+	Limit int `json:"limit" query:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+// BroadcastsResponse is the latest broadcasts, newest first
+type BroadcastsResponse struct {
+	Broadcasts []*models.Broadcast `json:"broadcasts"`
+}
+
+// Broadcasts lists the latest broadcasts
+func (h *BroadcastHandler) Broadcasts(c *fiber.Ctx) error {
+	var req BroadcastsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return problem.Error(c, models.ErrBadRequest)
+	}
+
+	if validationErrors, err := h.validator.Validate(req); err != nil {
+		return problem.Error(c, models.ErrInternalServer)
+	} else if len(validationErrors) > 0 {
+		return problem.Validation(c, validationErrors)
+	}
+
+	broadcasts, err := h.broadcastService.List(c.UserContext(), req.Limit)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(BroadcastsResponse{Broadcasts: broadcasts})
+}
+
+// Broadcast returns a broadcast with its delivery counts
+func (h *BroadcastHandler) Broadcast(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return problem.Error(c, models.ErrBroadcastNotFound)
+	}
+
+	result, err := h.broadcastService.Get(c.UserContext(), id)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// BroadcastRecipientsRequest filters the recipients of a broadcast
+type BroadcastRecipientsRequest struct {
+	Status string `json:"status" query:"status" validate:"omitempty,oneof=pending sending sent failed skipped cancelled"`
+	Cursor string `json:"cursor" query:"cursor" validate:"omitempty,max=128"`
+	Limit  int    `json:"limit" query:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+// BroadcastRecipients lists the delivery status of a broadcast per user
+func (h *BroadcastHandler) BroadcastRecipients(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return problem.Error(c, models.ErrBroadcastNotFound)
+	}
+
+	var req BroadcastRecipientsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return problem.Error(c, models.ErrBadRequest)
+	}
+
+	if validationErrors, err := h.validator.Validate(req); err != nil {
+		return problem.Error(c, models.ErrInternalServer)
+	} else if len(validationErrors) > 0 {
+		return problem.Validation(c, validationErrors)
+	}
+
+	page, err := h.broadcastService.Recipients(c.UserContext(), id, req.Status, req.Cursor, req.Limit)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+// PauseBroadcast stops sending a running broadcast until it is resumed
+func (h *BroadcastHandler) PauseBroadcast(c *fiber.Ctx) error {
+	return h.control(c, h.broadcastService.Pause)
+}
+
+// ResumeBroadcast continues sending a paused broadcast
+func (h *BroadcastHandler) ResumeBroadcast(c *fiber.Ctx) error {
+	return h.control(c, h.broadcastService.Resume)
+}
+
+// CancelBroadcast stops a broadcast for good
+func (h *BroadcastHandler) CancelBroadcast(c *fiber.Ctx) error {
+	return h.control(c, h.broadcastService.Cancel)
+}
+
+// control applies a status change to the broadcast in the path
+func (h *BroadcastHandler) control(c *fiber.Ctx, change func(ctx context.Context, id uuid.UUID) (*models.Broadcast, error)) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return problem.Error(c, models.ErrBroadcastNotFound)
+	}
+
+	result, err := change(c.UserContext(), id)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"renfound_v1/internal/delivery/http/openapi"
+	"renfound_v1/internal/delivery/http/problem"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/utils/logctx"
+)
+
+// docsCSP replaces the API policy for the documentation page
+const docsCSP = "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'; frame-ancestors 'none'"
+
+type DocsHandler struct {
+	spec   *openapi.Builder
+	logger *zap.Logger
+}
+
+func NewDocsHandler(spec *openapi.Builder, logger *zap.Logger) *DocsHandler {
+	return &DocsHandler{
+		spec:   spec,
+		logger: logger.With(zap.String("component", "docs_handler")),
+	}
+}
+
+// Spec serves the OpenAPI document
+func (h *DocsHandler) Spec(c *fiber.Ctx) error {
+	body, err := h.spec.JSON()
+	if err != nil {
+		logctx.Logger(c.UserContext(), h.logger).Error("Failed to encode OpenAPI document", zap.Error(err))
+		return problem.Error(c, models.ErrInternalServer)
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(body)
+}
+
+// UI serves the embedded documentation page
+func (h *DocsHandler) UI(c *fiber.Ctx) error {
+	// The page carries its script and styles inline and fetches the spec
+	c.Set(fiber.HeaderContentSecurityPolicy, docsCSP)
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(openapi.DocsHTML)
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"renfound_v1/internal/health"
+)
+
+type HealthHandler struct {
+	registry *health.Registry
+	logger   *zap.Logger
+}
+
+func NewHealthHandler(registry *health.Registry, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		registry: registry,
+		logger:   logger.With(zap.String("component", "health_handler")),
+	}
+}
+
+// Livez reports whether the process is alive and should not be restarted
+func (h *HealthHandler) Livez(c *fiber.Ctx) error {
+	return h.respond(c, h.registry.Run(c.UserContext(), health.Liveness))
+}
+
+// Readyz reports whether the instance can serve traffic
+func (h *HealthHandler) Readyz(c *fiber.Ctx) error {
+	return h.respond(c, h.registry.Run(c.UserContext(), health.Readiness))
+}
+
+// respond answers with the status only. The report names dependencies and
+// carries their raw errors, it is served on the ops listener instead.
+func (h *HealthHandler) respond(c *fiber.Ctx, report health.Report) error {
+	if !report.OK() {
+		return c.SendStatus(fiber.StatusServiceUnavailable)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
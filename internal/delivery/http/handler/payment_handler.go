@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"renfound_v1/internal/delivery/http/problem"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/usecase/payment"
+	"renfound_v1/internal/utils/validator"
+)
+
+// PaymentHandler serves the API of Telegram Stars payments
+type PaymentHandler struct {
+	paymentService payment.Service
+	validator      *validator.Validator
+	logger         *zap.Logger
+}
+
+func NewPaymentHandler(paymentService payment.Service, validator *validator.Validator, logger *zap.Logger) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: paymentService,
+		validator:      validator,
+		logger:         logger.With(zap.String("component", "payment_handler")),
+	}
+}
+
+// ProductsResponse is the products for sale
+type ProductsResponse struct {
+	Products []*models.Product `json:"products"`
+}
+
+// Products lists the products for sale
+func (h *PaymentHandler) Products(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(ProductsResponse{Products: h.paymentService.Products()})
+}
+
+// CreateInvoiceRequest names the product to buy
+type CreateInvoiceRequest struct {
+	ProductID string `json:"product_id" validate:"required,max=64"`
+}
+
+// CreateInvoice creates an invoice link for the current user to buy a
+// product
+func (h *PaymentHandler) CreateInvoice(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_user_id")
+	}
+
+	var req CreateInvoiceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return problem.ErrorWithDetail(c, models.ErrBadRequest, "detail.invalid_request_body")
+	}
+
+	if validationErrors, err := h.validator.Validate(req); err != nil {
+		return problem.Error(c, models.ErrInternalServer)
+	} else if len(validationErrors) > 0 {
+		return problem.Validation(c, validationErrors)
+	}
+
+	invoice, err := h.paymentService.CreateInvoice(c.UserContext(), userID, req.ProductID)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(invoice)
+}
+
+// PurchasesRequest limits the number of purchases listed
+type PurchasesRequest struct {
+	Limit int `json:"limit" query:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+// PurchasesResponse is the latest purchases, newest first
+type PurchasesResponse struct {
+	Purchases []*models.Purchase `json:"purchases"`
+}
+
+// Purchases lists the purchases of the current user
+func (h *PaymentHandler) Purchases(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_user_id")
+	}
+
+	return h.purchases(c, userID)
+}
+
+// UserPurchases lists the purchases of the user in the path, for admins
+func (h *PaymentHandler) UserPurchases(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return problem.Error(c, models.ErrUserNotFound)
+	}
+
+	return h.purchases(c, userID)
+}
+
+func (h *PaymentHandler) purchases(c *fiber.Ctx, userID uuid.UUID) error {
+	var req PurchasesRequest
+	if err := c.QueryParser(&req); err != nil {
+		return problem.Error(c, models.ErrBadRequest)
+	}
+
+	if validationErrors, err := h.validator.Validate(req); err != nil {
+		return problem.Error(c, models.ErrInternalServer)
+	} else if len(validationErrors) > 0 {
+		return problem.Validation(c, validationErrors)
+	}
+
+	purchases, err := h.paymentService.Purchases(c.UserContext(), userID, req.Limit)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(PurchasesResponse{Purchases: purchases})
+}
+
+// EntitlementsResponse is the features a user may use now
+type EntitlementsResponse struct {
+	Entitlements []*models.Entitlement `json:"entitlements"`
+}
+
+// Entitlements lists the features the current user paid for and may use
+func (h *PaymentHandler) Entitlements(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_user_id")
+	}
+
+	entitlements, err := h.paymentService.Entitlements(c.UserContext(), userID)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(EntitlementsResponse{Entitlements: entitlements})
+}
+
+// RefundPurchase returns the Stars of a purchase and revokes what it
+// granted
+func (h *PaymentHandler) RefundPurchase(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return problem.Error(c, models.ErrPurchaseNotFound)
+	}
+
+	purchase, err := h.paymentService.Refund(c.UserContext(), id)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(purchase)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/internal/delivery/bot"
+	"renfound_v1/internal/delivery/http/problem"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/utils/logctx"
+)
+
+// TelegramSecretHeader carries the secret registered with the webhook
+const TelegramSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+type TelegramHandler struct {
+	dispatcher *bot.Dispatcher
+	secret     string
+	logger     *zap.Logger
+}
+
+func NewTelegramHandler(dispatcher *bot.Dispatcher, secret string, logger *zap.Logger) *TelegramHandler {
+	return &TelegramHandler{
+		dispatcher: dispatcher,
+		secret:     secret,
+		logger:     logger.With(zap.String("component", "telegram_handler")),
+	}
+}
+
+// Webhook receives bot updates from Telegram. Updates are handled in the
+// background so Telegram gets its answer right away; any error status
+// makes Telegram deliver the update again later.
+func (h *TelegramHandler) Webhook(c *fiber.Ctx) error {
+	if subtle.ConstantTimeCompare([]byte(c.Get(TelegramSecretHeader)), []byte(h.secret)) != 1 {
+		logctx.Logger(c.UserContext(), h.logger).Warn("Rejected webhook call with a wrong secret")
+		return problem.Error(c, models.ErrUnauthorized)
+	}
+
+	var update telegram.Update
+	if err := json.Unmarshal(c.Body(), &update); err != nil || update.UpdateID == 0 {
+		return problem.ErrorWithDetail(c, models.ErrBadRequest, "detail.invalid_request_body")
+	}
+
+	if !h.dispatcher.Submit(c.UserContext(), &update) {
+		logctx.Logger(c.UserContext(), h.logger).Warn("Worker pool full, update left for redelivery",
+			zap.Int64("update_id", update.UpdateID))
+		return problem.Error(c, models.ErrTooManyRequests)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
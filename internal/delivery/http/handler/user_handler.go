@@ -1,33 +1,59 @@
 package handler
 
 import (
-	"errors"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"renfound_v1/internal/delivery/http/authcookie"
+	"renfound_v1/internal/delivery/http/clientip"
+	"renfound_v1/internal/delivery/http/locale"
+	"renfound_v1/internal/delivery/http/problem"
 	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/usecase/audit"
+	"renfound_v1/internal/usecase/login"
+	"renfound_v1/internal/usecase/referral"
 	"renfound_v1/internal/usecase/user"
 	"renfound_v1/internal/utils/validator"
 )
 
 type UserHandler struct {
-	userService user.Service
-	validator   *validator.Validator
-	logger      *zap.Logger
+	userService     user.Service
+	auditService    audit.Service
+	loginService    login.Service
+	referralService referral.Service
+	validator       *validator.Validator
+	cookies         *authcookie.Manager
+	logger          *zap.Logger
 }
 
 func NewUserHandler(
 	userService user.Service,
+	auditService audit.Service,
+	loginService login.Service,
+	referralService referral.Service,
 	validator *validator.Validator,
+	cookies *authcookie.Manager,
 	logger *zap.Logger,
 ) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		validator:   validator,
-		logger:      logger.With(zap.String("component", "user_handler")),
+		userService:     userService,
+		auditService:    auditService,
+		loginService:    loginService,
+		referralService: referralService,
+		validator:       validator,
+		cookies:         cookies,
+		logger:          logger.With(zap.String("component", "user_handler")),
 	}
 }
 
+// MessageResponse is returned by operations that have no resource to return
+type MessageResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
 type TelegramAuthRequest struct {
 	InitData string `json:"initData" validate:"required"`
 }
@@ -36,39 +62,26 @@ func (h *UserHandler) AuthWithTelegram(c *fiber.Ctx) error {
 	// parse req
 	var req TelegramAuthRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-			models.ErrBadRequest, "Invalid req body"))
+		return problem.ErrorWithDetail(c, models.ErrBadRequest, "detail.invalid_request_body")
 	}
 
 	// validate req
 	if validationErrors, err := h.validator.Validate(req); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
-			models.ErrInternalServer, "Validation err"))
+		return problem.Error(c, models.ErrInternalServer)
 	} else if len(validationErrors) > 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":       models.ErrValidation.Error(),
-			"description": "Validation failed",
-			"errors":      validationErrors,
-		})
+		return problem.Validation(c, validationErrors)
 	}
 
 	//get IP addr
 	userAgent := c.Get("User-Agent")
-	idAddress := c.IP()
+	idAddress := clientip.FromCtx(c)
 
 	//Authenticate
-	tokens, err := h.userService.AuthWithTelegram(c.Context(), req.InitData, userAgent, idAddress)
+	tokens, err := h.userService.AuthWithTelegram(c.UserContext(), req.InitData, userAgent, idAddress)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if errors.Is(err, models.ErrInvalidInitData) || errors.Is(err, models.ErrInvalidSignature) {
-			status = fiber.StatusBadRequest
-		} else if errors.Is(err, models.ErrExpiredToken) {
-			status = fiber.StatusUnauthorized
-		}
-
-		return c.Status(status).JSON(models.NewErrorResponse(err, ""))
+		return problem.Error(c, err)
 	}
-	return c.Status(fiber.StatusOK).JSON(tokens)
+	return h.respondTokens(c, tokens)
 }
 
 type RefreshTokenRequest struct {
@@ -77,45 +90,32 @@ type RefreshTokenRequest struct {
 
 // both token refreshing
 func (h *UserHandler) RefreshTokens(c *fiber.Ctx) error {
-	//parse req
-	var req RefreshTokenRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-			models.ErrBadRequest,
-			"Invalid req body",
-		))
-	}
-
-	//validate
-	if validationErrors, err := h.validator.Validate(req); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
-			models.ErrInternalServer,
-			"Validation error",
-		))
-	} else if len(validationErrors) > 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":       models.ErrValidation.Error(),
-			"description": "Validation failed",
-			"errors":      validationErrors,
-		})
+	// In cookie mode the token comes from the cookie, the body is a fallback
+	refreshToken := h.cookies.RefreshToken(c)
+	if refreshToken == "" {
+		//parse req
+		var req RefreshTokenRequest
+		if err := c.BodyParser(&req); err != nil {
+			return problem.ErrorWithDetail(c, models.ErrBadRequest, "detail.invalid_request_body")
+		}
 
+		//validate
+		if validationErrors, err := h.validator.Validate(req); err != nil {
+			return problem.Error(c, models.ErrInternalServer)
+		} else if len(validationErrors) > 0 {
+			return problem.Validation(c, validationErrors)
+		}
+		refreshToken = req.RefreshToken
 	}
 	userAgent := c.Get("User-Agent")
-	ipAddress := c.IP()
+	ipAddress := clientip.FromCtx(c)
 
-	tokens, err := h.userService.RefreshTokens(c.Context(), req.RefreshToken, userAgent, ipAddress)
+	tokens, err := h.userService.RefreshTokens(c.UserContext(), refreshToken, userAgent, ipAddress)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if errors.Is(err, models.ErrInvalidToken) || errors.Is(err, models.ErrSessionNotFound) {
-			status = fiber.StatusUnauthorized
-		} else if errors.Is(err, models.ErrExpiredToken) {
-			status = fiber.StatusUnauthorized
-		}
-
-		return c.Status(status).JSON(models.NewErrorResponse(err, ""))
+		return problem.Error(c, err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(tokens)
+	return h.respondTokens(c, tokens)
 }
 
 type LogoutRequest struct {
@@ -123,37 +123,36 @@ type LogoutRequest struct {
 }
 
 func (h *UserHandler) Logout(c *fiber.Ctx) error {
-	// Parse request
-	var req LogoutRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.NewErrorResponse(
-			models.ErrBadRequest,
-			"Invalid request body",
-		))
-	}
+	// In cookie mode the token comes from the cookie, the body is a fallback
+	refreshToken := h.cookies.RefreshToken(c)
+	if refreshToken == "" {
+		// Parse request
+		var req LogoutRequest
+		if err := c.BodyParser(&req); err != nil {
+			return problem.ErrorWithDetail(c, models.ErrBadRequest, "detail.invalid_request_body")
+		}
 
-	// Validate request
-	if validationErrors, err := h.validator.Validate(req); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(
-			models.ErrInternalServer,
-			"Validation error",
-		))
-	} else if len(validationErrors) > 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":       models.ErrValidation.Error(),
-			"description": "Validation failed",
-			"errors":      validationErrors,
-		})
+		// Validate request
+		if validationErrors, err := h.validator.Validate(req); err != nil {
+			return problem.Error(c, models.ErrInternalServer)
+		} else if len(validationErrors) > 0 {
+			return problem.Validation(c, validationErrors)
+		}
+		refreshToken = req.RefreshToken
 	}
 
 	// Logout user
-	if err := h.userService.Logout(c.Context(), req.RefreshToken); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(err, ""))
+	if err := h.userService.Logout(c.UserContext(), refreshToken); err != nil {
+		return problem.Error(c, err)
+	}
+
+	if h.cookies.Enabled() {
+		h.cookies.Clear(c)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"success": true,
-		"message": "Logged out successfully",
+	return c.Status(fiber.StatusOK).JSON(MessageResponse{
+		Success: true,
+		Message: locale.T(c, "message.logged_out", "Logged out successfully"),
 	})
 }
 
@@ -162,20 +161,17 @@ func (h *UserHandler) LogoutAll(c *fiber.Ctx) error {
 	// Get user ID from context
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
-			models.ErrUnauthorized,
-			"Missing user ID",
-		))
+		return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_user_id")
 	}
 
 	// Logout all sessions
-	if err := h.userService.LogoutAll(c.Context(), userID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.NewErrorResponse(err, ""))
+	if err := h.userService.LogoutAll(c.UserContext(), userID); err != nil {
+		return problem.Error(c, err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"success": true,
-		"message": "All sessions logged out successfully",
+	return c.Status(fiber.StatusOK).JSON(MessageResponse{
+		Success: true,
+		Message: locale.T(c, "message.logged_out_all", "All sessions logged out successfully"),
 	})
 }
 
@@ -184,21 +180,13 @@ func (h *UserHandler) GetMe(c *fiber.Ctx) error {
 	// Get user ID from context
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
-			models.ErrUnauthorized,
-			"Missing user ID",
-		))
+		return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_user_id")
 	}
 
 	// Get user
-	user, err := h.userService.GetUser(c.Context(), userID)
+	user, err := h.userService.GetUser(c.UserContext(), userID)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if errors.Is(err, models.ErrUserNotFound) {
-			status = fiber.StatusNotFound
-		}
-
-		return c.Status(status).JSON(models.NewErrorResponse(err, ""))
+		return problem.Error(c, err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(user)
@@ -209,24 +197,147 @@ func (h *UserHandler) DeleteMe(c *fiber.Ctx) error {
 	// Get user ID from context
 	userID, ok := c.Locals("userID").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
-			models.ErrUnauthorized,
-			"Missing user ID",
-		))
+		return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_user_id")
 	}
 
 	// Delete user
-	if err := h.userService.DeleteUser(c.Context(), userID); err != nil {
-		status := fiber.StatusInternalServerError
-		if errors.Is(err, models.ErrUserNotFound) {
-			status = fiber.StatusNotFound
-		}
-
-		return c.Status(status).JSON(models.NewErrorResponse(err, ""))
+	if err := h.userService.DeleteUser(c.UserContext(), userID); err != nil {
+		return problem.Error(c, err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"success": true,
-		"message": "User deleted successfully",
+	return c.Status(fiber.StatusOK).JSON(MessageResponse{
+		Success: true,
+		Message: locale.T(c, "message.user_deleted", "User deleted successfully"),
 	})
 }
+
+// SecurityEventsRequest pages through the user's security history
+type SecurityEventsRequest struct {
+	Cursor string `json:"cursor" query:"cursor" validate:"omitempty,max=128"`
+	Limit  int    `json:"limit" query:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+// SecurityEvent is an audit event as shown to the user it concerns. Who
+// performed the action is reduced to whether it was the user.
+type SecurityEvent struct {
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+	BySelf    bool      `json:"by_self"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SecurityEventsResponse struct {
+	Events     []SecurityEvent `json:"events"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// SecurityEvents lists the security history of the authenticated user
+func (h *UserHandler) SecurityEvents(c *fiber.Ctx) error {
+	// Get user ID from context
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_user_id")
+	}
+
+	var req SecurityEventsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return problem.Error(c, models.ErrBadRequest)
+	}
+
+	if validationErrors, err := h.validator.Validate(req); err != nil {
+		return problem.Error(c, models.ErrInternalServer)
+	} else if len(validationErrors) > 0 {
+		return problem.Validation(c, validationErrors)
+	}
+
+	page, err := h.auditService.History(c.UserContext(), userID, req.Cursor, req.Limit)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	resp := SecurityEventsResponse{
+		Events:     make([]SecurityEvent, 0, len(page.Events)),
+		NextCursor: page.NextCursor,
+	}
+	for _, event := range page.Events {
+		resp.Events = append(resp.Events, SecurityEvent{
+			Action:    event.Action,
+			Outcome:   event.Outcome,
+			Reason:    event.Reason,
+			BySelf:    event.ActorID != nil && *event.ActorID == userID,
+			IPAddress: event.IPAddress,
+			UserAgent: event.UserAgent,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// LoginsRequest limits the login history
+type LoginsRequest struct {
+	Limit int `json:"limit" query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// LoginsResponse is the latest logins of the user, newest first
+type LoginsResponse struct {
+	Logins []*models.Login `json:"logins"`
+}
+
+// Logins lists the latest sign ins and refreshes of the authenticated user
+func (h *UserHandler) Logins(c *fiber.Ctx) error {
+	// Get user ID from context
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_user_id")
+	}
+
+	var req LoginsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return problem.Error(c, models.ErrBadRequest)
+	}
+
+	if validationErrors, err := h.validator.Validate(req); err != nil {
+		return problem.Error(c, models.ErrInternalServer)
+	} else if len(validationErrors) > 0 {
+		return problem.Validation(c, validationErrors)
+	}
+
+	logins, err := h.loginService.History(c.UserContext(), userID, req.Limit)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(LoginsResponse{Logins: logins})
+}
+
+// Referrals returns the referral code of the authenticated user with the
+// signups it brought in
+func (h *UserHandler) Referrals(c *fiber.Ctx) error {
+	// Get user ID from context
+	userID, ok := c.Locals("userID").(uuid.UUID)
+	if !ok {
+		return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_user_id")
+	}
+
+	stats, err := h.referralService.Stats(c.UserContext(), userID)
+	if err != nil {
+		return problem.Error(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(stats)
+}
+
+// respondTokens writes the token pair. In cookie mode the refresh token is
+// set as a cookie and left out of the body.
+func (h *UserHandler) respondTokens(c *fiber.Ctx, tokens *models.Tokens) error {
+	if h.cookies.Enabled() {
+		h.cookies.SetRefreshToken(c, tokens.RefreshToken)
+		tokens = &models.Tokens{AccessToken: tokens.AccessToken}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(tokens)
+}
@@ -0,0 +1,18 @@
+package locale
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"renfound_v1/internal/i18n"
+)
+
+// FromCtx returns the locale for the response. It is resolved on every call
+// because the user's language only becomes known after authentication.
+func FromCtx(c *fiber.Ctx) string {
+	userLanguage, _ := c.Locals("languageCode").(string)
+	return i18n.Resolve(userLanguage, c.Get(fiber.HeaderAcceptLanguage))
+}
+
+// T translates key for the current request
+func T(c *fiber.Ctx, key, fallback string) string {
+	return i18n.T(FromCtx(c), key, fallback, nil)
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"renfound_v1/internal/delivery/http/clientip"
+	"renfound_v1/internal/usecase/audit"
+)
+
+type AuditMiddleware struct{}
+
+func NewAuditMiddleware() *AuditMiddleware {
+	return &AuditMiddleware{}
+}
+
+// Client stores the caller's IP and user agent in the user context, so
+// audit events recorded in any layer say where the request came from. It
+// must run after the client IP is resolved.
+func (m *AuditMiddleware) Client() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.SetUserContext(audit.WithClient(c.UserContext(), audit.Client{
+			IPAddress: clientip.FromCtx(c),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
+		}))
+
+		return c.Next()
+	}
+}
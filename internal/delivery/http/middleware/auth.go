@@ -4,19 +4,24 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"renfound_v1/config"
 	"renfound_v1/infrastructure/auth"
+	"renfound_v1/internal/delivery/http/problem"
 	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/utils/logctx"
 	"strings"
 )
 
 type AuthMiddleware struct {
 	telegramAuth *auth.TelegramAuth
+	admins       config.AdminConfig
 	logger       *zap.Logger
 }
 
-func NewAuthMiddleware(telegramAuth *auth.TelegramAuth, logger *zap.Logger) *AuthMiddleware {
+func NewAuthMiddleware(telegramAuth *auth.TelegramAuth, admins config.AdminConfig, logger *zap.Logger) *AuthMiddleware {
 	return &AuthMiddleware{
 		telegramAuth: telegramAuth,
+		admins:       admins,
 		logger:       logger.With(zap.String("component", "auth_middleware")),
 	}
 }
@@ -26,18 +31,13 @@ func (m *AuthMiddleware) Authenticate() fiber.Handler {
 		//get token from the header
 		authheader := c.Get("Authorization")
 		if authheader == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
-				models.ErrUnauthorized,
-				"Missing auth header",
-			))
+			return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.missing_auth_header")
 		}
 
 		// check correct format for the token
 		parts := strings.Split(authheader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
-				models.ErrUnauthorized,
-				"Invalid auth header format"))
+			return problem.ErrorWithDetail(c, models.ErrUnauthorized, "detail.invalid_auth_header")
 		}
 
 		token := parts[1]
@@ -45,31 +45,23 @@ func (m *AuthMiddleware) Authenticate() fiber.Handler {
 		//validate
 		claims, err := m.telegramAuth.ValidateAccessToken(token)
 		if err != nil {
-			status := fiber.StatusUnauthorized
-			errMsg := "Invalid token"
-
-			if err == models.ErrExpiredToken {
-				errMsg = "Token has expired"
-			}
-
-			return c.Status(status).JSON(models.NewErrorResponse(
-				models.ErrUnauthorized, errMsg))
+			// Expired tokens get their own code so clients know to refresh
+			return problem.Error(c, err)
 		}
 
 		//parse user id from claims
 		userID, err := uuid.Parse(claims.UserID)
 
 		if err != nil {
-			m.logger.Error("Invalid user ID in token", zap.Error(err), zap.String("user_id", claims.UserID))
-			return c.Status(fiber.StatusUnauthorized).JSON(models.NewErrorResponse(
-				models.ErrUnauthorized,
-				"Invalid token",
-			))
+			logctx.Logger(c.UserContext(), m.logger).Error("Invalid user ID in token", zap.Error(err), zap.String("user_id", claims.UserID))
+			return problem.Error(c, models.ErrInvalidToken)
 		}
 
 		// set user id and tg id in context for later
 		c.Locals("userID", userID)
 		c.Locals("telegramID", claims.TelegramID)
+		c.Locals("languageCode", claims.LanguageCode)
+		c.SetUserContext(logctx.WithUserID(c.UserContext(), userID.String()))
 
 		return c.Next()
 	}
@@ -108,6 +100,23 @@ func (m *AuthMiddleware) OptionalAuthenticate() fiber.Handler {
 		// Set user ID and telegram ID in context for later use
 		c.Locals("userID", userID)
 		c.Locals("telegramID", claims.TelegramID)
+		c.Locals("languageCode", claims.LanguageCode)
+		c.SetUserContext(logctx.WithUserID(c.UserContext(), userID.String()))
+
+		return c.Next()
+	}
+}
+
+// RequireAdmin lets only admins through. It must run after Authenticate.
+func (m *AuthMiddleware) RequireAdmin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		telegramID, ok := c.Locals("telegramID").(int64)
+		if !ok || !m.admins.IsAdmin(telegramID) {
+			logctx.Logger(c.UserContext(), m.logger).Warn("Admin access denied",
+				zap.Int64("telegram_id", telegramID),
+				zap.String("path", c.Path()))
+			return problem.Error(c, models.ErrForbidden)
+		}
 
 		return c.Next()
 	}
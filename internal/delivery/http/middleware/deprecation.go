@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"renfound_v1/internal/delivery/http/clientip"
+	"renfound_v1/internal/utils/logctx"
+)
+
+// deprecationLogInterval limits usage logs to one per client and route
+const deprecationLogInterval = time.Hour
+
+// maxTrackedClients bounds the memory used to throttle usage logs
+const maxTrackedClients = 10000
+
+// versionSegment matches the version segment of versioned API paths
+var versionSegment = regexp.MustCompile(`^/api/v\d+(/|$)`)
+
+// Deprecation describes a deprecated route. Zero values are omitted from the
+// response headers.
+type Deprecation struct {
+	// Since is when the route was deprecated
+	Since time.Time
+	// Sunset is when the route will stop working
+	Sunset time.Time
+	// Successor is the URL of the replacement. Route parameters, e.g. :id,
+	// are filled with the values of the request.
+	Successor string
+}
+
+type DeprecationMiddleware struct {
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+}
+
+func NewDeprecationMiddleware(logger *zap.Logger) *DeprecationMiddleware {
+	return &DeprecationMiddleware{
+		logger:   logger.With(zap.String("component", "deprecation_middleware")),
+		lastSeen: make(map[string]time.Time),
+	}
+}
+
+// Deprecate emits the Deprecation (RFC 9745), Sunset (RFC 8594) and
+// successor Link headers and logs which clients still call the route
+func (m *DeprecationMiddleware) Deprecate(d Deprecation) fiber.Handler {
+	deprecation := "true"
+	if !d.Since.IsZero() {
+		deprecation = "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	}
+
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", deprecation)
+		if !d.Sunset.IsZero() {
+			c.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Successor != "" {
+			c.Append(fiber.HeaderLink, "<"+successorFor(c, d.Successor)+`>; rel="successor-version"`)
+		}
+
+		err := c.Next()
+
+		// Logged after the handler so authenticated clients are identified
+		m.logUsage(c)
+
+		return err
+	}
+}
+
+// logUsage logs a deprecated call at most once per client, route and interval
+func (m *DeprecationMiddleware) logUsage(c *fiber.Ctx) {
+	route := c.Method() + " " + c.Route().Path
+	client := "ip:" + clientip.FromCtx(c)
+	if userID, ok := c.Locals("userID").(uuid.UUID); ok {
+		client = "user:" + userID.String()
+	}
+
+	now := time.Now()
+	key := route + " " + client
+
+	m.mu.Lock()
+	if last, ok := m.lastSeen[key]; ok && now.Sub(last) < deprecationLogInterval {
+		m.mu.Unlock()
+		return
+	}
+	if len(m.lastSeen) >= maxTrackedClients {
+		m.lastSeen = make(map[string]time.Time)
+	}
+	m.lastSeen[key] = now
+	m.mu.Unlock()
+
+	logctx.Logger(c.UserContext(), m.logger).Warn("Deprecated route called",
+		zap.String("route", route),
+		zap.String("client", client),
+		zap.String("user_agent", c.Get(fiber.HeaderUserAgent)))
+}
+
+// successorFor fills the route parameters of successor with the values of
+// the request
+func successorFor(c *fiber.Ctx, successor string) string {
+	if !strings.Contains(successor, "/:") {
+		return successor
+	}
+
+	segments := strings.Split(successor, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = c.Params(segment[1:])
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// unversionedPath strips the version segment, so /api/v1/auth/telegram and
+// its alias /api/auth/telegram are treated as the same route
+func unversionedPath(path string) string {
+	if loc := versionSegment.FindStringIndex(path); loc != nil {
+		return "/api/" + path[loc[1]:]
+	}
+	return path
+}
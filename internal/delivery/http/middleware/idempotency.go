@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/idempotency"
+	"renfound_v1/internal/delivery/http/problem"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/utils/logctx"
+)
+
+// IdempotencyKeyHeader carries the client generated key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader marks responses replayed from the store
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are the response headers stored with the body. Cookies
+// and CSRF tokens are credentials and are never stored.
+var replayedHeaders = []string{
+	fiber.HeaderContentType,
+	fiber.HeaderContentLanguage,
+}
+
+type IdempotencyMiddleware struct {
+	store   idempotency.Store
+	ttl     time.Duration
+	lockTTL time.Duration
+	logger  *zap.Logger
+}
+
+func NewIdempotencyMiddleware(store idempotency.Store, cfg config.IdempotencyConfig, logger *zap.Logger) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		store:   store,
+		ttl:     cfg.TTL,
+		lockTTL: cfg.LockTTL,
+		logger:  logger.With(zap.String("component", "idempotency_middleware")),
+	}
+}
+
+// Handle replays the stored response when a mutating request is retried
+// with the same Idempotency-Key. Keys are scoped to the authenticated user,
+// so it must run after authentication on protected routes.
+func (m *IdempotencyMiddleware) Handle() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if m.store == nil || key == "" || !isMutating(c.Method()) {
+			return c.Next()
+		}
+
+		if !validIdempotencyKey(key) {
+			return problem.ErrorWithDetail(c, models.ErrBadRequest, "detail.invalid_idempotency_key")
+		}
+
+		scopedKey := idempotencyScope(c) + ":" + key
+		fingerprint := requestFingerprint(c)
+
+		record, claimed, err := m.store.Reserve(c.UserContext(), scopedKey, fingerprint, m.lockTTL)
+		if err != nil {
+			// Fail open: losing deduplication beats rejecting every request
+			logctx.Logger(c.UserContext(), m.logger).Error("Idempotency store failed", zap.Error(err))
+			return c.Next()
+		}
+
+		if !claimed {
+			return m.replay(c, record, fingerprint)
+		}
+
+		err = c.Next()
+
+		// Server errors and errors rendered later by the error handler are not
+		// stored, the client may retry them
+		status := c.Response().StatusCode()
+		if err != nil || status >= fiber.StatusInternalServerError {
+			if releaseErr := m.store.Release(c.UserContext(), scopedKey); releaseErr != nil {
+				logctx.Logger(c.UserContext(), m.logger).Error("Failed to release idempotency key", zap.Error(releaseErr))
+			}
+			return err
+		}
+
+		if err := m.store.Complete(c.UserContext(), scopedKey, fingerprint, captureResponse(c), m.ttl); err != nil {
+			logctx.Logger(c.UserContext(), m.logger).Error("Failed to store idempotent response", zap.Error(err))
+		}
+
+		return nil
+	}
+}
+
+func (m *IdempotencyMiddleware) replay(c *fiber.Ctx, record *idempotency.Record, fingerprint string) error {
+	if record.Fingerprint != fingerprint {
+		return problem.Error(c, models.ErrIdempotencyKeyReused)
+	}
+
+	if record.Response == nil {
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return problem.Error(c, models.ErrIdempotencyInProgress)
+	}
+
+	for name, values := range record.Response.Headers {
+		for _, value := range values {
+			c.Response().Header.Add(name, value)
+		}
+	}
+	c.Set(IdempotentReplayedHeader, "true")
+
+	return c.Status(record.Response.Status).Send(record.Response.Body)
+}
+
+// captureResponse copies the response written by the handler
+func captureResponse(c *fiber.Ctx) *idempotency.Response {
+	resp := &idempotency.Response{
+		Status:  c.Response().StatusCode(),
+		Headers: make(map[string][]string),
+		Body:    append([]byte(nil), c.Response().Body()...),
+	}
+
+	for _, name := range replayedHeaders {
+		if value := c.GetRespHeader(name); value != "" {
+			resp.Headers[name] = []string{value}
+		}
+	}
+
+	return resp
+}
+
+// idempotencyScope keeps keys of different users apart
+func idempotencyScope(c *fiber.Ctx) string {
+	if userID, ok := c.Locals("userID").(uuid.UUID); ok {
+		return "user:" + userID.String()
+	}
+	return "anonymous"
+}
+
+// requestFingerprint hashes what makes two requests the same operation. The
+// credentials are included so a leaked key cannot replay another caller's
+// response, e.g. tokens issued to them.
+func requestFingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	for _, part := range [][]byte{
+		[]byte(c.Method()),
+		[]byte(unversionedPath(c.Route().Path)),
+		[]byte(c.Get(fiber.HeaderAuthorization)),
+		[]byte(c.Get(fiber.HeaderCookie)),
+		c.Body(),
+	} {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -4,9 +4,16 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"renfound_v1/internal/delivery/http/clientip"
+	"renfound_v1/internal/delivery/http/problem"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/utils/logctx"
 	"time"
 )
 
+// maxRequestIDLength bounds incoming request IDs, which end up in every log line
+const maxRequestIDLength = 128
+
 type LoggingMiddleware struct {
 	logger *zap.Logger
 }
@@ -23,12 +30,17 @@ func (m *LoggingMiddleware) Logger() fiber.Handler {
 		// Start timer
 		start := time.Now()
 
-		// Generate request ID
-		reqID := uuid.New().String()
+		// Keep the request ID of an upstream proxy or client so logs can be
+		// followed across services, generate one otherwise
+		reqID := c.Get(fiber.HeaderXRequestID)
+		if !validRequestID(reqID) {
+			reqID = uuid.New().String()
+		}
 		c.Locals("requestID", reqID)
+		c.SetUserContext(logctx.WithRequestID(c.UserContext(), reqID))
 
 		// Set request ID header
-		c.Set("X-Request-ID", reqID)
+		c.Set(fiber.HeaderXRequestID, reqID)
 
 		// Process request
 		err := c.Next()
@@ -39,31 +51,27 @@ func (m *LoggingMiddleware) Logger() fiber.Handler {
 		// Get status code
 		status := c.Response().StatusCode()
 
-		// Get user ID if available
-		var userID interface{}
-		if c.Locals("userID") != nil {
-			userID = c.Locals("userID")
-		}
+		// The request ID and, once authenticated, the user ID come from the
+		// request context
+		logger := logctx.Logger(c.UserContext(), m.logger)
 
 		// Determine log level based on status code
-		logFunc := m.logger.Info
+		logFunc := logger.Info
 		if status >= 500 {
-			logFunc = m.logger.Error
+			logFunc = logger.Error
 		} else if status >= 400 {
-			logFunc = m.logger.Warn
+			logFunc = logger.Warn
 		}
 
 		// Log the request
 		logFunc("HTTP Request",
-			zap.String("request_id", reqID),
 			zap.String("method", c.Method()),
 			zap.String("path", c.Path()),
 			zap.String("query", string(c.Request().URI().QueryString())),
 			zap.Int("status", status),
 			zap.Duration("latency", latency),
-			zap.String("ip", c.IP()),
+			zap.String("ip", clientip.FromCtx(c)),
 			zap.String("user_agent", c.Get("User-Agent")),
-			zap.Any("user_id", userID),
 			zap.Int64("body_size", int64(len(c.Request().Body()))),
 		)
 
@@ -76,28 +84,36 @@ func (m *LoggingMiddleware) RecoverWithLogger() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		defer func() {
 			if r := recover(); r != nil {
-				// Get request ID if available
-				reqID, _ := c.Locals("requestID").(string)
-				if reqID == "" {
-					reqID = uuid.New().String()
-				}
-
-				m.logger.Error("Recovered from panic",
-					zap.String("request_id", reqID),
+				logctx.Logger(c.UserContext(), m.logger).Error("Recovered from panic",
 					zap.String("method", c.Method()),
 					zap.String("path", c.Path()),
 					zap.Any("panic", r),
-					zap.String("ip", c.IP()),
+					zap.String("ip", clientip.FromCtx(c)),
 					zap.String("user_agent", c.Get("User-Agent")),
 				)
 
 				// Return internal server error
-				_ = c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Internal Server Error",
-				})
+				_ = problem.Error(c, models.ErrInternalServer)
 			}
 		}()
 
 		return c.Next()
 	}
 }
+
+// validRequestID accepts IDs made of letters, digits and -_.: only, so
+// clients can't inject arbitrary content into the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"renfound_v1/infrastructure/metrics"
+)
+
+type MetricsMiddleware struct {
+	metrics *metrics.Metrics
+}
+
+func NewMetricsMiddleware(m *metrics.Metrics) *MetricsMiddleware {
+	return &MetricsMiddleware{
+		metrics: m,
+	}
+}
+
+// Collect records request counts and latency by route pattern and status
+func (m *MetricsMiddleware) Collect() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		// Errors are rendered by the app error handler after the middleware
+		// chain returns, so derive the final status from the error
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		// Unmatched requests would otherwise create one series per raw path
+		route := c.Route().Path
+		if status == fiber.StatusNotFound && err != nil {
+			route = "unmatched"
+		}
+
+		m.metrics.ObserveHTTPRequest(c.Method(), route, status, time.Since(start))
+
+		return err
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/ratelimit"
+	"renfound_v1/internal/delivery/http/clientip"
+	"renfound_v1/internal/delivery/http/problem"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/utils/logctx"
+)
+
+type RateLimitMiddleware struct {
+	limiter  ratelimit.Limiter
+	policies map[string]config.RateLimitPolicy
+	enabled  bool
+	logger   *zap.Logger
+}
+
+func NewRateLimitMiddleware(limiter ratelimit.Limiter, cfg config.RateLimitConfig, logger *zap.Logger) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		limiter:  limiter,
+		policies: cfg.Policies,
+		enabled:  cfg.Enabled && limiter != nil,
+		logger:   logger.With(zap.String("component", "rate_limit_middleware")),
+	}
+}
+
+// Limit applies the named policy from config. It must be registered on the
+// route, not the group: the bucket is keyed by the matched route path, plus
+// client IP, user ID or nothing depending on the policy. Policies are
+// validated at startup, see config.validate, so an unknown policy panics.
+func (m *RateLimitMiddleware) Limit(policyName string) fiber.Handler {
+	if !m.enabled {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	policy, ok := m.policies[policyName]
+	if !ok {
+		panic(fmt.Sprintf("rate limit policy %q is not configured", policyName))
+	}
+
+	limit := ratelimit.Limit{
+		Rate:   policy.Limit,
+		Period: policy.Period,
+		Burst:  policy.Burst,
+	}
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Period.Seconds()))
+
+	return func(c *fiber.Ctx) error {
+		// Versioned routes and their aliases share a bucket
+		key := policyName + ":" + unversionedPath(c.Route().Path) + ":" + m.subject(c, policy.KeyBy)
+
+		result, err := m.limiter.Allow(c.Context(), key, limit)
+		if err != nil {
+			// Fail open: a broken limiter backend must not take the API down
+			logctx.Logger(c.UserContext(), m.logger).Error("Rate limiter failed", zap.Error(err), zap.String("policy", policyName))
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Policy", policyHeader)
+		c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+
+			logctx.Logger(c.UserContext(), m.logger).Warn("Rate limit exceeded",
+				zap.String("policy", policyName),
+				zap.String("path", c.Path()),
+				zap.String("ip", clientip.FromCtx(c)))
+
+			return problem.ErrorWithDetail(c, models.ErrTooManyRequests, "detail.rate_limit_exceeded")
+		}
+
+		return c.Next()
+	}
+}
+
+// subject returns the part of the bucket key that identifies the caller
+func (m *RateLimitMiddleware) subject(c *fiber.Ctx, keyBy string) string {
+	switch keyBy {
+	case "route":
+		return "all"
+	case "user":
+		// Public routes have no user, their callers are keyed by IP
+		if userID, ok := c.Locals("userID").(uuid.UUID); ok {
+			return "user:" + userID.String()
+		}
+		return "ip:" + clientip.FromCtx(c)
+	default:
+		return "ip:" + clientip.FromCtx(c)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
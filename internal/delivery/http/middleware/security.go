@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"renfound_v1/config"
+)
+
+type SecurityHeadersMiddleware struct {
+	hsts string
+	csp  string
+	// frameOptions is empty when framing by Telegram is allowed, as
+	// X-Frame-Options cannot list allowed origins
+	frameOptions string
+}
+
+func NewSecurityHeadersMiddleware(cfg config.SecurityConfig) *SecurityHeadersMiddleware {
+	m := &SecurityHeadersMiddleware{}
+
+	if cfg.HSTSMaxAge > 0 {
+		m.hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			m.hsts += "; includeSubDomains"
+		}
+	}
+
+	// The API serves JSON, nothing in a response needs to load resources
+	frameAncestors := "'none'"
+	m.frameOptions = "DENY"
+	if cfg.AllowTelegramEmbedding {
+		frameAncestors = "'self' " + strings.Join(config.TelegramWebOrigins, " ")
+		m.frameOptions = ""
+	}
+	m.csp = "default-src 'none'; frame-ancestors " + frameAncestors
+
+	return m
+}
+
+// Headers sets the security headers. Handlers serving HTML may override the
+// Content-Security-Policy with a less strict one.
+func (m *SecurityHeadersMiddleware) Headers() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+		c.Set(fiber.HeaderContentSecurityPolicy, m.csp)
+		if m.frameOptions != "" {
+			c.Set(fiber.HeaderXFrameOptions, m.frameOptions)
+		}
+		// Browsers ignore HSTS received over plain HTTP, so it is safe to
+		// send it regardless of where TLS terminates
+		if m.hsts != "" {
+			c.Set(fiber.HeaderStrictTransportSecurity, m.hsts)
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"renfound_v1/internal/delivery/http/clientip"
+)
+
+const tracerName = "renfound_v1/internal/delivery/http"
+
+type TracingMiddleware struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func NewTracingMiddleware() *TracingMiddleware {
+	return &TracingMiddleware{
+		tracer:     otel.Tracer(tracerName),
+		propagator: otel.GetTextMapPropagator(),
+	}
+}
+
+// Trace starts a server span for every request, continuing any trace passed
+// in the request headers. The span context is stored in the user context so
+// handlers must pass c.UserContext() down to the service layer.
+func (m *TracingMiddleware) Trace() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := m.propagator.Extract(c.UserContext(), headerCarrier{c})
+
+		ctx, span := m.tracer.Start(ctx, c.Method()+" "+c.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(c.Path()),
+				semconv.ClientAddress(clientip.FromCtx(c)),
+				semconv.UserAgentOriginal(c.Get(fiber.HeaderUserAgent)),
+			),
+		)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+			span.RecordError(err)
+		}
+
+		// The route pattern is only known once routing is done
+		route := c.Route().Path
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(
+			semconv.HTTPRoute(route),
+			semconv.HTTPResponseStatusCode(status),
+		)
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+
+		return err
+	}
+}
+
+// headerCarrier adapts fasthttp request headers to the propagation carrier
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0)
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,9 @@
+package openapi
+
+import _ "embed"
+
+// DocsHTML is a self-contained documentation page that renders the spec
+// served next to it at openapi.json
+//
+//go:embed docs.html
+var DocsHTML []byte
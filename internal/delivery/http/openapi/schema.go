@@ -0,0 +1,187 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// schemaGenerator converts Go types to JSON Schema, registering named
+// structs under components/schemas and referencing them
+type schemaGenerator struct {
+	components map[string]Schema
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		components: make(map[string]Schema),
+	}
+}
+
+func (g *schemaGenerator) schemaFor(v interface{}) Schema {
+	return g.schemaForType(reflect.TypeOf(v))
+}
+
+func (g *schemaGenerator) schemaForType(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case uuidType:
+		return Schema{"type": "string", "format": "uuid"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Schema{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return Schema{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": g.schemaForType(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": g.schemaForType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := schemaName(t)
+		if _, ok := g.components[name]; !ok {
+			// Reserve the name first so recursive types terminate
+			g.components[name] = Schema{}
+			g.components[name] = g.structSchema(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + name}
+	default:
+		return Schema{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) Schema {
+	properties := make(map[string]Schema)
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		// Embedded structs without a json name are flattened
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := g.structSchema(indirect(field.Type))
+			for k, v := range embedded["properties"].(map[string]Schema) {
+				properties[k] = v
+			}
+			if req, ok := embedded["required"].([]string); ok {
+				required = append(required, req...)
+			}
+			continue
+		}
+
+		properties[name] = g.schemaForType(field.Type)
+
+		if strings.Contains(field.Tag.Get("validate"), "required") || !omitEmpty && field.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+
+	schema := Schema{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// queryParameters lists the query tagged fields of a struct as parameters.
+// Formats the validator enforces are documented on the schema.
+func (g *schemaGenerator) queryParameters(v interface{}) []Parameter {
+	t := indirect(reflect.TypeOf(v))
+	var params []Parameter
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("query")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		schema := g.schemaForType(field.Type)
+		rules := field.Tag.Get("validate")
+		for _, rule := range strings.Split(rules, ",") {
+			switch {
+			case rule == "uuid":
+				schema["format"] = "uuid"
+			case strings.HasPrefix(rule, "datetime="):
+				schema["format"] = "date-time"
+			case strings.HasPrefix(rule, "oneof="):
+				schema["enum"] = strings.Fields(strings.TrimPrefix(rule, "oneof="))
+			}
+		}
+
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "query",
+			Required: strings.Contains(rules, "required"),
+			Schema:   schema,
+		})
+	}
+
+	return params
+}
+
+// jsonName returns the JSON property name of a field
+func jsonName(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(opts, "omitempty"), false
+}
+
+// schemaName qualifies the type name with its package, e.g. models.User
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if idx := strings.LastIndex(pkg, "/"); idx >= 0 {
+		pkg = pkg[idx+1:]
+	}
+	if pkg == "" {
+		return t.Name()
+	}
+	return pkg + "." + t.Name()
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,254 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the OpenAPI version of the generated document
+const Version = "3.1.0"
+
+// Schema is a JSON Schema object
+type Schema map[string]interface{}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Schema   Schema `json:"schema"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]Schema         `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// Document is an OpenAPI 3.1 document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+// Route documents a single operation. Request and Response hold zero values
+// of the body types, their schemas are generated by reflection.
+type Route struct {
+	Summary    string
+	Tags       []string
+	Auth       bool
+	Deprecated bool
+	// Idempotent operations accept an Idempotency-Key header
+	Idempotent bool
+	// Query is a zero value of a struct whose query tagged fields are the
+	// query parameters
+	Query    interface{}
+	Request  interface{}
+	Response interface{}
+	// Errors lists the statuses answered with problem details
+	Errors []int
+	// Responses documents other statuses that return a regular body, nil
+	// for statuses without one
+	Responses map[int]interface{}
+}
+
+const (
+	bearerScheme       = "bearerAuth"
+	problemContentType = "application/problem+json"
+)
+
+// Builder collects operations while routes are registered
+type Builder struct {
+	mu      sync.Mutex
+	doc     Document
+	schemas *schemaGenerator
+	problem Schema
+}
+
+// NewBuilder creates a builder. problemType is a zero value of the body
+// returned for error responses.
+func NewBuilder(info Info, problemType interface{}) *Builder {
+	schemas := newSchemaGenerator()
+	return &Builder{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]map[string]*Operation),
+			Components: Components{
+				Schemas: schemas.components,
+				SecuritySchemes: map[string]SecurityScheme{
+					bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				},
+			},
+		},
+		schemas: schemas,
+		problem: schemas.schemaFor(problemType),
+	}
+}
+
+// Add documents an operation. path uses Fiber syntax, e.g. /users/:id.
+func (b *Builder) Add(method, path string, route Route) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	openAPIPath, params := convertPath(path)
+
+	op := &Operation{
+		OperationID: operationID(method, openAPIPath),
+		Summary:     route.Summary,
+		Tags:        route.Tags,
+		Parameters:  params,
+		Responses:   make(map[string]Response),
+		Deprecated:  route.Deprecated,
+	}
+
+	if route.Idempotent {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:   "Idempotency-Key",
+			In:     "header",
+			Schema: Schema{"type": "string", "maxLength": 255},
+		})
+		route.Errors = append(route.Errors, http.StatusConflict, http.StatusUnprocessableEntity)
+	}
+
+	if route.Query != nil {
+		op.Parameters = append(op.Parameters, b.schemas.queryParameters(route.Query)...)
+	}
+
+	if route.Auth {
+		op.Security = []map[string][]string{{bearerScheme: {}}}
+	}
+
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				"application/json": {Schema: b.schemas.schemaFor(route.Request)},
+			},
+		}
+	}
+
+	success := Response{Description: http.StatusText(http.StatusOK)}
+	if route.Response != nil {
+		success.Content = map[string]MediaType{
+			"application/json": {Schema: b.schemas.schemaFor(route.Response)},
+		}
+	}
+	op.Responses[strconv.Itoa(http.StatusOK)] = success
+
+	for status, body := range route.Responses {
+		resp := Response{Description: http.StatusText(status)}
+		if body != nil {
+			resp.Content = map[string]MediaType{
+				"application/json": {Schema: b.schemas.schemaFor(body)},
+			}
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+	}
+
+	for _, status := range route.Errors {
+		op.Responses[strconv.Itoa(status)] = Response{
+			Description: http.StatusText(status),
+			Content: map[string]MediaType{
+				problemContentType: {Schema: b.problem},
+			},
+		}
+	}
+
+	if b.doc.Paths[openAPIPath] == nil {
+		b.doc.Paths[openAPIPath] = make(map[string]*Operation)
+	}
+	b.doc.Paths[openAPIPath][strings.ToLower(method)] = op
+}
+
+// Document returns the collected document
+func (b *Builder) Document() Document {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.doc
+}
+
+// JSON returns the document encoded as JSON
+func (b *Builder) JSON() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return json.Marshal(b.doc)
+}
+
+// convertPath turns /users/:id into /users/{id} and lists the parameters
+func convertPath(path string) (string, []Parameter) {
+	segments := strings.Split(path, "/")
+	var params []Parameter
+
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(segment, ":"), "?")
+		segments[i] = "{" + name + "}"
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   Schema{"type": "string"},
+		})
+	}
+
+	return strings.Join(segments, "/"), params
+}
+
+// operationID builds a stable identifier such as postApiAuthTelegram
+func operationID(method, path string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '.' || r == '-' || r == '_' || r == '{' || r == '}'
+	}) {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+
+	return sb.String()
+}
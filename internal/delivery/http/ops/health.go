@@ -0,0 +1,31 @@
+package ops
+
+import (
+	"net/http"
+
+	"renfound_v1/internal/health"
+)
+
+// healthHandler serves the detailed report of a probe. The public probes
+// only answer with the status, the report names dependencies and carries
+// their raw errors.
+type healthHandler struct {
+	registry *health.Registry
+	kind     health.Kind
+}
+
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET is supported"})
+		return
+	}
+
+	report := h.registry.Run(r.Context(), h.kind)
+
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
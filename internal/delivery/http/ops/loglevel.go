@@ -0,0 +1,99 @@
+package ops
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"renfound_v1/internal/utils/logcontrol"
+)
+
+// logLevelState is the body of /loglevel responses
+type logLevelState struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+	// Known lists the components whose level can be overridden
+	Known []string `json:"known_components"`
+}
+
+// logLevelRequest changes the global level, or the level of a single
+// component when Component is set. An empty component level removes the
+// override.
+type logLevelRequest struct {
+	Level     string `json:"level"`
+	Component string `json:"component,omitempty"`
+}
+
+// logLevelHandler serves GET and PUT /loglevel. Changes last until the
+// next change, SIGHUP or restart.
+type logLevelHandler struct {
+	levels *logcontrol.Controller
+	logger *zap.Logger
+}
+
+func (h *logLevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if err := h.update(w, r); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only GET and PUT are supported"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.state())
+}
+
+func (h *logLevelHandler) update(w http.ResponseWriter, r *http.Request) error {
+	var req logLevelRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+
+	if req.Component != "" && req.Level == "" {
+		h.levels.ClearComponentLevel(req.Component)
+		h.logger.Info("Log level override removed", zap.String("target_component", req.Component))
+		return nil
+	}
+
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+
+	if req.Component == "" {
+		h.levels.SetLevel(level)
+	} else if err := h.levels.SetComponentLevel(req.Component, level); err != nil {
+		return fmt.Errorf("%w %q", err, req.Component)
+	}
+
+	h.logger.Warn("Log level changed",
+		zap.String("level", level.String()),
+		zap.String("target_component", req.Component))
+
+	return nil
+}
+
+func (h *logLevelHandler) state() logLevelState {
+	state := logLevelState{
+		Level:      h.levels.Level().String(),
+		Components: map[string]string{},
+		Known:      h.levels.Components(),
+	}
+	for component, level := range h.levels.ComponentLevels() {
+		state.Components[component] = level.String()
+	}
+	return state
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,105 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/metrics"
+	"renfound_v1/internal/health"
+)
+
+// Server runs plain net/http listeners for operational endpoints, kept
+// apart from the public Fiber app. /metrics is served on the metrics
+// address, which scrapers must reach. /loglevel and the detailed health
+// reports, /livez and /readyz, are unauthenticated and are served on their
+// own address, loopback by default.
+type Server struct {
+	srv     *http.Server // nil when metrics are disabled
+	mux     *http.ServeMux
+	control *http.Server
+	cfg     config.MetricsConfig
+	logger  *zap.Logger
+}
+
+// NewServer creates the ops server, metrics are served when m is not nil
+func NewServer(cfg *config.AppConfig, m *metrics.Metrics, healthRegistry *health.Registry) *Server {
+	metricsCfg := cfg.Config.Metrics
+	controlCfg := cfg.Config.Logger.Control
+	logger := cfg.Logger.With(zap.String("component", "ops_server"))
+
+	s := &Server{
+		mux:    http.NewServeMux(),
+		cfg:    metricsCfg,
+		logger: logger,
+	}
+
+	if m != nil {
+		s.mux.Handle(metricsCfg.Path, m.Handler())
+		s.srv = &http.Server{
+			Addr:              metricsCfg.Host + ":" + metricsCfg.Port,
+			Handler:           s.mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+	}
+
+	controlMux := http.NewServeMux()
+	controlMux.Handle("/loglevel", &logLevelHandler{levels: cfg.LogLevels, logger: logger})
+	controlMux.Handle("/livez", &healthHandler{registry: healthRegistry, kind: health.Liveness})
+	controlMux.Handle("/readyz", &healthHandler{registry: healthRegistry, kind: health.Readiness})
+	s.control = &http.Server{
+		Addr:              controlCfg.Host + ":" + controlCfg.Port,
+		Handler:           controlMux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	return s
+}
+
+// Handle registers an additional handler on the metrics listener
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start starts the listeners and returns the first error of either
+func (s *Server) Start() error {
+	servers := []*http.Server{s.control}
+	if s.srv != nil {
+		s.logger.Info("Starting metrics listener", zap.String("addr", s.srv.Addr), zap.String("metrics_path", s.cfg.Path))
+		servers = append(servers, s.srv)
+	}
+	s.logger.Info("Starting control listener", zap.String("addr", s.control.Addr))
+
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+				return
+			}
+			errs <- nil
+		}(srv)
+	}
+
+	for range servers {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown gracefully shuts down the listeners
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.logger.Info("Shutting down ops server")
+
+	var errs []error
+	if s.srv != nil {
+		errs = append(errs, s.srv.Shutdown(ctx))
+	}
+	errs = append(errs, s.control.Shutdown(ctx))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,111 @@
+package problem
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"renfound_v1/internal/delivery/http/locale"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/utils/validator"
+)
+
+// ContentType is the media type of RFC 7807 problem details
+const ContentType = "application/problem+json"
+
+// typePrefix turns error codes into problem type URIs
+const typePrefix = "urn:renfound:problem:"
+
+// Problem is an RFC 7807 problem details document with the error code and
+// request ID as extension members
+type Problem struct {
+	Type      string                      `json:"type"`
+	Title     string                      `json:"title"`
+	Status    int                         `json:"status"`
+	Detail    string                      `json:"detail,omitempty"`
+	Instance  string                      `json:"instance,omitempty"`
+	Code      string                      `json:"code"`
+	RequestID string                      `json:"request_id,omitempty"`
+	Errors    []validator.ValidationError `json:"errors,omitempty"`
+}
+
+// Error writes the problem for a domain error
+func Error(c *fiber.Ctx, err error) error {
+	return write(c, build(c, models.LookupError(err), ""))
+}
+
+// ErrorWithDetail writes the problem for a domain error with an explanation
+// specific to this occurrence. detailKey is a message catalog key.
+func ErrorWithDetail(c *fiber.Ctx, err error, detailKey string) error {
+	return write(c, build(c, models.LookupError(err), locale.T(c, detailKey, detailKey)))
+}
+
+// Validation writes a validation problem listing the offending fields
+func Validation(c *fiber.Ctx, validationErrors []validator.ValidationError) error {
+	p := build(c, models.LookupError(models.ErrValidation), "")
+	p.Errors = validator.Localize(locale.FromCtx(c), validationErrors)
+	return write(c, p)
+}
+
+// FiberError writes the problem for errors returned to the Fiber error
+// handler, including Fiber's own routing errors such as 404 and 405
+func FiberError(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if !errors.As(err, &fiberErr) {
+		return Error(c, err)
+	}
+
+	info := models.ErrorInfo{
+		Code:    statusCode(fiberErr.Code),
+		Status:  fiberErr.Code,
+		Message: http.StatusText(fiberErr.Code),
+	}
+	if info.Message == "" {
+		info.Message = fiberErr.Message
+	}
+
+	detail := ""
+	if fiberErr.Message != info.Message {
+		detail = fiberErr.Message
+	}
+
+	return write(c, build(c, info, detail))
+}
+
+func build(c *fiber.Ctx, info models.ErrorInfo, detail string) Problem {
+	requestID, _ := c.Locals("requestID").(string)
+
+	return Problem{
+		Type:      typePrefix + info.Code,
+		Title:     locale.T(c, "error."+info.Code, info.Message),
+		Status:    info.Status,
+		Detail:    detail,
+		Instance:  c.Path(),
+		Code:      info.Code,
+		RequestID: requestID,
+	}
+}
+
+func write(c *fiber.Ctx, p Problem) error {
+	c.Status(p.Status)
+	if err := c.JSON(p); err != nil {
+		return err
+	}
+	// c.JSON sets application/json, override it afterwards
+	c.Set(fiber.HeaderContentType, ContentType)
+	c.Set(fiber.HeaderContentLanguage, locale.FromCtx(c))
+	return nil
+}
+
+// statusCode derives a stable code from an HTTP status, e.g. "method_not_allowed"
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "http_error"
+	}
+
+	text = strings.ToLower(text)
+	text = strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+	return text
+}
@@ -2,72 +2,146 @@ package router
 
 import (
 	"context"
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"go.uber.org/zap"
 
 	"renfound_v1/config"
 	"renfound_v1/infrastructure/auth"
+	"renfound_v1/infrastructure/idempotency"
+	"renfound_v1/infrastructure/metrics"
+	"renfound_v1/infrastructure/ratelimit"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/internal/delivery/bot"
+	"renfound_v1/internal/delivery/http/authcookie"
+	"renfound_v1/internal/delivery/http/clientip"
 	"renfound_v1/internal/delivery/http/handler"
 	"renfound_v1/internal/delivery/http/middleware"
+	"renfound_v1/internal/delivery/http/openapi"
+	"renfound_v1/internal/delivery/http/problem"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/health"
+	"renfound_v1/internal/usecase/audit"
+	"renfound_v1/internal/usecase/broadcast"
+	"renfound_v1/internal/usecase/login"
+	"renfound_v1/internal/usecase/payment"
+	"renfound_v1/internal/usecase/referral"
 	"renfound_v1/internal/usecase/user"
 	"renfound_v1/internal/utils/validator"
 )
 
+// apiVersion is reported in the OpenAPI document
+const apiVersion = "1.0.0"
+
 // Router handles routing for the application
 type Router struct {
 	app            *fiber.App
 	cfg            *config.AppConfig
 	userHandler    *handler.UserHandler
+	adminHandler   *handler.AdminHandler
+	healthHandler  *handler.HealthHandler
+	telegram       *handler.TelegramHandler  // nil when the webhook is disabled
+	broadcasts     *handler.BroadcastHandler // nil without a bot
+	payments       *handler.PaymentHandler   // nil without a bot
+	docsHandler    *handler.DocsHandler
+	spec           *openapi.Builder
 	authMiddleware *middleware.AuthMiddleware
 	logMiddleware  *middleware.LoggingMiddleware
+	rateLimit      *middleware.RateLimitMiddleware
+	deprecation    *middleware.DeprecationMiddleware
+	csrf           *middleware.CSRFMiddleware
+	idempotency    *middleware.IdempotencyMiddleware
 	logger         *zap.Logger
 }
 
+// registerFunc registers a route on a group
+type registerFunc func(group fiber.Router, method, path string, doc openapi.Route, handlers ...fiber.Handler)
+
 // NewRouter creates a new router
 func NewRouter(
 	cfg *config.AppConfig,
 	userService user.Service,
+	auditService audit.Service,
+	loginService login.Service,
+	referralService referral.Service,
 	telegramAuth *auth.TelegramAuth,
+	limiter ratelimit.Limiter,
+	idempotencyStore idempotency.Store,
+	appMetrics *metrics.Metrics,
+	healthRegistry *health.Registry,
+	dispatcher *bot.Dispatcher,
+	broadcastService broadcast.Service,
+	paymentService payment.Service,
 ) *Router {
 	logger := cfg.Logger.With(zap.String("component", "router"))
 
 	// Create validator
 	validatorUtil := validator.NewValidator(logger)
 
+	// Refresh token cookies, used only in cookie mode
+	cookies := authcookie.NewManager(cfg.Config)
+
 	// Create handlers
-	userHandler := handler.NewUserHandler(userService, validatorUtil, logger)
+	userHandler := handler.NewUserHandler(userService, auditService, loginService, referralService, validatorUtil, cookies, logger)
+	adminHandler := handler.NewAdminHandler(userService, auditService, referralService, validatorUtil, logger)
+	healthHandler := handler.NewHealthHandler(healthRegistry, logger)
+	var telegramHandler *handler.TelegramHandler
+	if dispatcher != nil {
+		telegramHandler = handler.NewTelegramHandler(dispatcher, cfg.Config.Telegram.Webhook.Secret, logger)
+	}
+	var broadcastHandler *handler.BroadcastHandler
+	if broadcastService != nil {
+		broadcastHandler = handler.NewBroadcastHandler(broadcastService, validatorUtil, logger)
+	}
+	var paymentHandler *handler.PaymentHandler
+	if paymentService != nil {
+		paymentHandler = handler.NewPaymentHandler(paymentService, validatorUtil, logger)
+	}
+
+	// The spec is filled in by SetupRoutes as routes are registered
+	spec := openapi.NewBuilder(openapi.Info{
+		Title:   "Renfound API",
+		Version: apiVersion,
+	}, problem.Problem{})
+	docsHandler := handler.NewDocsHandler(spec, logger)
 
 	// Create middlewares
-	authMiddleware := middleware.NewAuthMiddleware(telegramAuth, logger)
+	authMiddleware := middleware.NewAuthMiddleware(telegramAuth, cfg.Config.Admin, logger)
 	logMiddleware := middleware.NewLoggingMiddleware(logger)
+	rateLimit := middleware.NewRateLimitMiddleware(limiter, cfg.Config.RateLimit, logger)
+	metricsMiddleware := middleware.NewMetricsMiddleware(appMetrics)
+	tracingMiddleware := middleware.NewTracingMiddleware()
+	deprecationMiddleware := middleware.NewDeprecationMiddleware(logger)
+	csrfMiddleware := middleware.NewCSRFMiddleware(cookies, logger)
+	securityMiddleware := middleware.NewSecurityHeadersMiddleware(cfg.Config.Security)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyStore, cfg.Config.Idempotency, logger)
+	auditMiddleware := middleware.NewAuditMiddleware()
+
+	// Trusted proxies were validated when the config was loaded
+	trustedProxies, _ := cfg.Config.Proxy.TrustedPrefixes()
+	ipResolver := clientip.NewResolver(trustedProxies)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		// Override default error handler
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			// Status code defaults to 500
-			code := fiber.StatusInternalServerError
-
-			// Check if it's a Fiber error
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-			}
-
-			// Return JSON error
-			return c.Status(code).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
+		// Render every unhandled error as problem details
+		ErrorHandler: problem.FiberError,
 	})
 
 	// Register global middlewares
+	app.Use(ipResolver.Resolve())
+	app.Use(auditMiddleware.Client())
+	app.Use(securityMiddleware.Headers())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "*",
+		AllowOrigins:     cfg.Config.CORS.Origins(),
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-		AllowCredentials: false,
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Request-ID, " + authcookie.CSRFHeader + ", " + middleware.IdempotencyKeyHeader,
+		ExposeHeaders:    "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After, X-Request-ID, Deprecation, Sunset, Link, " + authcookie.CSRFHeader + ", " + middleware.IdempotentReplayedHeader,
+		AllowCredentials: cfg.Config.CORS.AllowCredentials,
 	}))
+	app.Use(tracingMiddleware.Trace())
+	app.Use(metricsMiddleware.Collect())
 	app.Use(logMiddleware.Logger())
 	app.Use(logMiddleware.RecoverWithLogger())
 
@@ -75,34 +149,345 @@ func NewRouter(
 		app:            app,
 		cfg:            cfg,
 		userHandler:    userHandler,
+		adminHandler:   adminHandler,
+		healthHandler:  healthHandler,
+		telegram:       telegramHandler,
+		broadcasts:     broadcastHandler,
+		payments:       paymentHandler,
+		docsHandler:    docsHandler,
+		spec:           spec,
 		authMiddleware: authMiddleware,
 		logMiddleware:  logMiddleware,
+		rateLimit:      rateLimit,
+		deprecation:    deprecationMiddleware,
+		csrf:           csrfMiddleware,
+		idempotency:    idempotencyMiddleware,
 		logger:         logger,
 	}
 }
 
 // SetupRoutes sets up the routes
 func (r *Router) SetupRoutes() {
+	// Probes live outside /api so they are not rate limited. They answer
+	// with the status only, the detailed reports are served on the ops
+	// control listener.
+	r.handle(r.app, fiber.MethodGet, "/livez", openapi.Route{
+		Summary:   "Liveness probe",
+		Tags:      []string{"health"},
+		Responses: map[int]interface{}{fiber.StatusServiceUnavailable: nil},
+	}, r.healthHandler.Livez)
+	r.handle(r.app, fiber.MethodGet, "/readyz", openapi.Route{
+		Summary:   "Readiness probe",
+		Tags:      []string{"health"},
+		Responses: map[int]interface{}{fiber.StatusServiceUnavailable: nil},
+	}, r.healthHandler.Readyz)
+
+	// Telegram posts updates from a few addresses, so the webhook is
+	// registered without the per IP rate limit of the /api routes
+	if r.telegram != nil {
+		r.handle(r.app, fiber.MethodPost, "/api/telegram/webhook", openapi.Route{
+			Summary: "Receive bot updates from Telegram",
+			Tags:    []string{"telegram"},
+			Request: telegram.Update{},
+			Errors:  []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusTooManyRequests},
+		}, r.telegram.Webhook)
+	}
+
+	// The default rate limit policy is applied per route rather than on the
+	// group, so each route gets its own bucket and it runs after the
+	// authentication of its group
 	api := r.app.Group("/api")
+	limitDefault := r.rateLimit.Limit(config.RateLimitPolicyDefault)
+	handle := r.limited(r.handle, limitDefault)
 
-	// Health check
-	api.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status": "ok",
-		})
-	})
+	// API documentation
+	handle(api, fiber.MethodGet, "/openapi.json", openapi.Route{
+		Summary:  "OpenAPI document",
+		Tags:     []string{"docs"},
+		Response: map[string]interface{}{},
+		Errors:   []int{fiber.StatusTooManyRequests},
+	}, r.docsHandler.Spec)
+	api.Get("/docs", limitDefault, r.docsHandler.UI)
 
-	// Auth routes
-	auth := api.Group("/auth")
-	auth.Post("/telegram", r.userHandler.AuthWithTelegram)
-	auth.Post("/refresh", r.userHandler.RefreshTokens)
-	auth.Post("/logout", r.userHandler.Logout)
-	auth.Post("/logout-all", r.authMiddleware.Authenticate(), r.userHandler.LogoutAll)
+	// Health check, kept for existing clients
+	r.handleDeprecated(api, fiber.MethodGet, "/health", middleware.Deprecation{
+		Successor: "/readyz",
+	}, openapi.Route{
+		Summary:   "Readiness probe",
+		Tags:      []string{"health"},
+		Responses: map[int]interface{}{fiber.StatusServiceUnavailable: nil},
+		Errors:    []int{fiber.StatusTooManyRequests},
+	}, limitDefault, r.healthHandler.Readyz)
+
+	// Versioned routes. The unversioned paths stay as deprecated aliases of
+	// v1 for Mini App clients installed before versioning.
+	r.setupV1(api.Group("/v1"), handle)
+	r.setupV1(api, r.limited(r.alias, limitDefault))
+}
+
+// setupV1 registers the v1 routes on group
+func (r *Router) setupV1(group fiber.Router, register registerFunc) {
+	// Auth routes. Routes issuing tokens do not honor Idempotency-Key, the
+	// stored response would keep live tokens at rest for anyone replaying
+	// the key.
+	auth := group.Group("/auth")
+	register(auth, fiber.MethodPost, "/telegram", openapi.Route{
+		Summary:  "Sign in with Telegram Mini App init data",
+		Tags:     []string{"auth"},
+		Request:  handler.TelegramAuthRequest{},
+		Response: models.Tokens{},
+		Errors:   []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.rateLimit.Limit(config.RateLimitPolicyAuth), r.userHandler.AuthWithTelegram)
+	register(auth, fiber.MethodPost, "/refresh", openapi.Route{
+		Summary:  "Exchange a refresh token for a new token pair",
+		Tags:     []string{"auth"},
+		Request:  handler.RefreshTokenRequest{},
+		Response: models.Tokens{},
+		Errors:   []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.rateLimit.Limit(config.RateLimitPolicyAuth), r.csrf.Protect(), r.userHandler.RefreshTokens)
+	register(auth, fiber.MethodPost, "/logout", openapi.Route{
+		Summary:    "Revoke a refresh token",
+		Idempotent: true,
+		Tags:       []string{"auth"},
+		Request:    handler.LogoutRequest{},
+		Response:   handler.MessageResponse{},
+		Errors:     []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.csrf.Protect(), r.idempotency.Handle(), r.userHandler.Logout)
+	register(auth, fiber.MethodPost, "/logout-all", openapi.Route{
+		Summary:    "Revoke every session of the current user",
+		Idempotent: true,
+		Tags:       []string{"auth"},
+		Auth:       true,
+		Response:   handler.MessageResponse{},
+		Errors:     []int{fiber.StatusUnauthorized, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.authMiddleware.Authenticate(), r.idempotency.Handle(), r.userHandler.LogoutAll)
 
 	// User routes
-	users := api.Group("/users", r.authMiddleware.Authenticate())
-	users.Get("/me", r.userHandler.GetMe)
-	users.Delete("/me", r.userHandler.DeleteMe)
+	users := group.Group("/users", r.authMiddleware.Authenticate())
+	register(users, fiber.MethodGet, "/me", openapi.Route{
+		Summary:  "Get the current user",
+		Tags:     []string{"users"},
+		Auth:     true,
+		Response: models.User{},
+		Errors:   []int{fiber.StatusUnauthorized, fiber.StatusNotFound, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.userHandler.GetMe)
+	register(users, fiber.MethodDelete, "/me", openapi.Route{
+		Summary:    "Delete the current user",
+		Idempotent: true,
+		Tags:       []string{"users"},
+		Auth:       true,
+		Response:   handler.MessageResponse{},
+		Errors:     []int{fiber.StatusUnauthorized, fiber.StatusNotFound, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.idempotency.Handle(), r.userHandler.DeleteMe)
+	register(users, fiber.MethodGet, "/me/security-events", openapi.Route{
+		Summary:  "List the security history of the current user",
+		Tags:     []string{"users"},
+		Auth:     true,
+		Query:    handler.SecurityEventsRequest{},
+		Response: handler.SecurityEventsResponse{},
+		Errors:   []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.userHandler.SecurityEvents)
+	register(users, fiber.MethodGet, "/me/logins", openapi.Route{
+		Summary:  "List the latest logins of the current user",
+		Tags:     []string{"users"},
+		Auth:     true,
+		Query:    handler.LoginsRequest{},
+		Response: handler.LoginsResponse{},
+		Errors:   []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.userHandler.Logins)
+	register(users, fiber.MethodGet, "/me/referrals", openapi.Route{
+		Summary:  "Get the referral code of the current user with its signups",
+		Tags:     []string{"users"},
+		Auth:     true,
+		Response: models.ReferralStats{},
+		Errors:   []int{fiber.StatusUnauthorized, fiber.StatusNotFound, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.userHandler.Referrals)
+
+	// Admin routes, for the Telegram users listed in config
+	admin := group.Group("/admin", r.authMiddleware.Authenticate(), r.authMiddleware.RequireAdmin())
+	register(admin, fiber.MethodGet, "/audit-events", openapi.Route{
+		Summary:  "Search the audit log",
+		Tags:     []string{"admin"},
+		Auth:     true,
+		Query:    handler.AuditEventsRequest{},
+		Response: audit.Page{},
+		Errors:   []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.adminHandler.AuditEvents)
+	register(admin, fiber.MethodGet, "/referral-campaigns", openapi.Route{
+		Summary:  "List the signups brought in per campaign",
+		Tags:     []string{"admin"},
+		Auth:     true,
+		Query:    handler.CampaignStatsRequest{},
+		Response: handler.CampaignStatsResponse{},
+		Errors:   []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.adminHandler.CampaignStats)
+	register(admin, fiber.MethodPost, "/users/:id/logout-all", openapi.Route{
+		Summary:    "Revoke every session of a user",
+		Idempotent: true,
+		Tags:       []string{"admin"},
+		Auth:       true,
+		Response:   handler.MessageResponse{},
+		Errors:     []int{fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.idempotency.Handle(), r.adminHandler.LogoutAllUser)
+
+	if r.broadcasts != nil {
+		r.setupBroadcasts(admin, register)
+	}
+	if r.payments != nil {
+		r.setupPayments(group, users, admin, register)
+	}
+}
+
+// setupPayments registers the routes of payments: buying on group, the
+// current user's purchases on users and refunds on admin
+func (r *Router) setupPayments(group, users, admin fiber.Router, register registerFunc) {
+	payments := group.Group("/payments", r.authMiddleware.Authenticate())
+	register(payments, fiber.MethodGet, "/products", openapi.Route{
+		Summary:  "List the products for sale",
+		Tags:     []string{"payments"},
+		Auth:     true,
+		Response: handler.ProductsResponse{},
+		Errors:   []int{fiber.StatusUnauthorized, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.payments.Products)
+	register(payments, fiber.MethodPost, "/invoices", openapi.Route{
+		Summary:    "Create an invoice link to buy a product with Telegram Stars",
+		Idempotent: true,
+		Tags:       []string{"payments"},
+		Auth:       true,
+		Request:    handler.CreateInvoiceRequest{},
+		Response:   payment.Invoice{},
+		Errors:     []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusNotFound, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.idempotency.Handle(), r.payments.CreateInvoice)
+
+	register(users, fiber.MethodGet, "/me/purchases", openapi.Route{
+		Summary:  "List the purchases of the current user",
+		Tags:     []string{"users"},
+		Auth:     true,
+		Query:    handler.PurchasesRequest{},
+		Response: handler.PurchasesResponse{},
+		Errors:   []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.payments.Purchases)
+	register(users, fiber.MethodGet, "/me/entitlements", openapi.Route{
+		Summary:  "List the features the current user paid for",
+		Tags:     []string{"users"},
+		Auth:     true,
+		Response: handler.EntitlementsResponse{},
+		Errors:   []int{fiber.StatusUnauthorized, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.payments.Entitlements)
+
+	register(admin, fiber.MethodGet, "/users/:id/purchases", openapi.Route{
+		Summary:  "List the purchases of a user",
+		Tags:     []string{"admin"},
+		Auth:     true,
+		Query:    handler.PurchasesRequest{},
+		Response: handler.PurchasesResponse{},
+		Errors:   []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.payments.UserPurchases)
+	register(admin, fiber.MethodPost, "/purchases/:id/refund", openapi.Route{
+		Summary:    "Refund a purchase, revoking what it granted",
+		Idempotent: true,
+		Tags:       []string{"admin"},
+		Auth:       true,
+		Response:   models.Purchase{},
+		Errors:     []int{fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusConflict, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.idempotency.Handle(), r.payments.RefundPurchase)
+}
+
+// setupBroadcasts registers the admin routes of broadcasts on group
+func (r *Router) setupBroadcasts(group fiber.Router, register registerFunc) {
+	register(group, fiber.MethodPost, "/broadcasts", openapi.Route{
+		Summary:    "Start a broadcast to a segment of users",
+		Idempotent: true,
+		Tags:       []string{"admin"},
+		Auth:       true,
+		Request:    handler.CreateBroadcastRequest{},
+		Response:   models.Broadcast{},
+		Errors:     []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.idempotency.Handle(), r.broadcasts.CreateBroadcast)
+	register(group, fiber.MethodGet, "/broadcasts", openapi.Route{
+		Summary:  "List the latest broadcasts",
+		Tags:     []string{"admin"},
+		Auth:     true,
+		Query:    handler.BroadcastsRequest{},
+		Response: handler.BroadcastsResponse{},
+		Errors:   []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.broadcasts.Broadcasts)
+	register(group, fiber.MethodGet, "/broadcasts/:id", openapi.Route{
+		Summary:  "Get a broadcast with its delivery counts",
+		Tags:     []string{"admin"},
+		Auth:     true,
+		Response: models.Broadcast{},
+		Errors:   []int{fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.broadcasts.Broadcast)
+	register(group, fiber.MethodGet, "/broadcasts/:id/recipients", openapi.Route{
+		Summary:  "List the delivery status of a broadcast per user",
+		Tags:     []string{"admin"},
+		Auth:     true,
+		Query:    handler.BroadcastRecipientsRequest{},
+		Response: broadcast.RecipientPage{},
+		Errors:   []int{fiber.StatusBadRequest, fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.broadcasts.BroadcastRecipients)
+	register(group, fiber.MethodPost, "/broadcasts/:id/pause", openapi.Route{
+		Summary:    "Pause a running broadcast",
+		Idempotent: true,
+		Tags:       []string{"admin"},
+		Auth:       true,
+		Response:   models.Broadcast{},
+		Errors:     []int{fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusConflict, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.idempotency.Handle(), r.broadcasts.PauseBroadcast)
+	register(group, fiber.MethodPost, "/broadcasts/:id/resume", openapi.Route{
+		Summary:    "Resume a paused broadcast",
+		Idempotent: true,
+		Tags:       []string{"admin"},
+		Auth:       true,
+		Response:   models.Broadcast{},
+		Errors:     []int{fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusConflict, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.idempotency.Handle(), r.broadcasts.ResumeBroadcast)
+	register(group, fiber.MethodPost, "/broadcasts/:id/cancel", openapi.Route{
+		Summary:    "Cancel a broadcast",
+		Idempotent: true,
+		Tags:       []string{"admin"},
+		Auth:       true,
+		Response:   models.Broadcast{},
+		Errors:     []int{fiber.StatusUnauthorized, fiber.StatusForbidden, fiber.StatusNotFound, fiber.StatusConflict, fiber.StatusTooManyRequests, fiber.StatusInternalServerError},
+	}, r.idempotency.Handle(), r.broadcasts.CancelBroadcast)
+}
+
+// handle registers a route and documents it in the OpenAPI spec, so the
+// spec cannot drift from the routes actually served
+func (r *Router) handle(group fiber.Router, method, path string, doc openapi.Route, handlers ...fiber.Handler) {
+	group.Add(method, path, handlers...)
+	r.spec.Add(method, prefixOf(group)+path, doc)
+}
+
+// handleDeprecated registers a deprecated route. Responses carry the
+// deprecation headers and the spec marks the operation as deprecated.
+func (r *Router) handleDeprecated(group fiber.Router, method, path string, deprecation middleware.Deprecation, doc openapi.Route, handlers ...fiber.Handler) {
+	doc.Deprecated = true
+	r.handle(group, method, path, doc, append([]fiber.Handler{r.deprecation.Deprecate(deprecation)}, handlers...)...)
+}
+
+// alias registers a deprecated unversioned route, its successor is the
+// same route under /api/v1
+func (r *Router) alias(group fiber.Router, method, path string, doc openapi.Route, handlers ...fiber.Handler) {
+	r.handleDeprecated(group, method, path, middleware.Deprecation{
+		Successor: "/api/v1" + strings.TrimPrefix(prefixOf(group)+path, "/api"),
+	}, doc, handlers...)
+}
+
+// limited wraps register so limit runs first among the route handlers
+func (r *Router) limited(register registerFunc, limit fiber.Handler) registerFunc {
+	return func(group fiber.Router, method, path string, doc openapi.Route, handlers ...fiber.Handler) {
+		register(group, method, path, doc, append([]fiber.Handler{limit}, handlers...)...)
+	}
+}
+
+// prefixOf returns the full path prefix of a group, empty for the app itself
+func prefixOf(group fiber.Router) string {
+	if g, ok := group.(*fiber.Group); ok {
+		return g.Prefix
+	}
+	return ""
 }
 
 // Start starts the server
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions, stored in audit_events.action. Never rename a published
+// action, queries and alerts filter on them.
+const (
+	AuditActionLogin          = "auth.login"
+	AuditActionRefresh        = "auth.refresh"
+	AuditActionLogout         = "auth.logout"
+	AuditActionLogoutAll      = "auth.logout_all"
+	AuditActionUserDelete     = "user.delete"
+	AuditActionAdminLogoutAll = "admin.user.logout_all"
+	AuditActionAdminAuditRead = "admin.audit.read"
+
+	AuditActionAdminBroadcastCreate = "admin.broadcast.create"
+	AuditActionAdminBroadcastPause  = "admin.broadcast.pause"
+	AuditActionAdminBroadcastResume = "admin.broadcast.resume"
+	AuditActionAdminBroadcastCancel = "admin.broadcast.cancel"
+
+	AuditActionAdminPurchaseRefund = "admin.purchase.refund"
+)
+
+// Audit outcomes
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent is a security relevant event. Events are append only, they
+// are never updated or deleted, not even when the user is deleted.
+type AuditEvent struct {
+	ID        uuid.UUID  `json:"id"`
+	ActorID   *uuid.UUID `json:"actor_id,omitempty"` // nil when the caller is not known, e.g. a failed login
+	Action    string     `json:"action"`
+	TargetID  *uuid.UUID `json:"target_id,omitempty"` // the user the action applies to
+	Outcome   string     `json:"outcome"`
+	Reason    string     `json:"reason,omitempty"` // error code of failures
+	IPAddress string     `json:"ip_address,omitempty"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// AuditFilter selects audit events, newest first. Zero fields don't filter.
+type AuditFilter struct {
+	ActorID  *uuid.UUID
+	TargetID *uuid.UUID
+	Action   string
+	Outcome  string
+	From     time.Time
+	To       time.Time
+	// Before continues a listing after the last event of the previous page
+	Before *AuditCursor
+	Limit  int
+}
+
+// AuditCursor is the position of an event in the newest first order
+type AuditCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
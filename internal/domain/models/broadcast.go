@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Broadcast statuses
+const (
+	BroadcastStatusRunning   = "running"
+	BroadcastStatusPaused    = "paused"
+	BroadcastStatusCancelled = "cancelled"
+	BroadcastStatusCompleted = "completed"
+)
+
+// Broadcast recipient statuses
+const (
+	RecipientStatusPending   = "pending"
+	RecipientStatusSending   = "sending" // claimed by an instance
+	RecipientStatusSent      = "sent"
+	RecipientStatusFailed    = "failed"
+	RecipientStatusSkipped   = "skipped" // the user blocked the bot
+	RecipientStatusCancelled = "cancelled"
+)
+
+// BroadcastSegment selects the users a broadcast is sent to. Zero fields
+// don't filter.
+type BroadcastSegment struct {
+	LanguageCode string `json:"language_code,omitempty"`
+	// ActiveWithinDays keeps users who logged in within that many days
+	ActiveWithinDays int `json:"active_within_days,omitempty"`
+}
+
+// BroadcastStats counts the recipients of a broadcast by status. Pending
+// includes recipients being sent to.
+type BroadcastStats struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Sent      int `json:"sent"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+	Cancelled int `json:"cancelled"`
+}
+
+// Broadcast is a message sent by admins to a segment of users. Recipients
+// are chosen when the broadcast is created.
+type Broadcast struct {
+	ID          uuid.UUID        `json:"id"`
+	Text        string           `json:"text"`
+	Segment     BroadcastSegment `json:"segment"`
+	Status      string           `json:"status"`
+	CreatedBy   *uuid.UUID       `json:"created_by,omitempty"`
+	Stats       BroadcastStats   `json:"stats"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+}
+
+// BroadcastRecipient is the delivery status of a broadcast to a user
+type BroadcastRecipient struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	Status    string     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// BroadcastDelivery is a recipient claimed for sending
+type BroadcastDelivery struct {
+	BroadcastID  uuid.UUID
+	UserID       uuid.UUID
+	TelegramID   int64
+	BotReachable bool
+	Text         string
+}
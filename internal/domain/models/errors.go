@@ -1,14 +1,24 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+)
 
 var (
 	// Generic errors
-	ErrInternalServer = errors.New("internal server error")
-	ErrNotFound       = errors.New("resource not found")
-	ErrConflict       = errors.New("resource already exists")
-	ErrBadRequest     = errors.New("bad request")
-	ErrValidation     = errors.New("validation error")
+	ErrInternalServer  = errors.New("internal server error")
+	ErrNotFound        = errors.New("resource not found")
+	ErrConflict        = errors.New("resource already exists")
+	ErrBadRequest      = errors.New("bad request")
+	ErrValidation      = errors.New("validation error")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrForbidden       = errors.New("forbidden")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+
+	// Idempotency errors
+	ErrIdempotencyKeyReused  = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
 
 	// Authentication errors
 	ErrUnauthorized       = errors.New("unauthorized")
@@ -17,6 +27,7 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrInvalidSignature   = errors.New("invalid signature")
 	ErrInvalidInitData    = errors.New("invalid telegram init data")
+	ErrInvalidCSRFToken   = errors.New("invalid csrf token")
 
 	// User errors
 	ErrUserNotFound = errors.New("user not found")
@@ -25,16 +36,79 @@ var (
 	// Session errors
 	ErrSessionNotFound = errors.New("session not found")
 	ErrInvalidSession  = errors.New("invalid session")
+
+	// Broadcast errors
+	ErrBroadcastNotFound       = errors.New("broadcast not found")
+	ErrBroadcastStatusConflict = errors.New("broadcast status does not allow this change")
+
+	// Payment errors
+	ErrProductNotFound       = errors.New("product not found")
+	ErrPurchaseNotFound      = errors.New("purchase not found")
+	ErrPurchaseNotRefundable = errors.New("purchase cannot be refunded")
 )
 
-type ErrorResponse struct {
-	Error       string `json:"error"`
-	Description string `json:"description,omitempty"`
+// ErrorInfo describes how a domain error is exposed to API clients
+type ErrorInfo struct {
+	Code    string // stable machine readable code, never change once published
+	Status  int    // HTTP status code
+	Message string // human readable summary
 }
 
-func NewErrorResponse(err error, description string) ErrorResponse {
-	return ErrorResponse{
-		Error:       err.Error(),
-		Description: description,
+// errorRegistry maps every domain error to its public representation.
+// Errors not listed here are reported as internal errors.
+var errorRegistry = []struct {
+	err  error
+	info ErrorInfo
+}{
+	// Generic errors
+	{ErrInternalServer, ErrorInfo{"internal_error", http.StatusInternalServerError, "Internal server error"}},
+	{ErrNotFound, ErrorInfo{"not_found", http.StatusNotFound, "Resource not found"}},
+	{ErrConflict, ErrorInfo{"conflict", http.StatusConflict, "Resource already exists"}},
+	{ErrBadRequest, ErrorInfo{"bad_request", http.StatusBadRequest, "Bad request"}},
+	{ErrValidation, ErrorInfo{"validation_failed", http.StatusBadRequest, "Validation failed"}},
+	{ErrTooManyRequests, ErrorInfo{"too_many_requests", http.StatusTooManyRequests, "Too many requests"}},
+	{ErrForbidden, ErrorInfo{"forbidden", http.StatusForbidden, "Forbidden"}},
+	{ErrInvalidCursor, ErrorInfo{"invalid_cursor", http.StatusBadRequest, "Invalid pagination cursor"}},
+
+	// Idempotency errors
+	{ErrIdempotencyKeyReused, ErrorInfo{"idempotency_key_reused", http.StatusUnprocessableEntity, "Idempotency key reused with a different request"}},
+	{ErrIdempotencyInProgress, ErrorInfo{"idempotency_in_progress", http.StatusConflict, "A request with this idempotency key is in progress"}},
+
+	// Authentication errors
+	{ErrUnauthorized, ErrorInfo{"unauthorized", http.StatusUnauthorized, "Unauthorized"}},
+	{ErrInvalidToken, ErrorInfo{"invalid_token", http.StatusUnauthorized, "Invalid token"}},
+	{ErrExpiredToken, ErrorInfo{"token_expired", http.StatusUnauthorized, "Token has expired"}},
+	{ErrInvalidCredentials, ErrorInfo{"invalid_credentials", http.StatusUnauthorized, "Invalid credentials"}},
+	{ErrInvalidSignature, ErrorInfo{"invalid_signature", http.StatusBadRequest, "Invalid signature"}},
+	{ErrInvalidInitData, ErrorInfo{"invalid_init_data", http.StatusBadRequest, "Invalid Telegram init data"}},
+	{ErrInvalidCSRFToken, ErrorInfo{"invalid_csrf_token", http.StatusForbidden, "Invalid CSRF token"}},
+
+	// User errors
+	{ErrUserNotFound, ErrorInfo{"user_not_found", http.StatusNotFound, "User not found"}},
+	{ErrUserExists, ErrorInfo{"user_exists", http.StatusConflict, "User already exists"}},
+
+	// Session errors
+	{ErrSessionNotFound, ErrorInfo{"session_not_found", http.StatusUnauthorized, "Session not found"}},
+	{ErrInvalidSession, ErrorInfo{"invalid_session", http.StatusUnauthorized, "Invalid session"}},
+
+	// Broadcast errors
+	{ErrBroadcastNotFound, ErrorInfo{"broadcast_not_found", http.StatusNotFound, "Broadcast not found"}},
+	{ErrBroadcastStatusConflict, ErrorInfo{"broadcast_status_conflict", http.StatusConflict, "Broadcast status does not allow this change"}},
+
+	// Payment errors
+	{ErrProductNotFound, ErrorInfo{"product_not_found", http.StatusNotFound, "Product not found"}},
+	{ErrPurchaseNotFound, ErrorInfo{"purchase_not_found", http.StatusNotFound, "Purchase not found"}},
+	{ErrPurchaseNotRefundable, ErrorInfo{"purchase_not_refundable", http.StatusConflict, "Purchase cannot be refunded"}},
+}
+
+// LookupError returns the public representation of err. Wrapped errors are
+// matched with errors.Is; unknown errors map to an internal error.
+func LookupError(err error) ErrorInfo {
+	for _, entry := range errorRegistry {
+		if errors.Is(err, entry.err) {
+			return entry.info
+		}
 	}
+
+	return errorRegistry[0].info
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Login methods, stored in login_history.method
+const (
+	LoginMethodTelegram = "telegram"
+	LoginMethodRefresh  = "refresh"
+)
+
+// Login is one successful sign in or token refresh of a user
+type Login struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Method    string    `json:"method"`
+	DeviceID  string    `json:"device_id"` // fingerprint of the device
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	NewDevice bool      `json:"new_device"` // first login from the device
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Device is a device a user has signed in from, identified by its
+// fingerprint
+type Device struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Fingerprint string    `json:"fingerprint"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	IPAddress   string    `json:"ip_address,omitempty"` // latest address
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
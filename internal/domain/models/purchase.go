@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purchase statuses
+const (
+	PurchaseStatusPending  = "pending" // invoice created, not paid yet
+	PurchaseStatusPaid     = "paid"
+	PurchaseStatusRefunded = "refunded"
+)
+
+// Product is a premium feature sold for Telegram Stars
+type Product struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Price       int    `json:"price"` // in Stars
+	Entitlement string `json:"entitlement"`
+	// DurationSeconds is how long the entitlement lasts, 0 for forever
+	DurationSeconds int64 `json:"duration_seconds,omitempty"`
+}
+
+// Purchase is an entry of the purchases ledger. It is created with the
+// invoice, which carries its ID as payload.
+type Purchase struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"` // nil once the account is deleted
+	TelegramID  int64      `json:"telegram_id"`
+	ProductID   string     `json:"product_id"`
+	Entitlement string     `json:"entitlement"`
+	Amount      int        `json:"amount"`
+	Currency    string     `json:"currency"`
+	// DurationSeconds is how long the entitlement lasts, as sold
+	DurationSeconds int64  `json:"duration_seconds,omitempty"`
+	Status          string `json:"status"`
+	// ChargeID is Telegram's id of the payment, needed to refund it
+	ChargeID   string     `json:"charge_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	PaidAt     *time.Time `json:"paid_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // nil for entitlements that never expire
+	RefundedAt *time.Time `json:"refunded_at,omitempty"`
+}
+
+// Entitlement is a feature a user paid for and may use
+type Entitlement struct {
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil if it never expires
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Referral attributes a signup to the user or the campaign that brought
+// the user in. One of ReferrerID and Campaign is set. A Telegram user is
+// attributed once, even if they delete their account and sign up again.
+type Referral struct {
+	TelegramID int64
+	UserID     uuid.UUID
+	ReferrerID *uuid.UUID
+	Campaign   string
+	CreatedAt  time.Time
+}
+
+// ReferralStats sums up the signups a user brought in
+type ReferralStats struct {
+	Code string `json:"code"`
+	// Link opens the Mini App with the code, empty unless the Mini App
+	// link is configured
+	Link           string     `json:"link,omitempty"`
+	Referred       int        `json:"referred"`
+	LastReferredAt *time.Time `json:"last_referred_at,omitempty"`
+}
+
+// CampaignStats counts the signups brought in by a campaign
+type CampaignStats struct {
+	Campaign     string    `json:"campaign"`
+	Signups      int       `json:"signups"`
+	LastSignupAt time.Time `json:"last_signup_at"`
+}
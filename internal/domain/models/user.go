@@ -8,28 +8,33 @@ import (
 
 // User represents a user entity
 type User struct {
-	ID         uuid.UUID `json:"id"`
-	TelegramID int64     `json:"telegram_id"`
-	Username   string    `json:"username,omitempty"`
-	FirstName  string    `json:"first_name,omitempty"`
-	LastName   string    `json:"last_name,omitempty"`
-	PhotoURL   string    `json:"photo_url,omitempty"`
-	AuthDate   int64     `json:"auth_date"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID           uuid.UUID `json:"id"`
+	TelegramID   int64     `json:"telegram_id"`
+	Username     string    `json:"username,omitempty"`
+	FirstName    string    `json:"first_name,omitempty"`
+	LastName     string    `json:"last_name,omitempty"`
+	PhotoURL     string    `json:"photo_url,omitempty"`
+	LanguageCode string    `json:"language_code,omitempty"`
+	AuthDate     int64     `json:"auth_date"`
+	// BotReachable is false while the user has the bot blocked
+	BotReachable bool      `json:"bot_reachable"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
-func NewUser(telegramID int64, username, firstName, lastName, photoURL string, authDate int64) *User {
+func NewUser(telegramID int64, username, firstName, lastName, photoURL, languageCode string, authDate int64) *User {
 	return &User{
-		ID:         uuid.New(),
-		TelegramID: telegramID,
-		Username:   username,
-		FirstName:  firstName,
-		LastName:   lastName,
-		PhotoURL:   photoURL,
-		AuthDate:   authDate,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:           uuid.New(),
+		TelegramID:   telegramID,
+		Username:     username,
+		FirstName:    firstName,
+		LastName:     lastName,
+		PhotoURL:     photoURL,
+		LanguageCode: languageCode,
+		AuthDate:     authDate,
+		BotReachable: true,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 }
 
@@ -59,10 +64,11 @@ func NewSession(userID uuid.UUID, refreshToken, userAgent, ipAddress string, ttl
 
 type Tokens struct {
 	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token,omitempty"` // empty when sent as a cookie
 }
 
 type Claims struct {
-	UserID     string `json:"user_id"`
-	TelegramID int64  `json:"telegram_id"`
+	UserID       string `json:"user_id"`
+	TelegramID   int64  `json:"telegram_id"`
+	LanguageCode string `json:"language_code,omitempty"`
 }
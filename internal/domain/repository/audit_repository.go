@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"renfound_v1/internal/domain/models"
+)
+
+// AuditRepository defines the interface for audit event persistence. There
+// is deliberately no way to update or delete events.
+type AuditRepository interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	List(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, error)
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"renfound_v1/internal/domain/models"
+)
+
+// BroadcastRepository defines the interface for broadcast persistence
+type BroadcastRepository interface {
+	// Create stores the broadcast together with its recipients, the users
+	// of its segment. Users who blocked the bot are added as skipped.
+	Create(ctx context.Context, broadcast *models.Broadcast) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Broadcast, error)
+	// GetStatus returns the status of a broadcast without counting its
+	// recipients
+	GetStatus(ctx context.Context, id uuid.UUID) (string, error)
+	List(ctx context.Context, limit int) ([]*models.Broadcast, error)
+	// SetStatus changes the status of a broadcast whose status is one of
+	// from. Cancelling also cancels the recipients not sent to yet, but
+	// those claimed under a lease still running.
+	SetStatus(ctx context.Context, id uuid.UUID, from []string, to string) error
+	// Complete marks running broadcasts without recipients left to send
+	// to as completed, and cancels the recipients of cancelled broadcasts
+	// whose lease ran out
+	Complete(ctx context.Context) error
+
+	// ListRecipients lists recipients by user ID, after the given one
+	ListRecipients(ctx context.Context, broadcastID uuid.UUID, status string, after *uuid.UUID, limit int) ([]*models.BroadcastRecipient, error)
+	// ClaimRecipients claims pending recipients of running broadcasts for
+	// lease, along with recipients whose previous lease ran out
+	ClaimRecipients(ctx context.Context, limit int, lease time.Duration) ([]*models.BroadcastDelivery, error)
+	// SetRecipientStatus records the outcome of a claimed recipient.
+	// Setting it back to pending releases it, or cancels it when the
+	// broadcast was cancelled.
+	SetRecipientStatus(ctx context.Context, broadcastID, userID uuid.UUID, status, errorMessage string) error
+}
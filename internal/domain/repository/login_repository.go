@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"renfound_v1/internal/domain/models"
+)
+
+// LoginRepository defines the interface for login history persistence
+type LoginRepository interface {
+	// TouchDevice stores the device or refreshes its last seen time,
+	// reporting whether the device was new
+	TouchDevice(ctx context.Context, device *models.Device) (bool, error)
+	CountDevices(ctx context.Context, userID uuid.UUID) (int, error)
+
+	CreateLogin(ctx context.Context, login *models.Login) error
+	ListLogins(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Login, error)
+}
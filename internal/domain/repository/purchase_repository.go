@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"renfound_v1/internal/domain/models"
+)
+
+// PurchaseRepository defines the interface for the purchases ledger.
+// Purchases and entitlements of a user are those of their Telegram user,
+// including purchases made with an account since deleted.
+type PurchaseRepository interface {
+	Create(ctx context.Context, purchase *models.Purchase) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
+	GetByChargeID(ctx context.Context, chargeID string) (*models.Purchase, error)
+	// MarkPaid records the payment of a pending purchase, ErrConflict if
+	// it is not pending. The entitlement starts when the user's current
+	// one of the same name expires.
+	MarkPaid(ctx context.Context, id uuid.UUID, chargeID string, paidAt time.Time) (*models.Purchase, error)
+	// MarkRefunded records the refund of a paid purchase,
+	// ErrPurchaseNotRefundable if it is not paid
+	MarkRefunded(ctx context.Context, id uuid.UUID, refundedAt time.Time) (*models.Purchase, error)
+
+	// ListByUser lists paid and refunded purchases, newest first
+	ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Purchase, error)
+	// ListEntitlements lists the entitlements of the user active at now
+	ListEntitlements(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Entitlement, error)
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"renfound_v1/internal/domain/models"
+)
+
+// ReferralRepository defines the interface for referral persistence
+type ReferralRepository interface {
+	// EnsureCode returns the referral code of the user, setting it to code
+	// first when the user has none. A code already taken is ErrConflict.
+	EnsureCode(ctx context.Context, userID uuid.UUID, code string) (string, error)
+	GetUserIDByCode(ctx context.Context, code string) (uuid.UUID, error)
+
+	// Create stores the referral unless the Telegram user was attributed
+	// before, reporting whether it was stored
+	Create(ctx context.Context, referral *models.Referral) (bool, error)
+	// CountByReferrer counts the users a user referred, with the time of
+	// the latest referral, nil when there was none
+	CountByReferrer(ctx context.Context, referrerID uuid.UUID) (int, *time.Time, error)
+	// ListCampaignStats lists campaigns by signups, most first
+	ListCampaignStats(ctx context.Context, limit int) ([]*models.CampaignStats, error)
+}
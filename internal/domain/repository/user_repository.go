@@ -14,6 +14,9 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
 	GetByTelegramID(ctx context.Context, telegramID int64) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
+	// SetBotReachable records whether the bot can message the user. Unknown
+	// Telegram users are ignored.
+	SetBotReachable(ctx context.Context, telegramID int64, reachable bool) error
 	Delete(ctx context.Context, id uuid.UUID) error
 
 	// Session operations
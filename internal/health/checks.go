@@ -0,0 +1,132 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"renfound_v1/infrastructure/persistence/postgres"
+	"renfound_v1/infrastructure/persistence/redis"
+	"renfound_v1/internal/utils/async"
+)
+
+// PostgresCheck pings the database and reports pool usage
+func PostgresCheck(db *postgres.Database) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		if err := db.Pool.Ping(ctx); err != nil {
+			return nil, fmt.Errorf("ping failed: %w", err)
+		}
+
+		stat := db.Pool.Stat()
+		return map[string]interface{}{
+			"total_conns":    stat.TotalConns(),
+			"acquired_conns": stat.AcquiredConns(),
+			"max_conns":      stat.MaxConns(),
+		}, nil
+	}
+}
+
+// RedisCheck pings redis
+func RedisCheck(client *redis.Client) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		if err := client.Ping(ctx).Err(); err != nil {
+			return nil, fmt.Errorf("ping failed: %w", err)
+		}
+		return nil, nil
+	}
+}
+
+// WorkerPoolCheck fails when any lane queue is filled above the given ratio
+// of its capacity, since new tasks are about to be dropped
+func WorkerPoolCheck(pool *async.WorkerPool, maxSaturation float64) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		stats := pool.Stats()
+
+		details := make(map[string]interface{}, len(stats.Lanes)+1)
+		details["active_workers"] = stats.ActiveWorkers
+
+		var saturated []string
+		for name, lane := range stats.Lanes {
+			saturation := 0.0
+			if lane.QueueCapacity > 0 {
+				saturation = float64(lane.QueueDepth) / float64(lane.QueueCapacity)
+			}
+			details[name] = map[string]interface{}{
+				"queue_depth":    lane.QueueDepth,
+				"queue_capacity": lane.QueueCapacity,
+				"saturation":     saturation,
+			}
+			if saturation >= maxSaturation {
+				saturated = append(saturated, name)
+			}
+		}
+
+		if len(saturated) > 0 {
+			return details, fmt.Errorf("lanes saturated: %v", saturated)
+		}
+		return details, nil
+	}
+}
+
+// WorkerPoolStallCheck fails when every worker stayed busy with tasks
+// queued and none finished for stallTimeout. Such a pool only recovers by
+// restarting the process, so the check belongs to the liveness probe.
+func WorkerPoolStallCheck(pool *async.WorkerPool, stallTimeout time.Duration) CheckFunc {
+	var (
+		mu           sync.Mutex
+		lastFinished uint64
+		lastProgress = time.Now()
+	)
+
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		stats := pool.Stats()
+		finished := stats.Completed + stats.Panicked
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		if finished != lastFinished || stats.ActiveWorkers < int64(stats.Workers) || stats.QueueDepth == 0 {
+			lastFinished = finished
+			lastProgress = now
+		}
+		stalled := now.Sub(lastProgress)
+
+		details := map[string]interface{}{
+			"active_workers": stats.ActiveWorkers,
+			"queue_depth":    stats.QueueDepth,
+			"stalled_for":    stalled.String(),
+		}
+		if stalled >= stallTimeout {
+			return details, fmt.Errorf("no task finished for %s with every worker busy", stalled.Round(time.Second))
+		}
+		return details, nil
+	}
+}
+
+// MigrationCheck fails when the database schema is behind the migrations
+// embedded in the binary or a migration was left dirty
+func MigrationCheck(db *postgres.Database, expected uint) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		version, dirty, err := db.MigrationVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		details := map[string]interface{}{
+			"version":  version,
+			"expected": expected,
+			"dirty":    dirty,
+		}
+
+		switch {
+		case dirty:
+			return details, errors.New("migration is dirty")
+		case version < expected:
+			return details, fmt.Errorf("schema version %d is behind expected %d", version, expected)
+		}
+		return details, nil
+	}
+}
@@ -0,0 +1,209 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Check statuses
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc runs a single health check. Details are optional and shown in
+// the report next to the check result.
+type CheckFunc func(ctx context.Context) (details map[string]interface{}, err error)
+
+// Kind selects which probe a check belongs to
+type Kind int
+
+const (
+	Readiness Kind = iota
+	Liveness
+)
+
+// Options configure a registered check
+type Options struct {
+	Kind     Kind
+	Timeout  time.Duration
+	CacheTTL time.Duration
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Status    string                 `json:"status"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Duration  string                 `json:"duration"`
+	CheckedAt time.Time              `json:"checked_at"`
+	Cached    bool                   `json:"cached"`
+}
+
+// Report aggregates the results of all checks of a probe
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// OK reports whether every check passed
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+	opts Options
+
+	// mu serializes runs so concurrent probes share one in-flight check
+	mu     sync.Mutex
+	last   Result
+	hasRun bool
+}
+
+// Registry holds the registered health checks
+type Registry struct {
+	mu             sync.RWMutex
+	checks         []*check
+	defaultTimeout time.Duration
+	defaultTTL     time.Duration
+	logger         *zap.Logger
+}
+
+func NewRegistry(defaultTimeout, defaultTTL time.Duration, logger *zap.Logger) *Registry {
+	return &Registry{
+		defaultTimeout: defaultTimeout,
+		defaultTTL:     defaultTTL,
+		logger:         logger.With(zap.String("component", "health")),
+	}
+}
+
+// Register adds a check. Zero timeout and cache TTL use the registry defaults.
+func (r *Registry) Register(name string, fn CheckFunc, opts Options) {
+	if opts.Timeout == 0 {
+		opts.Timeout = r.defaultTimeout
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = r.defaultTTL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, &check{name: name, fn: fn, opts: opts})
+}
+
+// Run executes every check of the given kind concurrently and aggregates
+// the results. Checks whose last result is younger than their cache TTL are
+// not re-run.
+func (r *Registry) Run(ctx context.Context, kind Kind) Report {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		if c.opts.Kind == kind {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = r.runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]Result, len(checks)),
+	}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) runCheck(ctx context.Context, c *check) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.hasRun && time.Since(c.last.CheckedAt) < c.opts.CacheTTL {
+		cached := c.last
+		cached.Cached = true
+		return cached
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := safeRun(checkCtx, c.fn)
+
+	result := Result{
+		Status:    StatusOK,
+		Details:   details,
+		Duration:  time.Since(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+		r.logger.Warn("Health check failed", zap.String("check", c.name), zap.Error(err))
+	}
+
+	c.last = result
+	c.hasRun = true
+
+	return result
+}
+
+// panicError is returned when a check panics
+type panicError struct {
+	value interface{}
+}
+
+func (e panicError) Error() string {
+	return fmt.Sprintf("check panicked: %v", e.value)
+}
+
+// safeRun runs the check and honors the timeout even if the check ignores ctx
+func safeRun(ctx context.Context, fn CheckFunc) (map[string]interface{}, error) {
+	type outcome struct {
+		details map[string]interface{}
+		err     error
+	}
+
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- outcome{err: panicError{value: rec}}
+			}
+		}()
+		details, err := fn(ctx)
+		done <- outcome{details: details, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.details, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,133 @@
+// Package i18n holds the message catalogs used for API responses
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is used when no supported locale can be determined
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// catalogs maps a locale to its messages, loaded once at startup
+var catalogs = mustLoadCatalogs()
+
+func mustLoadCatalogs() map[string]map[string]string {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic("i18n: failed to read catalogs: " + err.Error())
+	}
+
+	result := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := localeFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic("i18n: failed to read catalog " + entry.Name() + ": " + err.Error())
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic("i18n: invalid catalog " + entry.Name() + ": " + err.Error())
+		}
+
+		result[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+
+	return result
+}
+
+// Supported reports whether there is a catalog for the locale
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// T returns the message for key in locale, falling back to the default
+// locale and then to fallback. Placeholders like {param} are replaced with
+// the matching entry of args.
+func T(locale, key, fallback string, args map[string]string) string {
+	msg, ok := catalogs[locale][key]
+	if !ok {
+		msg, ok = catalogs[DefaultLocale][key]
+	}
+	if !ok {
+		msg = fallback
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+
+	pairs := make([]string, 0, len(args)*2)
+	for name, value := range args {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+// Normalize reduces a language tag such as "ru-RU" or "pt_BR" to a
+// supported locale, returning false if there is none
+func Normalize(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", false
+	}
+
+	base, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if Supported(base) {
+		return base, true
+	}
+	return "", false
+}
+
+// MatchAcceptLanguage picks the supported locale with the highest quality
+// from an Accept-Language header value
+func MatchAcceptLanguage(header string) (string, bool) {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if tag != "" && q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if locale, ok := Normalize(c.tag); ok {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// Resolve picks the response locale. The user's stored Telegram language
+// wins over Accept-Language, since Mini App webviews often send the device
+// language rather than the one the user chose in Telegram.
+func Resolve(userLanguage, acceptLanguage string) string {
+	if locale, ok := Normalize(userLanguage); ok {
+		return locale
+	}
+	if locale, ok := MatchAcceptLanguage(acceptLanguage); ok {
+		return locale
+	}
+	return DefaultLocale
+}
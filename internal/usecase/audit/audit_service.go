@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/utils/async"
+	"renfound_v1/internal/utils/logctx"
+	"renfound_v1/internal/utils/strutil"
+)
+
+var tracer = otel.Tracer("renfound_v1/internal/usecase/audit")
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type ServiceImpl struct {
+	auditRepo  repository.AuditRepository
+	workerPool *async.WorkerPool
+	logger     *zap.Logger
+}
+
+func NewService(cfg *config.AppConfig, auditRepo repository.AuditRepository, workerPool *async.WorkerPool) Service {
+	return &ServiceImpl{
+		auditRepo:  auditRepo,
+		workerPool: workerPool,
+		logger:     cfg.Logger.With(zap.String("component", "audit_service")),
+	}
+}
+
+func (s *ServiceImpl) Record(ctx context.Context, event models.AuditEvent) {
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now()
+
+	if event.ActorID == nil {
+		if id, err := uuid.Parse(logctx.UserID(ctx)); err == nil {
+			event.ActorID = &id
+		}
+	}
+	if event.RequestID == "" {
+		event.RequestID = logctx.RequestID(ctx)
+	}
+	client := ClientFromContext(ctx)
+	if event.IPAddress == "" {
+		event.IPAddress = client.IPAddress
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = client.UserAgent
+	}
+	event.UserAgent = strutil.Truncate(event.UserAgent, strutil.MaxUserAgentLength)
+
+	write := func(ctx context.Context) {
+		if err := s.auditRepo.Create(ctx, &event); err != nil {
+			logctx.Logger(ctx, s.logger).Error("Failed to record audit event",
+				zap.Error(err),
+				zap.String("action", event.Action),
+				zap.String("outcome", event.Outcome))
+		}
+	}
+
+	// Audit writes share the critical lane with session writes. When the
+	// lane is full the event is written inline rather than lost.
+	if !s.workerPool.SubmitContext(ctx, async.LaneCritical, write) {
+		write(ctx)
+	}
+}
+
+func (s *ServiceImpl) Search(ctx context.Context, query Query) (*Page, error) {
+	ctx, span := tracer.Start(ctx, "AuditService.Search")
+	defer span.End()
+
+	filter := models.AuditFilter{
+		ActorID:  query.ActorID,
+		TargetID: query.TargetID,
+		Action:   query.Action,
+		Outcome:  query.Outcome,
+		From:     query.From,
+		To:       query.To,
+	}
+
+	page, err := s.list(ctx, filter, query.Cursor, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reading the audit log is itself audited
+	s.Record(ctx, models.AuditEvent{
+		Action:  models.AuditActionAdminAuditRead,
+		Outcome: models.AuditOutcomeSuccess,
+	})
+
+	return page, nil
+}
+
+func (s *ServiceImpl) History(ctx context.Context, userID uuid.UUID, cursor string, limit int) (*Page, error) {
+	ctx, span := tracer.Start(ctx, "AuditService.History")
+	defer span.End()
+
+	return s.list(ctx, models.AuditFilter{TargetID: &userID}, cursor, limit)
+}
+
+// list fetches one page. One extra event is requested to know whether
+// another page follows.
+func (s *ServiceImpl) list(ctx context.Context, filter models.AuditFilter, cursor string, limit int) (*Page, error) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	if cursor != "" {
+		before, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		filter.Before = before
+	}
+	filter.Limit = limit + 1
+
+	events, err := s.auditRepo.List(ctx, filter)
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to list audit events", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+
+	page := &Page{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		last := page.Events[limit-1]
+		page.NextCursor = encodeCursor(models.AuditCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	return page, nil
+}
+
+// encodeCursor makes an opaque cursor, clients must not build their own
+func encodeCursor(cursor models.AuditCursor) string {
+	raw := strconv.FormatInt(cursor.CreatedAt.UnixMicro(), 10) + "_" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*models.AuditCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	micros, id, _ := strings.Cut(string(raw), "_")
+	createdAt, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuditCursor{CreatedAt: time.UnixMicro(createdAt), ID: parsedID}, nil
+}
@@ -0,0 +1,23 @@
+package audit
+
+import "context"
+
+// Client describes the caller of a request
+type Client struct {
+	IPAddress string
+	UserAgent string
+}
+
+type clientKey struct{}
+
+// WithClient returns a context carrying the caller details, recorded with
+// every event of the request
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext returns the caller carried by ctx, empty if none
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(clientKey{}).(Client)
+	return client
+}
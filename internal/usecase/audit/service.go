@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"renfound_v1/internal/domain/models"
+)
+
+// Service defines the interface for audit operations
+type Service interface {
+	// Record stores an event. Missing request details are taken from ctx.
+	// Failures are logged and never reported to the caller, auditing must
+	// not break the action being audited.
+	Record(ctx context.Context, event models.AuditEvent)
+
+	// Search lists events for admins, recording the search itself
+	Search(ctx context.Context, query Query) (*Page, error)
+
+	// History lists the events concerning a user, for the user themselves
+	History(ctx context.Context, userID uuid.UUID, cursor string, limit int) (*Page, error)
+}
+
+// Query selects events. Cursor is the NextCursor of the previous page.
+type Query struct {
+	ActorID  *uuid.UUID
+	TargetID *uuid.UUID
+	Action   string
+	Outcome  string
+	From     time.Time
+	To       time.Time
+	Cursor   string
+	Limit    int
+}
+
+// Page is a page of events, newest first. NextCursor is empty on the last
+// page.
+type Page struct {
+	Events     []*models.AuditEvent `json:"events"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
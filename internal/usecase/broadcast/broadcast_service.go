@@ -0,0 +1,221 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/ratelimit"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/usecase/audit"
+	"renfound_v1/internal/usecase/notification"
+	"renfound_v1/internal/utils/logctx"
+)
+
+var tracer = otel.Tracer("renfound_v1/internal/usecase/broadcast")
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type ServiceImpl struct {
+	cfg           config.BroadcastConfig
+	broadcastRepo repository.BroadcastRepository
+	notifier      notification.Service
+	audit         audit.Service
+	limiter       ratelimit.Limiter // nil paces each instance on its own
+	logger        *zap.Logger
+}
+
+func NewService(cfg *config.AppConfig, broadcastRepo repository.BroadcastRepository, notifier notification.Service, auditService audit.Service, limiter ratelimit.Limiter) Service {
+	return &ServiceImpl{
+		cfg:           cfg.Config.Broadcast,
+		broadcastRepo: broadcastRepo,
+		notifier:      notifier,
+		audit:         auditService,
+		limiter:       limiter,
+		logger:        cfg.Logger.With(zap.String("component", "broadcast_service")),
+	}
+}
+
+func (s *ServiceImpl) Create(ctx context.Context, createdBy uuid.UUID, text string, segment models.BroadcastSegment) (broadcast *models.Broadcast, err error) {
+	ctx, span := tracer.Start(ctx, "BroadcastService.Create")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.AuditActionAdminBroadcastCreate, err)
+	}()
+
+	now := time.Now()
+	broadcast = &models.Broadcast{
+		ID:        uuid.New(),
+		Text:      text,
+		Segment:   segment,
+		Status:    models.BroadcastStatusRunning,
+		CreatedBy: &createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.broadcastRepo.Create(ctx, broadcast); err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to create broadcast", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+
+	logctx.Logger(ctx, s.logger).Info("Broadcast created", zap.String("broadcast_id", broadcast.ID.String()))
+	return s.Get(ctx, broadcast.ID)
+}
+
+func (s *ServiceImpl) Get(ctx context.Context, id uuid.UUID) (*models.Broadcast, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastService.Get")
+	defer span.End()
+
+	broadcast, err := s.broadcastRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrBroadcastNotFound) {
+			return nil, models.ErrBroadcastNotFound
+		}
+		logctx.Logger(ctx, s.logger).Error("Failed to get broadcast", zap.Error(err), zap.String("broadcast_id", id.String()))
+		return nil, models.ErrInternalServer
+	}
+
+	return broadcast, nil
+}
+
+func (s *ServiceImpl) List(ctx context.Context, limit int) ([]*models.Broadcast, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastService.List")
+	defer span.End()
+
+	broadcasts, err := s.broadcastRepo.List(ctx, pageSize(limit))
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to list broadcasts", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+
+	return broadcasts, nil
+}
+
+// Recipients fetches one page. One extra recipient is requested to know
+// whether another page follows.
+func (s *ServiceImpl) Recipients(ctx context.Context, id uuid.UUID, status, cursor string, limit int) (*RecipientPage, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastService.Recipients")
+	defer span.End()
+
+	// Tell a missing broadcast from one without recipients
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	var after *uuid.UUID
+	if cursor != "" {
+		userID, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		after = &userID
+	}
+	limit = pageSize(limit)
+
+	recipients, err := s.broadcastRepo.ListRecipients(ctx, id, status, after, limit+1)
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to list broadcast recipients", zap.Error(err), zap.String("broadcast_id", id.String()))
+		return nil, models.ErrInternalServer
+	}
+
+	page := &RecipientPage{Recipients: recipients}
+	if len(recipients) > limit {
+		page.Recipients = recipients[:limit]
+		page.NextCursor = encodeCursor(page.Recipients[limit-1].UserID)
+	}
+
+	return page, nil
+}
+
+func (s *ServiceImpl) Pause(ctx context.Context, id uuid.UUID) (*models.Broadcast, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastService.Pause")
+	defer span.End()
+
+	return s.setStatus(ctx, id, models.AuditActionAdminBroadcastPause, models.BroadcastStatusPaused,
+		models.BroadcastStatusRunning)
+}
+
+func (s *ServiceImpl) Resume(ctx context.Context, id uuid.UUID) (*models.Broadcast, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastService.Resume")
+	defer span.End()
+
+	return s.setStatus(ctx, id, models.AuditActionAdminBroadcastResume, models.BroadcastStatusRunning,
+		models.BroadcastStatusPaused)
+}
+
+func (s *ServiceImpl) Cancel(ctx context.Context, id uuid.UUID) (*models.Broadcast, error) {
+	ctx, span := tracer.Start(ctx, "BroadcastService.Cancel")
+	defer span.End()
+
+	return s.setStatus(ctx, id, models.AuditActionAdminBroadcastCancel, models.BroadcastStatusCancelled,
+		models.BroadcastStatusRunning, models.BroadcastStatusPaused)
+}
+
+// setStatus moves a broadcast from one of the from statuses to status
+func (s *ServiceImpl) setStatus(ctx context.Context, id uuid.UUID, action, status string, from ...string) (broadcast *models.Broadcast, err error) {
+	defer func() {
+		s.record(ctx, action, err)
+	}()
+
+	if err := s.broadcastRepo.SetStatus(ctx, id, from, status); err != nil {
+		if errors.Is(err, models.ErrBroadcastNotFound) || errors.Is(err, models.ErrBroadcastStatusConflict) {
+			return nil, err
+		}
+		logctx.Logger(ctx, s.logger).Error("Failed to set broadcast status", zap.Error(err), zap.String("broadcast_id", id.String()))
+		return nil, models.ErrInternalServer
+	}
+
+	logctx.Logger(ctx, s.logger).Info("Broadcast status changed",
+		zap.String("broadcast_id", id.String()),
+		zap.String("status", status))
+	return s.Get(ctx, id)
+}
+
+// record audits an action of the authenticated admin
+func (s *ServiceImpl) record(ctx context.Context, action string, err error) {
+	event := models.AuditEvent{
+		Action:  action,
+		Outcome: models.AuditOutcomeSuccess,
+	}
+	if err != nil {
+		event.Outcome = models.AuditOutcomeFailure
+		event.Reason = models.LookupError(err).Code
+	}
+
+	s.audit.Record(ctx, event)
+}
+
+// pageSize applies the default and maximum page sizes
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
+
+// encodeCursor makes an opaque cursor, clients must not build their own
+func encodeCursor(userID uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(userID[:])
+}
+
+func decodeCursor(cursor string) (uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.FromBytes(raw)
+}
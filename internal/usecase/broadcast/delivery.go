@@ -0,0 +1,210 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"renfound_v1/infrastructure/ratelimit"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/utils/logctx"
+	"renfound_v1/internal/utils/strutil"
+)
+
+// maxErrorLength matches the broadcast_recipients.error column
+const maxErrorLength = 256
+
+// rateKey is the rate limit bucket shared by every instance
+const rateKey = "broadcast:delivery"
+
+// Run claims recipients batch by batch and sends to them at the configured
+// rate. Running several instances is safe, each claims its own recipients.
+// They share the rate through the limiter, without one each instance sends
+// at the full rate.
+func (s *ServiceImpl) Run(ctx context.Context) {
+	s.logger.Info("Broadcast delivery started")
+	if s.limiter == nil {
+		s.logger.Warn("Broadcast rate is not shared, run a single instance or configure redis")
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / s.cfg.RateLimit))
+	defer ticker.Stop()
+
+	for {
+		sent, err := s.deliverBatch(ctx, ticker.C)
+		if ctx.Err() != nil {
+			s.logger.Info("Broadcast delivery stopped")
+			return
+		}
+		if err != nil {
+			s.logger.Warn("Broadcast delivery interrupted", zap.Error(err))
+		}
+
+		// Wait for new work unless the batch was full of it
+		if sent == 0 || err != nil {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("Broadcast delivery stopped")
+				return
+			case <-time.After(s.cfg.PollInterval):
+			}
+		}
+	}
+}
+
+// deliverBatch sends to one batch of recipients, pacing sends on tick. It
+// returns the number of recipients handled. Recipients left when the batch
+// stops early are released for a later batch.
+func (s *ServiceImpl) deliverBatch(ctx context.Context, tick <-chan time.Time) (int, error) {
+	deliveries, err := s.broadcastRepo.ClaimRecipients(ctx, s.cfg.BatchSize, s.cfg.LeaseTimeout)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(deliveries) == 0 {
+		return 0, s.broadcastRepo.Complete(ctx)
+	}
+
+	for i, delivery := range deliveries {
+		if err := s.wait(ctx, tick); err != nil {
+			s.release(deliveries[i:])
+			return i, err
+		}
+
+		// The broadcast may have been paused or cancelled since the batch
+		// was claimed. Its recipients are released, which cancels them for
+		// a cancelled broadcast.
+		status, err := s.broadcastRepo.GetStatus(ctx, delivery.BroadcastID)
+		if err != nil {
+			s.release(deliveries[i:])
+			return i, err
+		}
+		if status != models.BroadcastStatusRunning {
+			s.release(deliveries[i : i+1])
+			continue
+		}
+
+		if err := s.deliver(ctx, delivery); err != nil {
+			s.release(deliveries[i:])
+			return i, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// wait blocks until the next message may be sent. The limiter keeps the
+// rate across instances, tick paces this instance when there is none or it
+// fails.
+func (s *ServiceImpl) wait(ctx context.Context, tick <-chan time.Time) error {
+	if s.limiter != nil {
+		limit := ratelimit.Limit{
+			Rate:   1,
+			Period: time.Duration(float64(time.Second) / s.cfg.RateLimit),
+			Burst:  1,
+		}
+
+		for {
+			result, err := s.limiter.Allow(ctx, rateKey, limit)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.logger.Error("Broadcast rate limiter failed", zap.Error(err))
+				break
+			}
+			if result.Allowed {
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(result.RetryAfter):
+			}
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-tick:
+		return nil
+	}
+}
+
+// deliver sends the broadcast to a recipient and records the outcome
+func (s *ServiceImpl) deliver(ctx context.Context, delivery *models.BroadcastDelivery) error {
+	ctx, span := tracer.Start(ctx, "BroadcastService.deliver")
+	defer span.End()
+
+	status, errorMessage := models.RecipientStatusSent, ""
+
+	if !delivery.BotReachable {
+		status = models.RecipientStatusSkipped
+	} else {
+		user := &models.User{
+			ID:           delivery.UserID,
+			TelegramID:   delivery.TelegramID,
+			BotReachable: true,
+		}
+
+		err := s.notifier.Notify(ctx, user, delivery.Text)
+		switch {
+		case err != nil && isTransient(err):
+			return fmt.Errorf("failed to reach telegram: %w", err)
+		case err != nil:
+			status, errorMessage = models.RecipientStatusFailed, strutil.Truncate(err.Error(), maxErrorLength)
+		case !user.BotReachable:
+			// The user blocked the bot since the broadcast was created
+			status = models.RecipientStatusSkipped
+		}
+	}
+
+	if err := s.broadcastRepo.SetRecipientStatus(ctx, delivery.BroadcastID, delivery.UserID, status, errorMessage); err != nil {
+		// The recipient is sent to again later. A duplicate message is
+		// preferred to losing track of it.
+		return err
+	}
+
+	if status == models.RecipientStatusFailed {
+		logctx.Logger(ctx, s.logger).Warn("Broadcast delivery failed",
+			zap.String("broadcast_id", delivery.BroadcastID.String()),
+			zap.String("user_id", delivery.UserID.String()),
+			zap.String("error", errorMessage))
+	}
+
+	return nil
+}
+
+// release hands claimed recipients back without waiting for their lease.
+// It runs on shutdown too, so it does not use the cancelled context.
+func (s *ServiceImpl) release(deliveries []*models.BroadcastDelivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, delivery := range deliveries {
+		if err := s.broadcastRepo.SetRecipientStatus(ctx, delivery.BroadcastID, delivery.UserID, models.RecipientStatusPending, ""); err != nil {
+			s.logger.Warn("Failed to release broadcast recipients, they wait for their lease", zap.Error(err))
+			return
+		}
+	}
+}
+
+// isTransient reports whether a send failed for reasons unrelated to the
+// recipient: Telegram is unreachable, failing or still rate limiting
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var apiErr *telegram.Error
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError
+}
@@ -0,0 +1,37 @@
+package broadcast
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"renfound_v1/internal/domain/models"
+)
+
+// Service defines the interface for broadcast operations
+type Service interface {
+	// Create starts a broadcast of text to the users of segment
+	Create(ctx context.Context, createdBy uuid.UUID, text string, segment models.BroadcastSegment) (*models.Broadcast, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Broadcast, error)
+	// List lists the latest broadcasts, newest first
+	List(ctx context.Context, limit int) ([]*models.Broadcast, error)
+	// Recipients lists the delivery status of a broadcast per user. Cursor
+	// is the NextCursor of the previous page.
+	Recipients(ctx context.Context, id uuid.UUID, status, cursor string, limit int) (*RecipientPage, error)
+
+	// Pause, Resume and Cancel control the delivery of a broadcast. The
+	// recipients an instance already claimed may still be sent to.
+	Pause(ctx context.Context, id uuid.UUID) (*models.Broadcast, error)
+	Resume(ctx context.Context, id uuid.UUID) (*models.Broadcast, error)
+	Cancel(ctx context.Context, id uuid.UUID) (*models.Broadcast, error)
+
+	// Run delivers running broadcasts until ctx is done. Deliveries are
+	// stored, so a restarted instance carries on where it stopped.
+	Run(ctx context.Context)
+}
+
+// RecipientPage is a page of recipients. NextCursor is empty on the last
+// page.
+type RecipientPage struct {
+	Recipients []*models.BroadcastRecipient `json:"recipients"`
+	NextCursor string                       `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,46 @@
+package login
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"strings"
+)
+
+// Fingerprint identifies a device by its user agent and network. Only the
+// first prefix bits of the address count, so a device keeps its
+// fingerprint while its address changes within the network.
+func Fingerprint(userAgent, ipAddress string, ipv4Bits, ipv6Bits int) string {
+	userAgent = strings.ToLower(strings.Join(strings.Fields(userAgent), " "))
+
+	return hashParts(userAgent, network(ipAddress, ipv4Bits, ipv6Bits))
+}
+
+// network masks the address to its network, unparsable addresses are
+// used as they are
+func network(ipAddress string, ipv4Bits, ipv6Bits int) string {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return ipAddress
+	}
+	addr = addr.Unmap()
+
+	bits := ipv6Bits
+	if addr.Is4() {
+		bits = ipv4Bits
+	}
+	prefix, err := addr.WithZone("").Prefix(bits)
+	if err != nil {
+		return addr.String()
+	}
+	return prefix.String()
+}
+
+func hashParts(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
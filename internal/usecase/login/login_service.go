@@ -0,0 +1,159 @@
+package login
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/i18n"
+	"renfound_v1/internal/utils/async"
+	"renfound_v1/internal/utils/logctx"
+	"renfound_v1/internal/utils/strutil"
+)
+
+var tracer = otel.Tracer("renfound_v1/internal/usecase/login")
+
+const (
+	defaultHistorySize = 20
+	maxHistorySize     = 100
+
+	// maxAlertDeviceLength keeps alerts readable on a phone
+	maxAlertDeviceLength = 120
+)
+
+type ServiceImpl struct {
+	cfg        config.LoginsConfig
+	loginRepo  repository.LoginRepository
+	notifier   Notifier
+	workerPool *async.WorkerPool
+	logger     *zap.Logger
+}
+
+// NewService creates the login history service. New device alerts are
+// disabled when notifier is nil.
+func NewService(cfg *config.AppConfig, loginRepo repository.LoginRepository, notifier Notifier, workerPool *async.WorkerPool) Service {
+	return &ServiceImpl{
+		cfg:        cfg.Config.Logins,
+		loginRepo:  loginRepo,
+		notifier:   notifier,
+		workerPool: workerPool,
+		logger:     cfg.Logger.With(zap.String("component", "login_service")),
+	}
+}
+
+func (s *ServiceImpl) Record(ctx context.Context, user *models.User, method, userAgent, ipAddress string) {
+	now := time.Now()
+	userAgent = strutil.Truncate(userAgent, strutil.MaxUserAgentLength)
+
+	login := &models.Login{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Method:    method,
+		DeviceID:  Fingerprint(userAgent, ipAddress, s.cfg.IPv4PrefixLength, s.cfg.IPv6PrefixLength),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		CreatedAt: now,
+	}
+	device := &models.Device{
+		UserID:      user.ID,
+		Fingerprint: login.DeviceID,
+		UserAgent:   userAgent,
+		IPAddress:   ipAddress,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+
+	// The copy keeps the task independent of later changes to the user
+	recipient := *user
+	if !s.workerPool.SubmitContext(ctx, async.LaneDefault, func(taskCtx context.Context) {
+		s.record(taskCtx, &recipient, login, device)
+	}) {
+		logctx.Logger(ctx, s.logger).Warn("Login history task rejected", zap.String("method", method))
+	}
+}
+
+func (s *ServiceImpl) record(ctx context.Context, user *models.User, login *models.Login, device *models.Device) {
+	ctx, span := tracer.Start(ctx, "LoginService.record")
+	defer span.End()
+
+	created, err := s.loginRepo.TouchDevice(ctx, device)
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to store device", zap.Error(err))
+		return
+	}
+
+	// The first device of an account is where it was created, there is
+	// nothing to alert about
+	if created {
+		count, err := s.loginRepo.CountDevices(ctx, user.ID)
+		if err != nil {
+			logctx.Logger(ctx, s.logger).Error("Failed to count devices", zap.Error(err))
+		}
+		login.NewDevice = err == nil && count > 1
+	}
+
+	if err := s.loginRepo.CreateLogin(ctx, login); err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to create login", zap.Error(err))
+	}
+
+	if login.NewDevice {
+		s.alert(ctx, user, login)
+	}
+}
+
+// alert tells the user about a login from a new device
+func (s *ServiceImpl) alert(ctx context.Context, user *models.User, login *models.Login) {
+	if s.notifier == nil || !s.cfg.NewDeviceAlerts {
+		return
+	}
+
+	locale, ok := i18n.Normalize(user.LanguageCode)
+	if !ok {
+		locale = i18n.DefaultLocale
+	}
+
+	device := strutil.Truncate(login.UserAgent, maxAlertDeviceLength)
+	if device == "" {
+		device = i18n.T(locale, "notification.unknown_device", "unknown device", nil)
+	}
+
+	text := i18n.T(locale, "notification.new_device",
+		"New sign in to your account from {device}, IP {ip}, at {time}. If this wasn't you, log out of all sessions.",
+		map[string]string{
+			"device": device,
+			"ip":     login.IPAddress,
+			"time":   login.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"),
+		})
+
+	if err := s.notifier.Notify(ctx, user, text); err != nil {
+		logctx.Logger(ctx, s.logger).Warn("Failed to send new device alert", zap.Error(err))
+		return
+	}
+
+	logctx.Logger(ctx, s.logger).Info("New device alert sent", zap.String("device_id", login.DeviceID))
+}
+
+func (s *ServiceImpl) History(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Login, error) {
+	ctx, span := tracer.Start(ctx, "LoginService.History")
+	defer span.End()
+
+	if limit <= 0 {
+		limit = defaultHistorySize
+	}
+	if limit > maxHistorySize {
+		limit = maxHistorySize
+	}
+
+	logins, err := s.loginRepo.ListLogins(ctx, userID, limit)
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to list logins", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+
+	return logins, nil
+}
@@ -0,0 +1,24 @@
+package login
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"renfound_v1/internal/domain/models"
+)
+
+// Service defines the interface for login history operations
+type Service interface {
+	// Record adds a successful sign in or refresh to the user's history in
+	// the background, alerting the user when the device is new. Failures
+	// are logged, they never fail the sign in.
+	Record(ctx context.Context, user *models.User, method, userAgent, ipAddress string)
+
+	// History lists the user's latest logins, newest first
+	History(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Login, error)
+}
+
+// Notifier sends a text message to a user through the bot
+type Notifier interface {
+	Notify(ctx context.Context, user *models.User, text string) error
+}
@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/utils/logctx"
+)
+
+type ServiceImpl struct {
+	userRepo repository.UserRepository
+	sender   Sender
+	logger   *zap.Logger
+}
+
+func NewService(cfg *config.AppConfig, userRepo repository.UserRepository, sender Sender) Service {
+	return &ServiceImpl{
+		userRepo: userRepo,
+		sender:   sender,
+		logger:   cfg.Logger.With(zap.String("component", "notification_service")),
+	}
+}
+
+func (s *ServiceImpl) Notify(ctx context.Context, user *models.User, text string) error {
+	if !user.BotReachable {
+		logctx.Logger(ctx, s.logger).Debug("Skipping notification, bot is blocked")
+		return nil
+	}
+
+	err := s.sender.Notify(ctx, user.TelegramID, text)
+	if errors.Is(err, telegram.ErrForbidden) {
+		// The user blocked the bot, stop messaging them until they are back
+		s.markUnreachable(ctx, user)
+		return nil
+	}
+	return err
+}
+
+// markUnreachable records that the bot can no longer message the user
+func (s *ServiceImpl) markUnreachable(ctx context.Context, user *models.User) {
+	user.BotReachable = false
+	if err := s.userRepo.SetBotReachable(ctx, user.TelegramID, false); err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to mark user unreachable", zap.Error(err))
+		return
+	}
+	logctx.Logger(ctx, s.logger).Info("User blocked the bot, marked unreachable")
+}
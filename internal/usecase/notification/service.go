@@ -0,0 +1,20 @@
+package notification
+
+import (
+	"context"
+
+	"renfound_v1/internal/domain/models"
+)
+
+// Service defines the interface for messaging users through the bot
+type Service interface {
+	// Notify sends a text message to the user. Users who blocked the bot
+	// are skipped without an error; user.BotReachable is cleared when the
+	// send finds out they did.
+	Notify(ctx context.Context, user *models.User, text string) error
+}
+
+// Sender delivers a text message to a Telegram chat
+type Sender interface {
+	Notify(ctx context.Context, chatID int64, text string) error
+}
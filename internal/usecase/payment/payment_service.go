@@ -0,0 +1,419 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/i18n"
+	"renfound_v1/internal/usecase/audit"
+	"renfound_v1/internal/utils/logctx"
+)
+
+var tracer = otel.Tracer("renfound_v1/internal/usecase/payment")
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+
+	// chargeAlreadyRefunded is the Bot API error of refunding a charge
+	// twice
+	chargeAlreadyRefunded = "CHARGE_ALREADY_REFUNDED"
+
+	// Updates are not delivered again once acknowledged, recording a
+	// payment is retried before giving up on it
+	recordAttempts   = 3
+	recordRetryDelay = time.Second
+)
+
+type ServiceImpl struct {
+	products     []*models.Product
+	invoiceTTL   time.Duration
+	purchaseRepo repository.PurchaseRepository
+	userRepo     repository.UserRepository
+	gateway      Gateway
+	audit        audit.Service
+	logger       *zap.Logger
+}
+
+func NewService(cfg *config.AppConfig, purchaseRepo repository.PurchaseRepository, userRepo repository.UserRepository, gateway Gateway, auditService audit.Service) Service {
+	products := make([]*models.Product, 0, len(cfg.Config.Payments.Products))
+	for _, product := range cfg.Config.Payments.Products {
+		products = append(products, &models.Product{
+			ID:              product.ID,
+			Title:           product.Title,
+			Description:     product.Description,
+			Price:           product.Price,
+			Entitlement:     product.Entitlement,
+			DurationSeconds: int64(product.Duration / time.Second),
+		})
+	}
+
+	return &ServiceImpl{
+		products:     products,
+		invoiceTTL:   cfg.Config.Payments.InvoiceTTL,
+		purchaseRepo: purchaseRepo,
+		userRepo:     userRepo,
+		gateway:      gateway,
+		audit:        auditService,
+		logger:       cfg.Logger.With(zap.String("component", "payment_service")),
+	}
+}
+
+func (s *ServiceImpl) Products() []*models.Product {
+	return s.products
+}
+
+// CreateInvoice stores the purchase before creating the invoice, so every
+// payment Telegram reports matches a purchase
+func (s *ServiceImpl) CreateInvoice(ctx context.Context, userID uuid.UUID, productID string) (*Invoice, error) {
+	ctx, span := tracer.Start(ctx, "PaymentService.CreateInvoice")
+	defer span.End()
+
+	product := s.product(productID)
+	if product == nil {
+		return nil, models.ErrProductNotFound
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil, models.ErrUserNotFound
+		}
+		logctx.Logger(ctx, s.logger).Error("Failed to get user", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+
+	purchase := &models.Purchase{
+		ID:              uuid.New(),
+		UserID:          &user.ID,
+		TelegramID:      user.TelegramID,
+		ProductID:       product.ID,
+		Entitlement:     product.Entitlement,
+		Amount:          product.Price,
+		Currency:        telegram.CurrencyStars,
+		DurationSeconds: product.DurationSeconds,
+		Status:          models.PurchaseStatusPending,
+		CreatedAt:       time.Now(),
+	}
+	if err := s.purchaseRepo.Create(ctx, purchase); err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to create purchase", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+
+	link, err := s.gateway.CreateInvoiceLink(ctx, telegram.CreateInvoiceLinkParams{
+		Title:       product.Title,
+		Description: product.Description,
+		Payload:     purchase.ID.String(),
+		Currency:    purchase.Currency,
+		Prices:      []telegram.LabeledPrice{{Label: product.Title, Amount: product.Price}},
+	})
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to create invoice link", zap.Error(err), zap.String("product_id", product.ID))
+		return nil, models.ErrInternalServer
+	}
+
+	logctx.Logger(ctx, s.logger).Info("Invoice created",
+		zap.String("purchase_id", purchase.ID.String()),
+		zap.String("product_id", product.ID))
+	return &Invoice{PurchaseID: purchase.ID, Link: link}, nil
+}
+
+func (s *ServiceImpl) PreCheckout(ctx context.Context, query *telegram.PreCheckoutQuery) error {
+	ctx, span := tracer.Start(ctx, "PaymentService.PreCheckout")
+	defer span.End()
+
+	logger := logctx.Logger(ctx, s.logger).With(zap.String("payload", query.InvoicePayload))
+
+	reason, err := s.checkOrder(ctx, query)
+	if err != nil {
+		// Not confirming is safe, the user is not charged
+		logger.Error("Failed to check order", zap.Error(err))
+		reason = "lookup failed"
+	}
+
+	if reason != "" {
+		logger.Warn("Order rejected", zap.String("reason", reason))
+
+		locale := i18n.DefaultLocale
+		if l, ok := i18n.Normalize(query.From.LanguageCode); ok {
+			locale = l
+		}
+		message := i18n.T(locale, "payments.invoice_unavailable", "This purchase is no longer available. Please start it again from the app.", nil)
+		return s.gateway.AnswerPreCheckoutQuery(ctx, query.ID, false, message)
+	}
+
+	return s.gateway.AnswerPreCheckoutQuery(ctx, query.ID, true, "")
+}
+
+// checkOrder returns why an order can't be paid, empty if it can
+func (s *ServiceImpl) checkOrder(ctx context.Context, query *telegram.PreCheckoutQuery) (string, error) {
+	id, err := uuid.Parse(query.InvoicePayload)
+	if err != nil {
+		return "unknown payload", nil
+	}
+
+	purchase, err := s.purchaseRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrPurchaseNotFound) {
+			return "unknown purchase", nil
+		}
+		return "", err
+	}
+
+	switch {
+	case purchase.Status != models.PurchaseStatusPending:
+		return "purchase " + purchase.Status, nil
+	case purchase.TelegramID != query.From.ID:
+		return "purchase of another user", nil
+	case purchase.Currency != query.Currency || purchase.Amount != query.TotalAmount:
+		return "price mismatch", nil
+	case time.Since(purchase.CreatedAt) > s.invoiceTTL:
+		return "invoice expired", nil
+	case s.product(purchase.ProductID) == nil:
+		return "product no longer sold", nil
+	}
+	return "", nil
+}
+
+func (s *ServiceImpl) CompletePayment(ctx context.Context, telegramID int64, payment *telegram.SuccessfulPayment) error {
+	ctx, span := tracer.Start(ctx, "PaymentService.CompletePayment")
+	defer span.End()
+
+	logger := logctx.Logger(ctx, s.logger).With(
+		zap.String("payload", payment.InvoicePayload),
+		zap.String("charge_id", payment.TelegramPaymentChargeID))
+
+	id, err := uuid.Parse(payment.InvoicePayload)
+	if err != nil {
+		logger.Error("Payment received for an unknown payload")
+		return s.refundCharge(ctx, telegramID, payment.TelegramPaymentChargeID)
+	}
+
+	paidAt := time.Now()
+	for attempt := 1; ; attempt++ {
+		purchase, err := s.purchaseRepo.MarkPaid(ctx, id, payment.TelegramPaymentChargeID, paidAt)
+		switch {
+		case err == nil:
+			logger.Info("Payment completed",
+				zap.String("purchase_id", purchase.ID.String()),
+				zap.String("entitlement", purchase.Entitlement))
+			return nil
+		case errors.Is(err, models.ErrPurchaseNotFound):
+			logger.Error("Payment received for an unknown purchase")
+			return s.refundCharge(ctx, telegramID, payment.TelegramPaymentChargeID)
+		case errors.Is(err, models.ErrConflict):
+			return s.completeAgain(ctx, id, telegramID, payment.TelegramPaymentChargeID)
+		case attempt == recordAttempts:
+			// The purchase stays pending, the log has what is needed to
+			// record or refund the payment by hand
+			logger.Error("Failed to record payment, giving up", zap.Error(err), zap.Int64("telegram_id", telegramID))
+			return err
+		}
+
+		logger.Warn("Failed to record payment, retrying", zap.Error(err), zap.Int("attempt", attempt))
+		select {
+		case <-ctx.Done():
+			logger.Error("Failed to record payment, giving up", zap.Error(ctx.Err()), zap.Int64("telegram_id", telegramID))
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * recordRetryDelay):
+		}
+	}
+}
+
+// completeAgain handles a payment of a purchase that is no longer
+// pending. It is either the same payment delivered again, or the invoice
+// was paid twice and the second charge goes back to the user.
+func (s *ServiceImpl) completeAgain(ctx context.Context, id uuid.UUID, telegramID int64, chargeID string) error {
+	purchase, err := s.purchaseRepo.GetByID(ctx, id)
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to get purchase", zap.Error(err), zap.String("purchase_id", id.String()))
+		return err
+	}
+
+	if purchase.ChargeID == chargeID {
+		logctx.Logger(ctx, s.logger).Debug("Payment already recorded", zap.String("purchase_id", id.String()))
+		return nil
+	}
+
+	logctx.Logger(ctx, s.logger).Warn("Purchase paid twice",
+		zap.String("purchase_id", id.String()),
+		zap.String("charge_id", chargeID))
+	return s.refundCharge(ctx, telegramID, chargeID)
+}
+
+// refundCharge returns the Stars of a payment without a purchase to record
+// it on
+func (s *ServiceImpl) refundCharge(ctx context.Context, telegramID int64, chargeID string) error {
+	if err := s.gateway.RefundStarPayment(ctx, telegramID, chargeID); err != nil && !alreadyRefunded(err) {
+		logctx.Logger(ctx, s.logger).Error("Failed to refund stray payment", zap.Error(err), zap.String("charge_id", chargeID))
+		return err
+	}
+
+	logctx.Logger(ctx, s.logger).Info("Stray payment refunded", zap.String("charge_id", chargeID))
+	return nil
+}
+
+// Refund calls Telegram before recording the refund. When recording it
+// fails, refunding again finds the charge refunded and records it.
+func (s *ServiceImpl) Refund(ctx context.Context, purchaseID uuid.UUID) (purchase *models.Purchase, err error) {
+	ctx, span := tracer.Start(ctx, "PaymentService.Refund")
+	defer span.End()
+
+	var targetID *uuid.UUID
+	defer func() {
+		event := models.AuditEvent{
+			Action:   models.AuditActionAdminPurchaseRefund,
+			TargetID: targetID,
+			Outcome:  models.AuditOutcomeSuccess,
+		}
+		if err != nil {
+			event.Outcome = models.AuditOutcomeFailure
+			event.Reason = models.LookupError(err).Code
+		}
+		s.audit.Record(ctx, event)
+	}()
+
+	logger := logctx.Logger(ctx, s.logger).With(zap.String("purchase_id", purchaseID.String()))
+
+	purchase, err = s.purchaseRepo.GetByID(ctx, purchaseID)
+	if err != nil {
+		if errors.Is(err, models.ErrPurchaseNotFound) {
+			return nil, models.ErrPurchaseNotFound
+		}
+		logger.Error("Failed to get purchase", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+	targetID = purchase.UserID
+
+	if purchase.Status != models.PurchaseStatusPaid {
+		return nil, models.ErrPurchaseNotRefundable
+	}
+
+	if err := s.gateway.RefundStarPayment(ctx, purchase.TelegramID, purchase.ChargeID); err != nil {
+		if !alreadyRefunded(err) {
+			logger.Error("Failed to refund payment", zap.Error(err))
+			return nil, models.ErrInternalServer
+		}
+		logger.Warn("Payment was already refunded")
+	}
+
+	purchase, err = s.purchaseRepo.MarkRefunded(ctx, purchaseID, time.Now())
+	if err != nil {
+		if errors.Is(err, models.ErrPurchaseNotRefundable) {
+			// Recorded meanwhile from Telegram's refund message
+			return s.purchaseRepo.GetByID(ctx, purchaseID)
+		}
+		logger.Error("Failed to record refund", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+
+	logger.Info("Purchase refunded", zap.String("entitlement", purchase.Entitlement))
+	return purchase, nil
+}
+
+func (s *ServiceImpl) RecordRefund(ctx context.Context, refund *telegram.RefundedPayment) error {
+	ctx, span := tracer.Start(ctx, "PaymentService.RecordRefund")
+	defer span.End()
+
+	logger := logctx.Logger(ctx, s.logger).With(zap.String("charge_id", refund.TelegramPaymentChargeID))
+
+	purchase, err := s.purchaseRepo.GetByChargeID(ctx, refund.TelegramPaymentChargeID)
+	if err != nil {
+		if errors.Is(err, models.ErrPurchaseNotFound) {
+			// A stray payment refunded by CompletePayment
+			logger.Info("Refund of an unknown charge")
+			return nil
+		}
+		logger.Error("Failed to get purchase", zap.Error(err))
+		return err
+	}
+
+	if _, err := s.purchaseRepo.MarkRefunded(ctx, purchase.ID, time.Now()); err != nil {
+		if errors.Is(err, models.ErrPurchaseNotRefundable) {
+			return nil
+		}
+		logger.Error("Failed to record refund", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Refund recorded", zap.String("purchase_id", purchase.ID.String()))
+	return nil
+}
+
+func (s *ServiceImpl) Purchases(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Purchase, error) {
+	ctx, span := tracer.Start(ctx, "PaymentService.Purchases")
+	defer span.End()
+
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	purchases, err := s.purchaseRepo.ListByUser(ctx, userID, limit)
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to list purchases", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+
+	return purchases, nil
+}
+
+func (s *ServiceImpl) Entitlements(ctx context.Context, userID uuid.UUID) ([]*models.Entitlement, error) {
+	ctx, span := tracer.Start(ctx, "PaymentService.Entitlements")
+	defer span.End()
+
+	entitlements, err := s.purchaseRepo.ListEntitlements(ctx, userID, time.Now())
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to list entitlements", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+	if entitlements == nil {
+		entitlements = []*models.Entitlement{}
+	}
+
+	return entitlements, nil
+}
+
+func (s *ServiceImpl) HasEntitlement(ctx context.Context, userID uuid.UUID, name string) (bool, error) {
+	entitlements, err := s.Entitlements(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entitlement := range entitlements {
+		if entitlement.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// product returns the product for sale with the ID, nil if there is none
+func (s *ServiceImpl) product(id string) *models.Product {
+	for _, product := range s.products {
+		if product.ID == id {
+			return product
+		}
+	}
+	return nil
+}
+
+// alreadyRefunded reports whether a refund failed because the charge was
+// refunded before
+func alreadyRefunded(err error) bool {
+	var apiErr *telegram.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest &&
+		strings.Contains(apiErr.Description, chargeAlreadyRefunded)
+}
@@ -0,0 +1,665 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/infrastructure/telegram/telegramtest"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/usecase/audit"
+)
+
+const (
+	testToken      = "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsawq"
+	testTelegramID = 4242
+	month          = 30 * 24 * time.Hour
+)
+
+var testProducts = []config.ProductConfig{
+	{ID: "premium_month", Title: "Premium", Description: "A month of premium", Price: 100, Entitlement: "premium", Duration: month},
+	{ID: "themes", Title: "Themes", Description: "All themes, forever", Price: 250, Entitlement: "themes"},
+}
+
+type fixture struct {
+	service *ServiceImpl
+	srv     *telegramtest.Server
+	repo    *fakePurchaseRepository
+	audit   *fakeAudit
+	user    *models.User
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	srv := telegramtest.NewServer(testToken)
+	t.Cleanup(srv.Close)
+
+	cfg := &config.AppConfig{
+		Logger: zap.NewNop(),
+		Config: &config.Config{
+			Telegram: config.TelegramConfig{
+				BotToken:       testToken,
+				APIURL:         srv.URL,
+				Timeout:        5 * time.Second,
+				RateLimit:      1000,
+				ChatRateLimit:  1000,
+				GroupRateLimit: 1000,
+			},
+			Payments: config.PaymentsConfig{
+				InvoiceTTL: time.Hour,
+				Products:   testProducts,
+			},
+		},
+	}
+
+	user := &models.User{ID: uuid.New(), TelegramID: testTelegramID}
+	repo := newFakePurchaseRepository()
+	auditService := &fakeAudit{}
+	service := NewService(cfg, repo, &fakeUserRepository{user: user}, telegram.NewClient(cfg), auditService)
+
+	return &fixture{
+		service: service.(*ServiceImpl),
+		srv:     srv,
+		repo:    repo,
+		audit:   auditService,
+		user:    user,
+	}
+}
+
+// buy creates an invoice of the product and returns it as the fake Bot
+// API created it
+func (f *fixture) buy(t *testing.T, productID string) (*Invoice, telegramtest.Invoice) {
+	t.Helper()
+
+	invoice, err := f.service.CreateInvoice(context.Background(), f.user.ID, productID)
+	if err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+	invoices := f.srv.Invoices()
+	return invoice, invoices[len(invoices)-1]
+}
+
+// pay pays the invoice and records the payment
+func (f *fixture) pay(t *testing.T, invoice telegramtest.Invoice) *telegram.SuccessfulPayment {
+	t.Helper()
+
+	payment := f.srv.Pay(testTelegramID, invoice).Message.SuccessfulPayment
+	if err := f.service.CompletePayment(context.Background(), testTelegramID, payment); err != nil {
+		t.Fatalf("CompletePayment: %v", err)
+	}
+	return payment
+}
+
+// preCheckout answers a pre-checkout query and returns the answer sent to
+// the fake Bot API
+func (f *fixture) preCheckout(t *testing.T, query *telegram.PreCheckoutQuery) telegramtest.Request {
+	t.Helper()
+
+	if err := f.service.PreCheckout(context.Background(), query); err != nil {
+		t.Fatalf("PreCheckout: %v", err)
+	}
+	answers := f.srv.Requests("answerPreCheckoutQuery")
+	if len(answers) == 0 {
+		t.Fatal("pre-checkout query was not answered")
+	}
+	return answers[len(answers)-1]
+}
+
+func TestCreateInvoice(t *testing.T) {
+	f := newFixture(t)
+
+	invoice, sent := f.buy(t, "premium_month")
+
+	if invoice.Link != sent.Link {
+		t.Errorf("link = %q, want %q", invoice.Link, sent.Link)
+	}
+	if sent.Payload != invoice.PurchaseID.String() {
+		t.Errorf("payload = %q, want the purchase id %s", sent.Payload, invoice.PurchaseID)
+	}
+	if sent.Currency != telegram.CurrencyStars || sent.Amount != 100 || sent.Title != "Premium" {
+		t.Errorf("unexpected invoice %+v", sent)
+	}
+
+	purchase := f.repo.get(invoice.PurchaseID)
+	if purchase == nil {
+		t.Fatal("purchase was not stored")
+	}
+	if purchase.Status != models.PurchaseStatusPending || purchase.TelegramID != testTelegramID ||
+		purchase.Entitlement != "premium" || purchase.DurationSeconds != int64(month/time.Second) {
+		t.Errorf("unexpected purchase %+v", purchase)
+	}
+}
+
+func TestCreateInvoiceUnknownProduct(t *testing.T) {
+	f := newFixture(t)
+
+	_, err := f.service.CreateInvoice(context.Background(), f.user.ID, "gold")
+	if !errors.Is(err, models.ErrProductNotFound) {
+		t.Fatalf("err = %v, want ErrProductNotFound", err)
+	}
+	if len(f.srv.Invoices()) != 0 {
+		t.Error("invoice was created")
+	}
+}
+
+func TestPreCheckoutAccepts(t *testing.T) {
+	f := newFixture(t)
+	_, sent := f.buy(t, "premium_month")
+
+	answer := f.preCheckout(t, f.srv.PreCheckout(testTelegramID, sent).PreCheckoutQuery)
+
+	if answer.Params["ok"] != true {
+		t.Errorf("order rejected: %v", answer.Params)
+	}
+}
+
+func TestPreCheckoutRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(f *fixture, purchaseID uuid.UUID, query *telegram.PreCheckoutQuery)
+	}{
+		{"other user", func(f *fixture, _ uuid.UUID, query *telegram.PreCheckoutQuery) {
+			query.From.ID = 777
+		}},
+		{"price mismatch", func(f *fixture, _ uuid.UUID, query *telegram.PreCheckoutQuery) {
+			query.TotalAmount = 1
+		}},
+		{"currency mismatch", func(f *fixture, _ uuid.UUID, query *telegram.PreCheckoutQuery) {
+			query.Currency = "USD"
+		}},
+		{"expired", func(f *fixture, purchaseID uuid.UUID, _ *telegram.PreCheckoutQuery) {
+			f.repo.update(purchaseID, func(p *models.Purchase) {
+				p.CreatedAt = time.Now().Add(-2 * time.Hour)
+			})
+		}},
+		{"not pending", func(f *fixture, purchaseID uuid.UUID, _ *telegram.PreCheckoutQuery) {
+			f.repo.update(purchaseID, func(p *models.Purchase) {
+				p.Status = models.PurchaseStatusPaid
+			})
+		}},
+		{"unknown payload", func(f *fixture, _ uuid.UUID, query *telegram.PreCheckoutQuery) {
+			query.InvoicePayload = "not-a-purchase"
+		}},
+		{"unknown purchase", func(f *fixture, _ uuid.UUID, query *telegram.PreCheckoutQuery) {
+			query.InvoicePayload = uuid.NewString()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			invoice, sent := f.buy(t, "premium_month")
+
+			query := f.srv.PreCheckout(testTelegramID, sent).PreCheckoutQuery
+			tt.modify(f, invoice.PurchaseID, query)
+			answer := f.preCheckout(t, query)
+
+			if answer.Params["ok"] != false {
+				t.Errorf("order accepted: %v", answer.Params)
+			}
+			if answer.Params["error_message"] == "" || answer.Params["error_message"] == nil {
+				t.Error("rejection has no error message")
+			}
+		})
+	}
+}
+
+func TestCompletePayment(t *testing.T) {
+	f := newFixture(t)
+	invoice, sent := f.buy(t, "premium_month")
+
+	payment := f.pay(t, sent)
+
+	purchase := f.repo.get(invoice.PurchaseID)
+	if purchase.Status != models.PurchaseStatusPaid || purchase.ChargeID != payment.TelegramPaymentChargeID {
+		t.Errorf("unexpected purchase %+v", purchase)
+	}
+	if purchase.ExpiresAt == nil || purchase.ExpiresAt.Sub(*purchase.PaidAt) != month {
+		t.Errorf("expires at %v, want a month after %v", purchase.ExpiresAt, purchase.PaidAt)
+	}
+	if n := len(f.srv.Requests("refundStarPayment")); n != 0 {
+		t.Errorf("got %d refunds, want none", n)
+	}
+}
+
+func TestCompletePaymentRedelivered(t *testing.T) {
+	f := newFixture(t)
+	invoice, sent := f.buy(t, "premium_month")
+	payment := f.pay(t, sent)
+	paid := *f.repo.get(invoice.PurchaseID)
+
+	if err := f.service.CompletePayment(context.Background(), testTelegramID, payment); err != nil {
+		t.Fatalf("CompletePayment again: %v", err)
+	}
+
+	if n := len(f.srv.Requests("refundStarPayment")); n != 0 {
+		t.Errorf("got %d refunds of the same charge, want none", n)
+	}
+	if purchase := f.repo.get(invoice.PurchaseID); !purchase.ExpiresAt.Equal(*paid.ExpiresAt) {
+		t.Errorf("entitlement extended again, expires at %v instead of %v", purchase.ExpiresAt, paid.ExpiresAt)
+	}
+}
+
+func TestCompletePaymentPaidTwice(t *testing.T) {
+	f := newFixture(t)
+	invoice, sent := f.buy(t, "premium_month")
+	first := f.pay(t, sent)
+
+	second := f.pay(t, sent)
+
+	refunds := f.srv.Requests("refundStarPayment")
+	if len(refunds) != 1 {
+		t.Fatalf("got %d refunds, want 1", len(refunds))
+	}
+	if id := refunds[0].Params["telegram_payment_charge_id"]; id != second.TelegramPaymentChargeID {
+		t.Errorf("refunded charge %v, want the second one %s", id, second.TelegramPaymentChargeID)
+	}
+	if purchase := f.repo.get(invoice.PurchaseID); purchase.ChargeID != first.TelegramPaymentChargeID {
+		t.Errorf("purchase charge = %s, want the first one %s", purchase.ChargeID, first.TelegramPaymentChargeID)
+	}
+}
+
+func TestCompletePaymentUnknownPayload(t *testing.T) {
+	for _, payload := range []string{"not-a-purchase", uuid.NewString()} {
+		t.Run(payload, func(t *testing.T) {
+			f := newFixture(t)
+
+			payment := f.srv.Pay(testTelegramID, telegramtest.Invoice{
+				Payload:  payload,
+				Currency: telegram.CurrencyStars,
+				Amount:   100,
+			}).Message.SuccessfulPayment
+			if err := f.service.CompletePayment(context.Background(), testTelegramID, payment); err != nil {
+				t.Fatalf("CompletePayment: %v", err)
+			}
+
+			refunds := f.srv.Requests("refundStarPayment")
+			if len(refunds) != 1 {
+				t.Fatalf("got %d refunds, want 1", len(refunds))
+			}
+			if id := refunds[0].Params["telegram_payment_charge_id"]; id != payment.TelegramPaymentChargeID {
+				t.Errorf("refunded charge %v, want %s", id, payment.TelegramPaymentChargeID)
+			}
+		})
+	}
+}
+
+func TestRefund(t *testing.T) {
+	f := newFixture(t)
+	invoice, sent := f.buy(t, "themes")
+	f.pay(t, sent)
+
+	purchase, err := f.service.Refund(context.Background(), invoice.PurchaseID)
+	if err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if purchase.Status != models.PurchaseStatusRefunded || purchase.RefundedAt == nil {
+		t.Errorf("unexpected purchase %+v", purchase)
+	}
+	if ok, _ := f.service.HasEntitlement(context.Background(), f.user.ID, "themes"); ok {
+		t.Error("entitlement kept after the refund")
+	}
+
+	event := f.audit.last()
+	if event.Action != models.AuditActionAdminPurchaseRefund || event.Outcome != models.AuditOutcomeSuccess {
+		t.Errorf("unexpected audit event %+v", event)
+	}
+
+	if _, err := f.service.Refund(context.Background(), invoice.PurchaseID); !errors.Is(err, models.ErrPurchaseNotRefundable) {
+		t.Errorf("refunding again: err = %v, want ErrPurchaseNotRefundable", err)
+	}
+}
+
+func TestRefundAlreadyRefunded(t *testing.T) {
+	f := newFixture(t)
+	invoice, sent := f.buy(t, "themes")
+	payment := f.pay(t, sent)
+
+	// Refunded on Telegram's side, the refund was not recorded
+	if err := f.service.gateway.RefundStarPayment(context.Background(), testTelegramID, payment.TelegramPaymentChargeID); err != nil {
+		t.Fatalf("RefundStarPayment: %v", err)
+	}
+
+	purchase, err := f.service.Refund(context.Background(), invoice.PurchaseID)
+	if err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if purchase.Status != models.PurchaseStatusRefunded {
+		t.Errorf("status = %s, want refunded", purchase.Status)
+	}
+}
+
+func TestRefundFailure(t *testing.T) {
+	f := newFixture(t)
+	invoice, sent := f.buy(t, "themes")
+	f.pay(t, sent)
+	f.srv.Fail("refundStarPayment", telegramtest.Response{Code: 400, Description: "Bad Request: CHARGE_NOT_FOUND"})
+
+	if _, err := f.service.Refund(context.Background(), invoice.PurchaseID); !errors.Is(err, models.ErrInternalServer) {
+		t.Fatalf("err = %v, want ErrInternalServer", err)
+	}
+	if purchase := f.repo.get(invoice.PurchaseID); purchase.Status != models.PurchaseStatusPaid {
+		t.Errorf("status = %s, want paid", purchase.Status)
+	}
+	if event := f.audit.last(); event.Outcome != models.AuditOutcomeFailure {
+		t.Errorf("unexpected audit event %+v", event)
+	}
+}
+
+func TestRecordRefund(t *testing.T) {
+	f := newFixture(t)
+	invoice, sent := f.buy(t, "themes")
+	payment := f.pay(t, sent)
+
+	refund := &telegram.RefundedPayment{
+		Currency:                payment.Currency,
+		TotalAmount:             payment.TotalAmount,
+		InvoicePayload:          payment.InvoicePayload,
+		TelegramPaymentChargeID: payment.TelegramPaymentChargeID,
+	}
+	if err := f.service.RecordRefund(context.Background(), refund); err != nil {
+		t.Fatalf("RecordRefund: %v", err)
+	}
+	if purchase := f.repo.get(invoice.PurchaseID); purchase.Status != models.PurchaseStatusRefunded {
+		t.Errorf("status = %s, want refunded", purchase.Status)
+	}
+
+	// Recording it again is a no-op
+	if err := f.service.RecordRefund(context.Background(), refund); err != nil {
+		t.Errorf("RecordRefund again: %v", err)
+	}
+
+	// A stray payment refunded by CompletePayment
+	refund.TelegramPaymentChargeID = "unknown-charge"
+	if err := f.service.RecordRefund(context.Background(), refund); err != nil {
+		t.Errorf("RecordRefund of an unknown charge: %v", err)
+	}
+}
+
+func TestHasEntitlementStacks(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	if ok, err := f.service.HasEntitlement(ctx, f.user.ID, "premium"); err != nil || ok {
+		t.Fatalf("HasEntitlement before buying = %v, %v", ok, err)
+	}
+
+	_, first := f.buy(t, "premium_month")
+	f.pay(t, first)
+	_, second := f.buy(t, "premium_month")
+	f.pay(t, second)
+
+	entitlements, err := f.service.Entitlements(ctx, f.user.ID)
+	if err != nil {
+		t.Fatalf("Entitlements: %v", err)
+	}
+	if len(entitlements) != 1 || entitlements[0].Name != "premium" {
+		t.Fatalf("unexpected entitlements %+v", entitlements)
+	}
+	if until := time.Until(*entitlements[0].ExpiresAt); until < 2*month-time.Minute || until > 2*month {
+		t.Errorf("premium expires in %v, want two months", until)
+	}
+
+	if ok, err := f.service.HasEntitlement(ctx, f.user.ID, "premium"); err != nil || !ok {
+		t.Errorf("HasEntitlement = %v, %v, want true", ok, err)
+	}
+	if ok, _ := f.service.HasEntitlement(ctx, f.user.ID, "themes"); ok {
+		t.Error("entitled to a feature not bought")
+	}
+}
+
+func TestHasEntitlementExpires(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	invoice, sent := f.buy(t, "premium_month")
+	f.pay(t, sent)
+	f.repo.update(invoice.PurchaseID, func(p *models.Purchase) {
+		expired := time.Now().Add(-time.Minute)
+		p.ExpiresAt = &expired
+	})
+
+	if ok, err := f.service.HasEntitlement(ctx, f.user.ID, "premium"); err != nil || ok {
+		t.Errorf("HasEntitlement after expiry = %v, %v, want false", ok, err)
+	}
+
+	// Buying again after expiry starts from now
+	_, sent = f.buy(t, "premium_month")
+	f.pay(t, sent)
+
+	entitlements, _ := f.service.Entitlements(ctx, f.user.ID)
+	if len(entitlements) != 1 {
+		t.Fatalf("unexpected entitlements %+v", entitlements)
+	}
+	if until := time.Until(*entitlements[0].ExpiresAt); until < month-time.Minute || until > month {
+		t.Errorf("premium expires in %v, want a month", until)
+	}
+}
+
+func TestHasEntitlementForever(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	_, sent := f.buy(t, "themes")
+	f.pay(t, sent)
+
+	entitlements, _ := f.service.Entitlements(ctx, f.user.ID)
+	if len(entitlements) != 1 || entitlements[0].Name != "themes" || entitlements[0].ExpiresAt != nil {
+		t.Fatalf("unexpected entitlements %+v", entitlements)
+	}
+	if ok, err := f.service.HasEntitlement(ctx, f.user.ID, "themes"); err != nil || !ok {
+		t.Errorf("HasEntitlement = %v, %v, want true", ok, err)
+	}
+}
+
+// fakePurchaseRepository is an in-memory purchases ledger following the
+// postgres implementation
+type fakePurchaseRepository struct {
+	mu        sync.Mutex
+	purchases map[uuid.UUID]*models.Purchase
+}
+
+var _ repository.PurchaseRepository = (*fakePurchaseRepository)(nil)
+
+func newFakePurchaseRepository() *fakePurchaseRepository {
+	return &fakePurchaseRepository{purchases: make(map[uuid.UUID]*models.Purchase)}
+}
+
+// get returns a copy of the purchase, nil if there is none
+func (r *fakePurchaseRepository) get(id uuid.UUID) *models.Purchase {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purchase, ok := r.purchases[id]
+	if !ok {
+		return nil
+	}
+	p := *purchase
+	return &p
+}
+
+// update changes a stored purchase
+func (r *fakePurchaseRepository) update(id uuid.UUID, change func(p *models.Purchase)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change(r.purchases[id])
+}
+
+func (r *fakePurchaseRepository) Create(ctx context.Context, purchase *models.Purchase) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := *purchase
+	r.purchases[p.ID] = &p
+	return nil
+}
+
+func (r *fakePurchaseRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Purchase, error) {
+	if purchase := r.get(id); purchase != nil {
+		return purchase, nil
+	}
+	return nil, models.ErrPurchaseNotFound
+}
+
+func (r *fakePurchaseRepository) GetByChargeID(ctx context.Context, chargeID string) (*models.Purchase, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, purchase := range r.purchases {
+		if purchase.ChargeID == chargeID {
+			p := *purchase
+			return &p, nil
+		}
+	}
+	return nil, models.ErrPurchaseNotFound
+}
+
+func (r *fakePurchaseRepository) MarkPaid(ctx context.Context, id uuid.UUID, chargeID string, paidAt time.Time) (*models.Purchase, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purchase, ok := r.purchases[id]
+	if !ok {
+		return nil, models.ErrPurchaseNotFound
+	}
+	if purchase.Status != models.PurchaseStatusPending {
+		return nil, models.ErrConflict
+	}
+
+	purchase.Status = models.PurchaseStatusPaid
+	purchase.ChargeID = chargeID
+	purchase.PaidAt = &paidAt
+	if purchase.DurationSeconds > 0 {
+		start := paidAt
+		for _, other := range r.purchases {
+			if other.TelegramID == purchase.TelegramID && other.Entitlement == purchase.Entitlement &&
+				other.Status == models.PurchaseStatusPaid && other.ExpiresAt != nil && other.ExpiresAt.After(start) {
+				start = *other.ExpiresAt
+			}
+		}
+		expiresAt := start.Add(time.Duration(purchase.DurationSeconds) * time.Second)
+		purchase.ExpiresAt = &expiresAt
+	}
+
+	p := *purchase
+	return &p, nil
+}
+
+func (r *fakePurchaseRepository) MarkRefunded(ctx context.Context, id uuid.UUID, refundedAt time.Time) (*models.Purchase, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purchase, ok := r.purchases[id]
+	if !ok {
+		return nil, models.ErrPurchaseNotFound
+	}
+	if purchase.Status != models.PurchaseStatusPaid {
+		return nil, models.ErrPurchaseNotRefundable
+	}
+
+	purchase.Status = models.PurchaseStatusRefunded
+	purchase.RefundedAt = &refundedAt
+
+	p := *purchase
+	return &p, nil
+}
+
+func (r *fakePurchaseRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Purchase, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purchases []*models.Purchase
+	for _, purchase := range r.purchases {
+		if purchase.UserID != nil && *purchase.UserID == userID && purchase.Status != models.PurchaseStatusPending {
+			p := *purchase
+			purchases = append(purchases, &p)
+		}
+	}
+	sort.Slice(purchases, func(i, j int) bool {
+		return purchases[i].CreatedAt.After(purchases[j].CreatedAt)
+	})
+	if len(purchases) > limit {
+		purchases = purchases[:limit]
+	}
+	return purchases, nil
+}
+
+func (r *fakePurchaseRepository) ListEntitlements(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Entitlement, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byName := make(map[string]*models.Entitlement)
+	for _, purchase := range r.purchases {
+		if purchase.UserID == nil || *purchase.UserID != userID || purchase.Status != models.PurchaseStatusPaid ||
+			(purchase.ExpiresAt != nil && !purchase.ExpiresAt.After(now)) {
+			continue
+		}
+
+		entitlement, ok := byName[purchase.Entitlement]
+		switch {
+		case !ok:
+			entitlement = &models.Entitlement{Name: purchase.Entitlement, ExpiresAt: purchase.ExpiresAt}
+			byName[purchase.Entitlement] = entitlement
+		case purchase.ExpiresAt == nil:
+			entitlement.ExpiresAt = nil
+		case entitlement.ExpiresAt != nil && purchase.ExpiresAt.After(*entitlement.ExpiresAt):
+			entitlement.ExpiresAt = purchase.ExpiresAt
+		}
+	}
+
+	var entitlements []*models.Entitlement
+	for _, entitlement := range byName {
+		entitlements = append(entitlements, entitlement)
+	}
+	sort.Slice(entitlements, func(i, j int) bool {
+		return entitlements[i].Name < entitlements[j].Name
+	})
+	return entitlements, nil
+}
+
+type fakeUserRepository struct {
+	repository.UserRepository
+	user *models.User
+}
+
+func (r *fakeUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	if id != r.user.ID {
+		return nil, models.ErrUserNotFound
+	}
+	return r.user, nil
+}
+
+type fakeAudit struct {
+	audit.Service
+
+	mu     sync.Mutex
+	events []models.AuditEvent
+}
+
+func (a *fakeAudit) Record(ctx context.Context, event models.AuditEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.events = append(a.events, event)
+}
+
+// last returns the last event recorded
+func (a *fakeAudit) last() models.AuditEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.events) == 0 {
+		return models.AuditEvent{}
+	}
+	return a.events[len(a.events)-1]
+}
@@ -0,0 +1,53 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"renfound_v1/infrastructure/telegram"
+	"renfound_v1/internal/domain/models"
+)
+
+// Service sells premium features for Telegram Stars and tells which
+// features users are entitled to
+type Service interface {
+	// Products lists the products for sale
+	Products() []*models.Product
+	// CreateInvoice creates the invoice of a product for the user, opened
+	// by the Mini App with Telegram.WebApp.openInvoice
+	CreateInvoice(ctx context.Context, userID uuid.UUID, productID string) (*Invoice, error)
+
+	// PreCheckout answers a pre-checkout query, confirming the order if
+	// the invoice can still be paid by the user
+	PreCheckout(ctx context.Context, query *telegram.PreCheckoutQuery) error
+	// CompletePayment records a successful payment of the Telegram user.
+	// Recording a payment again is a no-op.
+	CompletePayment(ctx context.Context, telegramID int64, payment *telegram.SuccessfulPayment) error
+
+	// Refund returns the Stars of a paid purchase and revokes its
+	// entitlement
+	Refund(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error)
+	// RecordRefund records a refund Telegram reports. Recording a refund
+	// again is a no-op.
+	RecordRefund(ctx context.Context, refund *telegram.RefundedPayment) error
+
+	// Purchases lists the paid and refunded purchases of the user
+	Purchases(ctx context.Context, userID uuid.UUID, limit int) ([]*models.Purchase, error)
+	// Entitlements lists the features the user may use now
+	Entitlements(ctx context.Context, userID uuid.UUID) ([]*models.Entitlement, error)
+	// HasEntitlement reports whether the user may use a feature now
+	HasEntitlement(ctx context.Context, userID uuid.UUID, name string) (bool, error)
+}
+
+// Gateway is the part of the Bot API payments go through
+type Gateway interface {
+	CreateInvoiceLink(ctx context.Context, params telegram.CreateInvoiceLinkParams) (string, error)
+	AnswerPreCheckoutQuery(ctx context.Context, queryID string, ok bool, errorMessage string) error
+	RefundStarPayment(ctx context.Context, userID int64, chargeID string) error
+}
+
+// Invoice is an invoice link for a pending purchase
+type Invoice struct {
+	PurchaseID uuid.UUID `json:"purchase_id"`
+	Link       string    `json:"link"`
+}
@@ -0,0 +1,191 @@
+package referral
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"renfound_v1/config"
+	"renfound_v1/internal/domain/models"
+	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/utils/logctx"
+)
+
+var tracer = otel.Tracer("renfound_v1/internal/usecase/referral")
+
+const (
+	// codeAlphabet leaves out characters that are easily mistaken for
+	// each other when a code is read out
+	codeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	codeLength   = 8
+	// codeAttempts bounds the retries after generating a taken code
+	codeAttempts = 3
+
+	defaultCampaignLimit = 50
+	maxCampaignLimit     = 200
+)
+
+// namePattern matches the codes and campaign names accepted in a start
+// parameter, within what Telegram allows in startapp
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,60}$`)
+
+type ServiceImpl struct {
+	referralRepo repository.ReferralRepository
+	miniAppLink  string
+	logger       *zap.Logger
+}
+
+func NewService(cfg *config.AppConfig, referralRepo repository.ReferralRepository) Service {
+	return &ServiceImpl{
+		referralRepo: referralRepo,
+		miniAppLink:  cfg.Config.Telegram.MiniAppLink,
+		logger:       cfg.Logger.With(zap.String("component", "referral_service")),
+	}
+}
+
+func (s *ServiceImpl) Attribute(ctx context.Context, user *models.User, startParam string) {
+	ctx, span := tracer.Start(ctx, "ReferralService.Attribute")
+	defer span.End()
+
+	logger := logctx.Logger(ctx, s.logger).With(zap.String("start_param", startParam))
+	referral := &models.Referral{
+		TelegramID: user.TelegramID,
+		UserID:     user.ID,
+		CreatedAt:  time.Now(),
+	}
+
+	switch {
+	case strings.HasPrefix(startParam, ReferrerPrefix):
+		code := strings.TrimPrefix(startParam, ReferrerPrefix)
+		if !namePattern.MatchString(code) {
+			logger.Debug("Ignoring malformed referral code")
+			return
+		}
+
+		referrerID, err := s.referralRepo.GetUserIDByCode(ctx, code)
+		if err != nil {
+			if errors.Is(err, models.ErrUserNotFound) {
+				logger.Info("Ignoring unknown referral code")
+				return
+			}
+			logger.Error("Failed to resolve referral code", zap.Error(err))
+			return
+		}
+
+		if referrerID == user.ID {
+			logger.Warn("Ignoring self-referral")
+			return
+		}
+		referral.ReferrerID = &referrerID
+
+	case strings.HasPrefix(startParam, CampaignPrefix):
+		campaign := strings.TrimPrefix(startParam, CampaignPrefix)
+		if !namePattern.MatchString(campaign) {
+			logger.Debug("Ignoring malformed campaign name")
+			return
+		}
+		referral.Campaign = campaign
+
+	default:
+		return
+	}
+
+	created, err := s.referralRepo.Create(ctx, referral)
+	if err != nil {
+		logger.Error("Failed to attribute signup", zap.Error(err))
+		return
+	}
+	if !created {
+		logger.Info("Signup already attributed")
+		return
+	}
+
+	logger.Info("Signup attributed", zap.String("campaign", referral.Campaign))
+}
+
+func (s *ServiceImpl) Stats(ctx context.Context, userID uuid.UUID) (*models.ReferralStats, error) {
+	ctx, span := tracer.Start(ctx, "ReferralService.Stats")
+	defer span.End()
+
+	code, err := s.ensureCode(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, last, err := s.referralRepo.CountByReferrer(ctx, userID)
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to count referrals", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+
+	stats := &models.ReferralStats{
+		Code:           code,
+		Referred:       count,
+		LastReferredAt: last,
+	}
+	if s.miniAppLink != "" {
+		stats.Link = s.miniAppLink + "?startapp=" + ReferrerPrefix + code
+	}
+
+	return stats, nil
+}
+
+func (s *ServiceImpl) CampaignStats(ctx context.Context, limit int) ([]*models.CampaignStats, error) {
+	ctx, span := tracer.Start(ctx, "ReferralService.CampaignStats")
+	defer span.End()
+
+	if limit <= 0 {
+		limit = defaultCampaignLimit
+	}
+	if limit > maxCampaignLimit {
+		limit = maxCampaignLimit
+	}
+
+	stats, err := s.referralRepo.ListCampaignStats(ctx, limit)
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to list campaign stats", zap.Error(err))
+		return nil, models.ErrInternalServer
+	}
+
+	return stats, nil
+}
+
+// ensureCode returns the code of the user, creating one if needed. A new
+// code is drawn again when it happens to be taken.
+func (s *ServiceImpl) ensureCode(ctx context.Context, userID uuid.UUID) (string, error) {
+	for attempt := 0; attempt < codeAttempts; attempt++ {
+		code, err := s.referralRepo.EnsureCode(ctx, userID, newCode())
+		switch {
+		case err == nil:
+			return code, nil
+		case errors.Is(err, models.ErrUserNotFound):
+			return "", models.ErrUserNotFound
+		case errors.Is(err, models.ErrConflict):
+			continue
+		default:
+			logctx.Logger(ctx, s.logger).Error("Failed to get referral code", zap.Error(err))
+			return "", models.ErrInternalServer
+		}
+	}
+
+	logctx.Logger(ctx, s.logger).Error("Failed to draw a free referral code", zap.Int("attempts", codeAttempts))
+	return "", models.ErrInternalServer
+}
+
+// newCode draws a random referral code
+func newCode() string {
+	b := make([]byte, codeLength)
+	for i := range b {
+		// crypto/rand never fails on supported platforms
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(codeAlphabet))))
+		b[i] = codeAlphabet[n.Int64()]
+	}
+	return string(b)
+}
@@ -0,0 +1,31 @@
+package referral
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"renfound_v1/internal/domain/models"
+)
+
+// Start parameter prefixes naming the source of a signup. Mini App links
+// carry them in startapp, e.g. https://t.me/bot/app?startapp=ref_k3m9x2pq.
+const (
+	ReferrerPrefix = "ref_"
+	CampaignPrefix = "cmp_"
+)
+
+// Service defines the interface for referral operations
+type Service interface {
+	// Attribute credits the signup of user to the referrer or campaign
+	// named in startParam. Other start parameters are ignored. A user is
+	// attributed once and never to themselves. Failures are logged, they
+	// never fail the signup.
+	Attribute(ctx context.Context, user *models.User, startParam string)
+
+	// Stats returns the referral code of the user with the signups it
+	// brought in. The code is created on first use.
+	Stats(ctx context.Context, userID uuid.UUID) (*models.ReferralStats, error)
+
+	// CampaignStats lists the campaigns that brought in most signups
+	CampaignStats(ctx context.Context, limit int) ([]*models.CampaignStats, error)
+}
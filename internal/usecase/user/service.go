@@ -15,9 +15,13 @@ type Service interface {
 	Logout(ctx context.Context, refreshToken string) error
 	LogoutAll(ctx context.Context, userID uuid.UUID) error
 
+	// Admin methods
+	AdminLogoutAll(ctx context.Context, userID uuid.UUID) error
+
 	// User methods
 	GetUser(ctx context.Context, id uuid.UUID) (*models.User, error)
 	GetUserByTelegramID(ctx context.Context, telegramID int64) (*models.User, error)
 	UpdateUser(ctx context.Context, user *models.User) error
+	SetBotReachable(ctx context.Context, telegramID int64, reachable bool) error
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 }
@@ -4,19 +4,32 @@ import (
 	"context"
 	"errors"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"go.uber.org/zap"
 	"renfound_v1/config"
 	"renfound_v1/infrastructure/auth"
+	"renfound_v1/infrastructure/metrics"
 	"renfound_v1/internal/domain/models"
 	"renfound_v1/internal/domain/repository"
+	"renfound_v1/internal/usecase/audit"
+	"renfound_v1/internal/usecase/login"
+	"renfound_v1/internal/usecase/referral"
 	"renfound_v1/internal/utils/async"
+	"renfound_v1/internal/utils/logctx"
 )
 
+var tracer = otel.Tracer("renfound_v1/internal/usecase/user")
+
 type ServiceImpl struct {
 	cfg          *config.AppConfig
 	userRepo     repository.UserRepository
 	telegramAuth *auth.TelegramAuth
+	audit        audit.Service
+	logins       login.Service
+	referrals    referral.Service
 	workerPool   *async.WorkerPool
+	metrics      *metrics.Metrics
 	logger       *zap.Logger
 }
 
@@ -24,17 +37,38 @@ func NewService(
 	cfg *config.AppConfig,
 	userRepo repository.UserRepository,
 	telegramAuth *auth.TelegramAuth,
-	workerPool *async.WorkerPool) Service {
+	auditService audit.Service,
+	loginService login.Service,
+	referralService referral.Service,
+	workerPool *async.WorkerPool,
+	metrics *metrics.Metrics) Service {
 	return &ServiceImpl{
 		cfg:          cfg,
 		userRepo:     userRepo,
 		telegramAuth: telegramAuth,
+		audit:        auditService,
+		logins:       loginService,
+		referrals:    referralService,
 		workerPool:   workerPool,
+		metrics:      metrics,
 		logger:       cfg.Logger.With(zap.String("component", "user_service")),
 	}
 }
 
-func (s *ServiceImpl) AuthWithTelegram(ctx context.Context, initData, userAgent, ipAddress string) (*models.Tokens, error) {
+func (s *ServiceImpl) AuthWithTelegram(ctx context.Context, initData, userAgent, ipAddress string) (tokens *models.Tokens, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.AuthWithTelegram")
+	defer span.End()
+
+	// Set once the user is known, failed sign ins have no subject
+	var subject *uuid.UUID
+	defer func() {
+		s.metrics.ObserveAuth("telegram", authOutcome(err, false))
+		s.recordAuth(ctx, models.AuditActionLogin, subject, userAgent, ipAddress, err, false)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}()
+
 	// Validate Telegram init data
 	telegramUser, err := s.telegramAuth.ValidateInitData(ctx, initData)
 	if err != nil {
@@ -45,7 +79,7 @@ func (s *ServiceImpl) AuthWithTelegram(ctx context.Context, initData, userAgent,
 	user, err := s.userRepo.GetByTelegramID(ctx, telegramUser.ID)
 	if err != nil {
 		if !errors.Is(err, models.ErrUserNotFound) {
-			s.logger.Error("Failed to get user by Telegram ID", zap.Error(err), zap.Int64("telegram_id", telegramUser.ID))
+			logctx.Logger(ctx, s.logger).Error("Failed to get user by Telegram ID", zap.Error(err), zap.Int64("telegram_id", telegramUser.ID))
 			return nil, models.ErrInternalServer
 		}
 
@@ -56,31 +90,52 @@ func (s *ServiceImpl) AuthWithTelegram(ctx context.Context, initData, userAgent,
 			telegramUser.FirstName,
 			telegramUser.LastName,
 			telegramUser.PhotoURL,
+			telegramUser.LanguageCode,
 			telegramUser.AuthDate,
 		)
 
 		if err := s.userRepo.Create(ctx, user); err != nil {
-			s.logger.Error("Failed to create user", zap.Error(err), zap.Int64("telegram_id", telegramUser.ID))
+			logctx.Logger(ctx, s.logger).Error("Failed to create user", zap.Error(err), zap.Int64("telegram_id", telegramUser.ID))
 			return nil, models.ErrInternalServer
 		}
+
+		// Only signups are attributed, returning users keep their source
+		if telegramUser.StartParam != "" {
+			s.referrals.Attribute(ctx, user, telegramUser.StartParam)
+		}
 	} else {
 		// Update existing user with new data
 		user.Username = telegramUser.Username
 		user.FirstName = telegramUser.FirstName
 		user.LastName = telegramUser.LastName
 		user.PhotoURL = telegramUser.PhotoURL
+		user.LanguageCode = telegramUser.LanguageCode
 		user.AuthDate = telegramUser.AuthDate
 
 		if err := s.userRepo.Update(ctx, user); err != nil {
-			s.logger.Error("Failed to update user", zap.Error(err), zap.Int64("telegram_id", telegramUser.ID))
+			logctx.Logger(ctx, s.logger).Error("Failed to update user", zap.Error(err), zap.Int64("telegram_id", telegramUser.ID))
 			return nil, models.ErrInternalServer
 		}
+
+		// Reopening the Mini App means the user is back, the bot may message
+		// them again
+		if !user.BotReachable {
+			if err := s.userRepo.SetBotReachable(ctx, user.TelegramID, true); err != nil {
+				logctx.Logger(ctx, s.logger).Error("Failed to reset bot reachability", zap.Error(err), zap.Int64("telegram_id", telegramUser.ID))
+				return nil, models.ErrInternalServer
+			}
+			user.BotReachable = true
+		}
 	}
 
+	// The request was anonymous until now, later logs belong to the user
+	ctx = logctx.WithUserID(ctx, user.ID.String())
+	subject = &user.ID
+
 	// Generate tokens
-	tokens, err := s.telegramAuth.GenerateTokens(user.ID, user.TelegramID)
+	tokens, err = s.telegramAuth.GenerateTokens(ctx, user.ID, user.TelegramID, user.LanguageCode)
 	if err != nil {
-		s.logger.Error("Failed to generate tokens", zap.Error(err), zap.String("user_id", user.ID.String()))
+		logctx.Logger(ctx, s.logger).Error("Failed to generate tokens", zap.Error(err))
 		return nil, models.ErrInternalServer
 	}
 
@@ -93,19 +148,35 @@ func (s *ServiceImpl) AuthWithTelegram(ctx context.Context, initData, userAgent,
 		s.cfg.Config.JWT.RefreshTTL,
 	)
 
-	// Submit task to worker pool
-	s.workerPool.Submit(func() {
-		// Use background context as the original ctx might be cancelled
-		bgCtx := context.Background()
-		if err := s.userRepo.CreateSession(bgCtx, session); err != nil {
-			s.logger.Error("Failed to create session", zap.Error(err), zap.String("user_id", user.ID.String()))
+	// Session writes go to the critical lane so bulk jobs can't delay them
+	s.workerPool.SubmitContext(ctx, async.LaneCritical, func(taskCtx context.Context) {
+		if err := s.userRepo.CreateSession(taskCtx, session); err != nil {
+			logctx.Logger(taskCtx, s.logger).Error("Failed to create session", zap.Error(err))
 		}
 	})
 
+	s.logins.Record(ctx, user, models.LoginMethodTelegram, userAgent, ipAddress)
+
 	return tokens, nil
 }
 
-func (s *ServiceImpl) RefreshTokens(ctx context.Context, refreshToken, userAgent, ipAddress string) (*models.Tokens, error) {
+func (s *ServiceImpl) RefreshTokens(ctx context.Context, refreshToken, userAgent, ipAddress string) (tokens *models.Tokens, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.RefreshTokens")
+	defer span.End()
+
+	// A validly signed token without a session was already rotated or
+	// revoked, so reusing it is reported as a replay
+	replay := false
+	// Set once the token is known to be ours
+	var subject *uuid.UUID
+	defer func() {
+		s.metrics.ObserveAuth("refresh", authOutcome(err, replay))
+		s.recordAuth(ctx, models.AuditActionRefresh, subject, userAgent, ipAddress, err, replay)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}()
+
 	// Validate refresh token
 	userIDStr, err := s.telegramAuth.ValidateRefreshToken(refreshToken)
 	if err != nil {
@@ -115,50 +186,55 @@ func (s *ServiceImpl) RefreshTokens(ctx context.Context, refreshToken, userAgent
 	// Parse user ID
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		s.logger.Error("Invalid user ID in token", zap.Error(err), zap.String("user_id", userIDStr))
+		logctx.Logger(ctx, s.logger).Error("Invalid user ID in token", zap.Error(err), zap.String("user_id", userIDStr))
 		return nil, models.ErrInvalidToken
 	}
+	subject = &userID
 
 	// Check if session exists
 	session, err := s.userRepo.GetSessionByToken(ctx, refreshToken)
 	if err != nil {
 		if errors.Is(err, models.ErrSessionNotFound) {
+			replay = true
 			return nil, models.ErrInvalidToken
 		}
-		s.logger.Error("Failed to get session", zap.Error(err), zap.String("refresh_token", refreshToken))
+		logctx.Logger(ctx, s.logger).Error("Failed to get session", zap.Error(err))
 		return nil, models.ErrInternalServer
 	}
 
 	// Verify session belongs to the user
 	if session.UserID != userID {
-		s.logger.Warn("Session user ID mismatch",
+		logctx.Logger(ctx, s.logger).Warn("Session user ID mismatch",
 			zap.String("token_user_id", userID.String()),
 			zap.String("session_user_id", session.UserID.String()))
 		return nil, models.ErrInvalidToken
 	}
 
+	// Refresh requests are anonymous, later logs belong to the user
+	ctx = logctx.WithUserID(ctx, userID.String())
+
 	// Get user
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, models.ErrUserNotFound) {
 			return nil, models.ErrInvalidToken
 		}
-		s.logger.Error("Failed to get user", zap.Error(err), zap.String("user_id", userID.String()))
+		logctx.Logger(ctx, s.logger).Error("Failed to get user", zap.Error(err))
 		return nil, models.ErrInternalServer
 	}
 
 	// Delete old session
 	if err := s.userRepo.DeleteSession(ctx, session.ID); err != nil {
 		if !errors.Is(err, models.ErrSessionNotFound) {
-			s.logger.Error("Failed to delete session", zap.Error(err), zap.String("session_id", session.ID.String()))
+			logctx.Logger(ctx, s.logger).Error("Failed to delete session", zap.Error(err), zap.String("session_id", session.ID.String()))
 			return nil, models.ErrInternalServer
 		}
 	}
 
 	// Generate new tokens
-	tokens, err := s.telegramAuth.GenerateTokens(user.ID, user.TelegramID)
+	tokens, err = s.telegramAuth.GenerateTokens(ctx, user.ID, user.TelegramID, user.LanguageCode)
 	if err != nil {
-		s.logger.Error("Failed to generate tokens", zap.Error(err), zap.String("user_id", user.ID.String()))
+		logctx.Logger(ctx, s.logger).Error("Failed to generate tokens", zap.Error(err))
 		return nil, models.ErrInternalServer
 	}
 
@@ -171,19 +247,22 @@ func (s *ServiceImpl) RefreshTokens(ctx context.Context, refreshToken, userAgent
 		s.cfg.Config.JWT.RefreshTTL,
 	)
 
-	// Submit task to worker pool
-	s.workerPool.Submit(func() {
-		// Use background context as the original ctx might be cancelled
-		bgCtx := context.Background()
-		if err := s.userRepo.CreateSession(bgCtx, newSession); err != nil {
-			s.logger.Error("Failed to create session", zap.Error(err), zap.String("user_id", user.ID.String()))
+	// Session writes go to the critical lane so bulk jobs can't delay them
+	s.workerPool.SubmitContext(ctx, async.LaneCritical, func(taskCtx context.Context) {
+		if err := s.userRepo.CreateSession(taskCtx, newSession); err != nil {
+			logctx.Logger(taskCtx, s.logger).Error("Failed to create session", zap.Error(err))
 		}
 	})
 
+	s.logins.Record(ctx, user, models.LoginMethodRefresh, userAgent, ipAddress)
+
 	return tokens, nil
 }
 
 func (s *ServiceImpl) Logout(ctx context.Context, refreshToken string) error {
+	ctx, span := tracer.Start(ctx, "UserService.Logout")
+	defer span.End()
+
 	// Check if session exists
 	session, err := s.userRepo.GetSessionByToken(ctx, refreshToken)
 	if err != nil {
@@ -191,25 +270,66 @@ func (s *ServiceImpl) Logout(ctx context.Context, refreshToken string) error {
 			// Already logged out
 			return nil
 		}
-		s.logger.Error("Failed to get session", zap.Error(err), zap.String("refresh_token", refreshToken))
+		logctx.Logger(ctx, s.logger).Error("Failed to get session", zap.Error(err))
 		return models.ErrInternalServer
 	}
 
+	// Logout requests are anonymous, later logs belong to the session owner
+	ctx = logctx.WithUserID(ctx, session.UserID.String())
+
 	// Delete session
 	if err := s.userRepo.DeleteSession(ctx, session.ID); err != nil {
 		if !errors.Is(err, models.ErrSessionNotFound) {
-			s.logger.Error("Failed to delete session", zap.Error(err), zap.String("session_id", session.ID.String()))
+			logctx.Logger(ctx, s.logger).Error("Failed to delete session", zap.Error(err), zap.String("session_id", session.ID.String()))
 			return models.ErrInternalServer
 		}
 	}
 
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:   models.AuditActionLogout,
+		TargetID: &session.UserID,
+		Outcome:  models.AuditOutcomeSuccess,
+	})
+
 	return nil
 }
 
-func (s *ServiceImpl) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+func (s *ServiceImpl) LogoutAll(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.LogoutAll")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.AuditActionLogoutAll, userID, err)
+	}()
+
 	// Delete all sessions for the user
 	if err := s.userRepo.DeleteUserSessions(ctx, userID); err != nil {
-		s.logger.Error("Failed to delete user sessions", zap.Error(err), zap.String("user_id", userID.String()))
+		logctx.Logger(ctx, s.logger).Error("Failed to delete user sessions", zap.Error(err), zap.String("user_id", userID.String()))
+		return models.ErrInternalServer
+	}
+
+	return nil
+}
+
+func (s *ServiceImpl) AdminLogoutAll(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.AdminLogoutAll")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.AuditActionAdminLogoutAll, userID, err)
+	}()
+
+	// Unlike users, admins can name a user that doesn't exist
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return models.ErrUserNotFound
+		}
+		logctx.Logger(ctx, s.logger).Error("Failed to get user", zap.Error(err), zap.String("user_id", userID.String()))
+		return models.ErrInternalServer
+	}
+
+	if err := s.userRepo.DeleteUserSessions(ctx, userID); err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to delete user sessions", zap.Error(err), zap.String("user_id", userID.String()))
 		return models.ErrInternalServer
 	}
 
@@ -217,12 +337,15 @@ func (s *ServiceImpl) LogoutAll(ctx context.Context, userID uuid.UUID) error {
 }
 
 func (s *ServiceImpl) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUser")
+	defer span.End()
+
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, models.ErrUserNotFound) {
 			return nil, models.ErrUserNotFound
 		}
-		s.logger.Error("Failed to get user", zap.Error(err), zap.String("user_id", id.String()))
+		logctx.Logger(ctx, s.logger).Error("Failed to get user", zap.Error(err), zap.String("user_id", id.String()))
 		return nil, models.ErrInternalServer
 	}
 
@@ -230,12 +353,15 @@ func (s *ServiceImpl) GetUser(ctx context.Context, id uuid.UUID) (*models.User,
 }
 
 func (s *ServiceImpl) GetUserByTelegramID(ctx context.Context, telegramID int64) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByTelegramID")
+	defer span.End()
+
 	user, err := s.userRepo.GetByTelegramID(ctx, telegramID)
 	if err != nil {
 		if errors.Is(err, models.ErrUserNotFound) {
 			return nil, models.ErrUserNotFound
 		}
-		s.logger.Error("Failed to get user by Telegram ID", zap.Error(err), zap.Int64("telegram_id", telegramID))
+		logctx.Logger(ctx, s.logger).Error("Failed to get user by Telegram ID", zap.Error(err), zap.Int64("telegram_id", telegramID))
 		return nil, models.ErrInternalServer
 	}
 
@@ -243,21 +369,43 @@ func (s *ServiceImpl) GetUserByTelegramID(ctx context.Context, telegramID int64)
 }
 
 func (s *ServiceImpl) UpdateUser(ctx context.Context, user *models.User) error {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
 	if err := s.userRepo.Update(ctx, user); err != nil {
 		if errors.Is(err, models.ErrUserNotFound) {
 			return models.ErrUserNotFound
 		}
-		s.logger.Error("Failed to update user", zap.Error(err), zap.String("user_id", user.ID.String()))
+		logctx.Logger(ctx, s.logger).Error("Failed to update user", zap.Error(err), zap.String("user_id", user.ID.String()))
+		return models.ErrInternalServer
+	}
+
+	return nil
+}
+
+func (s *ServiceImpl) SetBotReachable(ctx context.Context, telegramID int64, reachable bool) error {
+	ctx, span := tracer.Start(ctx, "UserService.SetBotReachable")
+	defer span.End()
+
+	if err := s.userRepo.SetBotReachable(ctx, telegramID, reachable); err != nil {
+		logctx.Logger(ctx, s.logger).Error("Failed to set bot reachability", zap.Error(err), zap.Int64("telegram_id", telegramID))
 		return models.ErrInternalServer
 	}
 
 	return nil
 }
 
-func (s *ServiceImpl) DeleteUser(ctx context.Context, id uuid.UUID) error {
+func (s *ServiceImpl) DeleteUser(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
+	defer func() {
+		s.record(ctx, models.AuditActionUserDelete, id, err)
+	}()
+
 	//remove all sessions of a user
 	if err := s.userRepo.DeleteUserSessions(ctx, id); err != nil {
-		s.logger.Error("Failed to delete user sessions", zap.Error(err), zap.String("user_id", id.String()))
+		logctx.Logger(ctx, s.logger).Error("Failed to delete user sessions", zap.Error(err), zap.String("user_id", id.String()))
 		return models.ErrInternalServer
 	}
 
@@ -266,8 +414,65 @@ func (s *ServiceImpl) DeleteUser(ctx context.Context, id uuid.UUID) error {
 		if errors.Is(err, models.ErrUserNotFound) {
 			return models.ErrUserNotFound
 		}
-		s.logger.Error("Failed to delete user", zap.Error(err), zap.String("user_id", id.String()))
+		logctx.Logger(ctx, s.logger).Error("Failed to delete user", zap.Error(err), zap.String("user_id", id.String()))
 		return models.ErrInternalServer
 	}
 	return nil
 }
+
+// recordAuth audits a sign in or refresh. subject is both the actor and
+// the target, nil when the caller could not be identified.
+func (s *ServiceImpl) recordAuth(ctx context.Context, action string, subject *uuid.UUID, userAgent, ipAddress string, err error, replay bool) {
+	event := models.AuditEvent{
+		Action:    action,
+		ActorID:   subject,
+		TargetID:  subject,
+		Outcome:   models.AuditOutcomeSuccess,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+	if err != nil {
+		event.Outcome = models.AuditOutcomeFailure
+		event.Reason = models.LookupError(err).Code
+		if replay {
+			event.Reason = "token_replay"
+		}
+	}
+
+	s.audit.Record(ctx, event)
+}
+
+// record audits an action of the authenticated caller on a user
+func (s *ServiceImpl) record(ctx context.Context, action string, target uuid.UUID, err error) {
+	event := models.AuditEvent{
+		Action:   action,
+		TargetID: &target,
+		Outcome:  models.AuditOutcomeSuccess,
+	}
+	if err != nil {
+		event.Outcome = models.AuditOutcomeFailure
+		event.Reason = models.LookupError(err).Code
+	}
+
+	s.audit.Record(ctx, event)
+}
+
+// authOutcome maps the result of an auth flow to a metrics outcome label
+func authOutcome(err error, replay bool) string {
+	switch {
+	case err == nil:
+		return metrics.AuthSuccess
+	case replay:
+		return metrics.AuthReplay
+	case errors.Is(err, models.ErrInvalidSignature):
+		return metrics.AuthInvalidSignature
+	case errors.Is(err, models.ErrExpiredToken):
+		return metrics.AuthExpired
+	case errors.Is(err, models.ErrInvalidInitData):
+		return metrics.AuthInvalidInitData
+	case errors.Is(err, models.ErrInvalidToken):
+		return metrics.AuthInvalidToken
+	default:
+		return metrics.AuthInternalError
+	}
+}
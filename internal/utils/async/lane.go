@@ -0,0 +1,134 @@
+package async
+
+import (
+	"sort"
+	"time"
+)
+
+// Well-known lane names
+const (
+	LaneCritical = "critical"
+	LaneDefault  = "default"
+	LaneBulk     = "bulk"
+)
+
+// LaneConfig describes a named queue inside the worker pool. Lanes with a
+// higher priority are always served first; MaxConcurrency and RateLimit cap
+// how much of the pool a single lane may use.
+type LaneConfig struct {
+	Name           string
+	Priority       int
+	QueueSize      int
+	MaxConcurrency int     // 0 means limited only by the pool size
+	RateLimit      float64 // tasks per second, 0 means unlimited
+	RateBurst      int
+}
+
+// DefaultLanes returns the lane set used when none is configured
+func DefaultLanes() []LaneConfig {
+	return []LaneConfig{
+		{Name: LaneCritical, Priority: 100, QueueSize: 100},
+		{Name: LaneDefault, Priority: 50, QueueSize: 100},
+		{Name: LaneBulk, Priority: 10, QueueSize: 1000, MaxConcurrency: 2},
+	}
+}
+
+// LaneStats is a point-in-time snapshot of a single lane
+type LaneStats struct {
+	Priority       int    `json:"priority"`
+	QueueDepth     int    `json:"queue_depth"`
+	QueueCapacity  int    `json:"queue_capacity"`
+	Running        int    `json:"running"`
+	MaxConcurrency int    `json:"max_concurrency"`
+	Completed      uint64 `json:"completed"`
+	Dropped        uint64 `json:"dropped"`
+	Panicked       uint64 `json:"panicked"`
+	Abandoned      uint64 `json:"abandoned"`
+}
+
+type lane struct {
+	LaneConfig
+	queue   []Task
+	running int
+	limiter *tokenBucket
+
+	completed uint64
+	dropped   uint64
+	panicked  uint64
+	abandoned uint64
+}
+
+func newLane(cfg LaneConfig) *lane {
+	l := &lane{LaneConfig: cfg}
+	if cfg.RateLimit > 0 {
+		l.limiter = newTokenBucket(cfg.RateLimit, cfg.RateBurst)
+	}
+	return l
+}
+
+// hasCapacity reports whether the lane may start another task
+func (l *lane) hasCapacity() bool {
+	return len(l.queue) > 0 && (l.MaxConcurrency <= 0 || l.running < l.MaxConcurrency)
+}
+
+func (l *lane) pop() Task {
+	task := l.queue[0]
+	l.queue[0] = nil
+	l.queue = l.queue[1:]
+	return task
+}
+
+func (l *lane) stats() LaneStats {
+	return LaneStats{
+		Priority:       l.Priority,
+		QueueDepth:     len(l.queue),
+		QueueCapacity:  l.QueueSize,
+		Running:        l.running,
+		MaxConcurrency: l.MaxConcurrency,
+		Completed:      l.completed,
+		Dropped:        l.dropped,
+		Panicked:       l.panicked,
+		Abandoned:      l.abandoned,
+	}
+}
+
+// sortLanes orders lanes by descending priority, keeping config order for ties
+func sortLanes(lanes []*lane) {
+	sort.SliceStable(lanes, func(i, j int) bool {
+		return lanes[i].Priority > lanes[j].Priority
+	})
+}
+
+// tokenBucket is a minimal token bucket used for per-lane rate limiting.
+// It is not safe for concurrent use; the pool guards it with its mutex.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// take consumes a token if available, otherwise it returns how long to wait
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
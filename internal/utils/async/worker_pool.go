@@ -1,6 +1,10 @@
 package async
 
 import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"sync"
 	"time"
@@ -8,29 +12,73 @@ import (
 
 type Task func()
 
+// ContextTask is a task that receives the context it was submitted with
+type ContextTask func(ctx context.Context)
+
+var tracer = otel.Tracer("renfound_v1/internal/utils/async")
+
+// Stats is a point-in-time snapshot of the worker pool counters
+type Stats struct {
+	Workers       int                  `json:"workers"`
+	QueueDepth    int                  `json:"queue_depth"`
+	QueueCapacity int                  `json:"queue_capacity"`
+	ActiveWorkers int64                `json:"active_workers"`
+	Completed     uint64               `json:"completed"`
+	Dropped       uint64               `json:"dropped"`
+	Panicked      uint64               `json:"panicked"`
+	Abandoned     uint64               `json:"abandoned"`
+	Lanes         map[string]LaneStats `json:"lanes"`
+}
+
 type WorkerPool struct {
-	tasks        chan Task
+	lanes        []*lane
+	lanesByName  map[string]*lane
 	workersCount int
 	logger       *zap.Logger
 	wg           sync.WaitGroup
-	shutdown     chan struct{}
-	isShutDown   bool
-	mu           sync.Mutex
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	isShutDown bool
+	active     int
+
+	// wakeup re-checks rate limited lanes once their next token is due
+	wakeup *time.Timer
+	wakeAt time.Time
 }
 
-func NewWorkerPool(workersCount int, queueSize int, logger *zap.Logger) *WorkerPool {
+// NewWorkerPool creates a pool with the given number of workers shared by the
+// lanes. A "default" lane is added if the configuration does not define one.
+func NewWorkerPool(workersCount int, lanes []LaneConfig, logger *zap.Logger) *WorkerPool {
+	if len(lanes) == 0 {
+		lanes = DefaultLanes()
+	}
+
 	pool := &WorkerPool{
-		tasks:        make(chan Task, queueSize),
+		lanesByName:  make(map[string]*lane, len(lanes)),
 		workersCount: workersCount,
 		logger:       logger.With(zap.String("component", "worker_pool")),
-		shutdown:     make(chan struct{}),
 	}
+	pool.cond = sync.NewCond(&pool.mu)
+
+	for _, cfg := range lanes {
+		l := newLane(cfg)
+		pool.lanes = append(pool.lanes, l)
+		pool.lanesByName[cfg.Name] = l
+	}
+	if _, ok := pool.lanesByName[LaneDefault]; !ok {
+		l := newLane(LaneConfig{Name: LaneDefault, Priority: 50, QueueSize: 100})
+		pool.lanes = append(pool.lanes, l)
+		pool.lanesByName[LaneDefault] = l
+	}
+	sortLanes(pool.lanes)
+
 	pool.start()
 	return pool
 }
 
 func (p *WorkerPool) start() {
-	p.logger.Info("Starting worker pool", zap.Int("workers", p.workersCount))
+	p.logger.Info("Starting worker pool", zap.Int("workers", p.workersCount), zap.Int("lanes", len(p.lanes)))
 
 	for i := 0; i < p.workersCount; i++ {
 		p.wg.Add(1)
@@ -42,68 +90,253 @@ func (p *WorkerPool) worker(id int) {
 	defer p.wg.Done()
 	p.logger.Debug("Worker started", zap.Int("worker_id", id))
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	for {
-		select {
-		case task, ok := <-p.tasks:
-			if !ok {
-				p.logger.Debug("Worker shutting down (channel closed)", zap.Int("worker_id", id))
-				return
+		task, l, wait := p.next(time.Now())
+		if task != nil {
+			l.running++
+			p.active++
+			p.mu.Unlock()
+
+			panicked := p.run(id, l.Name, task)
+
+			p.mu.Lock()
+			l.running--
+			p.active--
+			if panicked {
+				l.panicked++
+			} else {
+				l.completed++
 			}
-			startTime := time.Now()
-
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						p.logger.Error("Task panicked", zap.Any("panic", r), zap.Int("worker_id", id))
-					}
-				}()
-				task()
-			}()
-			p.logger.Debug("Task completed",
-				zap.Int("worker_id", id),
-				zap.Duration("duration", time.Since(startTime)))
-		case <-p.shutdown:
-			p.logger.Debug("Worker shutting down (shutdown signal)", zap.Int("worker_id", id))
+			// A concurrency slot was freed, other workers may now pick this lane
+			p.cond.Broadcast()
+			continue
+		}
+
+		// Once shut down, workers exit as soon as every lane is drained
+		if p.isShutDown && p.queued() == 0 {
+			p.logger.Debug("Worker shutting down (queue drained)", zap.Int("worker_id", id))
 			return
 		}
+
+		if wait > 0 {
+			p.scheduleWakeup(wait)
+		}
+		p.cond.Wait()
 	}
 }
 
+// next picks a task from the highest priority lane that has work, a free
+// concurrency slot and a rate limit token. If only rate limited lanes have
+// work, it returns the shortest time until one of them may run.
+// Must be called with p.mu held.
+func (p *WorkerPool) next(now time.Time) (Task, *lane, time.Duration) {
+	var wait time.Duration
+
+	for _, l := range p.lanes {
+		if !l.hasCapacity() {
+			continue
+		}
+
+		if l.limiter != nil {
+			ok, d := l.limiter.take(now)
+			if !ok {
+				if wait == 0 || d < wait {
+					wait = d
+				}
+				continue
+			}
+		}
+
+		return l.pop(), l, 0
+	}
+
+	return nil, nil, wait
+}
+
+// scheduleWakeup wakes the workers after d unless an earlier wakeup is pending.
+// Must be called with p.mu held.
+func (p *WorkerPool) scheduleWakeup(d time.Duration) {
+	at := time.Now().Add(d)
+	if p.wakeup != nil && !p.wakeAt.IsZero() && p.wakeAt.Before(at) {
+		return
+	}
+
+	if p.wakeup != nil {
+		p.wakeup.Stop()
+	}
+	p.wakeAt = at
+	p.wakeup = time.AfterFunc(d, func() {
+		p.mu.Lock()
+		p.wakeAt = time.Time{}
+		p.cond.Broadcast()
+		p.mu.Unlock()
+	})
+}
+
+// queued returns the number of tasks waiting in all lanes.
+// Must be called with p.mu held.
+func (p *WorkerPool) queued() int {
+	total := 0
+	for _, l := range p.lanes {
+		total += len(l.queue)
+	}
+	return total
+}
+
+// run executes a single task and reports whether it panicked
+func (p *WorkerPool) run(workerID int, laneName string, task Task) (panicked bool) {
+	startTime := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			p.logger.Error("Task panicked",
+				zap.Any("panic", r),
+				zap.Int("worker_id", workerID),
+				zap.String("lane", laneName))
+			return
+		}
+		p.logger.Debug("Task completed",
+			zap.Int("worker_id", workerID),
+			zap.String("lane", laneName),
+			zap.Duration("duration", time.Since(startTime)))
+	}()
+
+	task()
+	return false
+}
+
+// Submit queues a task on the default lane
 func (p *WorkerPool) Submit(task Task) bool {
+	return p.SubmitTo(LaneDefault, task)
+}
+
+// SubmitTo queues a task on the named lane. Unknown lanes fall back to the
+// default lane. It returns false when the pool is shutting down or the lane
+// queue is full; in both cases the task is dropped.
+func (p *WorkerPool) SubmitTo(laneName string, task Task) bool {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	l, ok := p.lanesByName[laneName]
+	if !ok {
+		p.logger.Warn("Unknown lane, using default", zap.String("lane", laneName))
+		l = p.lanesByName[LaneDefault]
+	}
+
 	if p.isShutDown {
+		l.dropped++
+		p.logger.Warn("Task submitted after shutdown", zap.String("lane", l.Name))
 		return false
 	}
 
-	select {
-	case p.tasks <- task:
-		return true
-	default:
-		p.logger.Warn("The queue is full")
+	if len(l.queue) >= l.QueueSize {
+		l.dropped++
+		p.logger.Warn("The queue is full", zap.String("lane", l.Name))
 		return false
 	}
+
+	l.queue = append(l.queue, task)
+	p.cond.Signal()
+	return true
+}
+
+// SubmitContext queues a task on the named lane and runs it with a context
+// derived from ctx. The task context keeps ctx values but is not cancelled
+// with it, since the submitting request usually finishes first. The task
+// runs in its own trace, linked to the span active in ctx.
+func (p *WorkerPool) SubmitContext(ctx context.Context, laneName string, task ContextTask) bool {
+	link := trace.LinkFromContext(ctx)
+	detached := context.WithoutCancel(ctx)
+
+	return p.SubmitTo(laneName, func() {
+		taskCtx, span := tracer.Start(detached, "async.task",
+			trace.WithNewRoot(),
+			trace.WithLinks(link),
+			trace.WithAttributes(attribute.String("async.lane", laneName)),
+		)
+		defer span.End()
+
+		task(taskCtx)
+	})
+}
+
+// Stats returns a snapshot of the pool counters
+func (p *WorkerPool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := Stats{
+		Workers:       p.workersCount,
+		ActiveWorkers: int64(p.active),
+		Lanes:         make(map[string]LaneStats, len(p.lanes)),
+	}
+
+	for _, l := range p.lanes {
+		ls := l.stats()
+		stats.Lanes[l.Name] = ls
+		stats.QueueDepth += ls.QueueDepth
+		stats.QueueCapacity += ls.QueueCapacity
+		stats.Completed += ls.Completed
+		stats.Dropped += ls.Dropped
+		stats.Panicked += ls.Panicked
+		stats.Abandoned += ls.Abandoned
+	}
+
+	return stats
 }
 
-func (p *WorkerPool) Shutdown(wait bool) {
+// Shutdown stops accepting new tasks and lets the workers drain the queue.
+// If ctx is done before the queue is empty, the remaining tasks are abandoned
+// and their count is returned together with the context error. Tasks that
+// are already running are not interrupted.
+func (p *WorkerPool) Shutdown(ctx context.Context) (int, error) {
 	p.mu.Lock()
 	if p.isShutDown {
 		p.mu.Unlock()
-		return
+		return 0, nil
 	}
 	p.isShutDown = true
+	queued := p.queued()
+	p.cond.Broadcast()
 	p.mu.Unlock()
-	p.logger.Info("Shutting down worker pool")
-
-	close(p.tasks)
 
-	close(p.shutdown)
+	p.logger.Info("Draining worker pool", zap.Int("queued", queued))
 
-	if wait {
-		p.logger.Debug("Waiting for all workers to finish")
+	done := make(chan struct{})
+	go func() {
 		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
 		p.logger.Info("Worker pool shutdown complete")
+		return 0, nil
+	case <-ctx.Done():
 	}
 
+	// Drain deadline exceeded, discard whatever is still queued. Workers stuck
+	// in long-running tasks are not waited for.
+	p.mu.Lock()
+	abandoned := 0
+	for _, l := range p.lanes {
+		n := len(l.queue)
+		l.abandoned += uint64(n)
+		l.queue = nil
+		abandoned += n
+	}
+	running := p.active
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	p.logger.Warn("Worker pool drain deadline exceeded",
+		zap.Int("abandoned", abandoned),
+		zap.Int("still_running", running),
+		zap.Error(ctx.Err()))
+
+	return abandoned, ctx.Err()
 }
@@ -0,0 +1,233 @@
+// Package logcontrol changes log verbosity at runtime. Loggers are matched
+// by their "component" field, so a single component can be made more or
+// less verbose than the rest of the application, and high volume
+// components can be sampled.
+package logcontrol
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// componentKey is the field loggers are tagged with, see logger.With calls
+const componentKey = "component"
+
+// ErrUnknownComponent is returned when overriding the level of a component
+// no logger was tagged with
+var ErrUnknownComponent = errors.New("unknown component")
+
+// Sampling limits how many info and debug entries with the same message a
+// component logs per tick. Warnings and errors are never sampled.
+type Sampling struct {
+	Tick       time.Duration
+	Initial    int // entries logged per tick before sampling starts
+	Thereafter int // then every Nth entry is logged
+	Components []string
+}
+
+// Controller holds the global level and per component overrides
+type Controller struct {
+	level      zap.AtomicLevel
+	components atomic.Pointer[map[string]zapcore.Level]
+	mu         sync.Mutex // serializes writers of components
+	sampling   Sampling
+	sampled    map[string]struct{}
+	// known holds the components loggers were tagged with. Overrides are
+	// limited to them, so the set of overrides stays bounded.
+	known sync.Map
+}
+
+// NewController creates a controller starting at level with the given
+// component overrides
+func NewController(level zapcore.Level, components map[string]zapcore.Level, sampling Sampling) *Controller {
+	c := &Controller{
+		level:    zap.NewAtomicLevelAt(level),
+		sampling: sampling,
+		sampled:  make(map[string]struct{}, len(sampling.Components)),
+	}
+	for _, component := range sampling.Components {
+		c.sampled[component] = struct{}{}
+	}
+	c.storeComponents(components)
+
+	return c
+}
+
+// Level returns the global level
+func (c *Controller) Level() zapcore.Level {
+	return c.level.Level()
+}
+
+// SetLevel changes the global level, overrides are kept
+func (c *Controller) SetLevel(level zapcore.Level) {
+	c.level.SetLevel(level)
+}
+
+// ComponentLevels returns a copy of the component overrides
+func (c *Controller) ComponentLevels() map[string]zapcore.Level {
+	current := *c.components.Load()
+	levels := make(map[string]zapcore.Level, len(current))
+	for component, level := range current {
+		levels[component] = level
+	}
+	return levels
+}
+
+// Components returns the sorted names of the components loggers were
+// tagged with so far
+func (c *Controller) Components() []string {
+	var components []string
+	c.known.Range(func(key, _ interface{}) bool {
+		components = append(components, key.(string))
+		return true
+	})
+	sort.Strings(components)
+	return components
+}
+
+// SetComponentLevel overrides the level of a single component. Only
+// components loggers were tagged with, or already overridden, can be
+// overridden, ErrUnknownComponent otherwise.
+func (c *Controller) SetComponentLevel(component string, level zapcore.Level) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	levels := c.ComponentLevels()
+	if _, ok := levels[component]; !ok {
+		if _, ok := c.known.Load(component); !ok {
+			return ErrUnknownComponent
+		}
+	}
+
+	levels[component] = level
+	c.storeComponents(levels)
+	return nil
+}
+
+// ClearComponentLevel makes a component follow the global level again
+func (c *Controller) ClearComponentLevel(component string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	levels := c.ComponentLevels()
+	delete(levels, component)
+	c.storeComponents(levels)
+}
+
+// Reset replaces the global level and every override at once
+func (c *Controller) Reset(level zapcore.Level, components map[string]zapcore.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.level.SetLevel(level)
+	c.storeComponents(components)
+}
+
+// storeComponents publishes a copy of levels. Readers never lock, they
+// load the map pointer on every entry.
+func (c *Controller) storeComponents(levels map[string]zapcore.Level) {
+	copied := make(map[string]zapcore.Level, len(levels))
+	for component, level := range levels {
+		copied[component] = level
+	}
+	c.components.Store(&copied)
+}
+
+// enabled reports whether component logs at level
+func (c *Controller) enabled(component string, level zapcore.Level) bool {
+	if component != "" {
+		if min, ok := (*c.components.Load())[component]; ok {
+			return level >= min
+		}
+	}
+	return c.level.Enabled(level)
+}
+
+// Wrap returns a core that filters entries by the controller levels. The
+// wrapped core must accept every level, filtering is left to the
+// controller.
+func (c *Controller) Wrap(core zapcore.Core) zapcore.Core {
+	return &levelCore{Core: core, ctrl: c}
+}
+
+// levelCore gates entries on the level of the component it was tagged with
+type levelCore struct {
+	zapcore.Core
+	ctrl      *Controller
+	component string
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.ctrl.enabled(c.component, level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	child := &levelCore{
+		Core:      c.Core.With(fields),
+		ctrl:      c.ctrl,
+		component: c.component,
+	}
+
+	for _, f := range fields {
+		if f.Key != componentKey || f.Type != zapcore.StringType {
+			continue
+		}
+
+		child.component = f.String
+		if _, ok := c.ctrl.known.Load(f.String); !ok {
+			c.ctrl.known.Store(f.String, struct{}{})
+		}
+		if _, ok := c.ctrl.sampled[f.String]; ok && c.ctrl.sampling.Thereafter > 0 {
+			child.Core = newSampledCore(child.Core, c.ctrl.sampling)
+		}
+	}
+
+	return child
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// sampledCore samples info and debug entries and passes the rest through
+type sampledCore struct {
+	zapcore.Core
+	sampler zapcore.Core
+}
+
+func newSampledCore(core zapcore.Core, s Sampling) zapcore.Core {
+	tick := s.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+
+	return &sampledCore{
+		Core:    core,
+		sampler: zapcore.NewSamplerWithOptions(core, tick, s.Initial, s.Thereafter),
+	}
+}
+
+func (c *sampledCore) With(fields []zapcore.Field) zapcore.Core {
+	// The sampler shares its counters with its children, so request scoped
+	// loggers are sampled together
+	return &sampledCore{
+		Core:    c.Core.With(fields),
+		sampler: c.sampler.With(fields),
+	}
+}
+
+func (c *sampledCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.WarnLevel {
+		return c.Core.Check(ent, ce)
+	}
+	return c.sampler.Check(ent, ce)
+}
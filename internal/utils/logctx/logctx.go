@@ -0,0 +1,60 @@
+// Package logctx carries request scoped log fields in context.Context, so
+// logs written in any layer can be correlated with the request
+package logctx
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	userIDKey
+)
+
+// WithRequestID returns a context carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, empty if none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithUserID returns a context carrying the authenticated user ID
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserID returns the user ID carried by ctx, empty if none
+func UserID(ctx context.Context) string {
+	id, _ := ctx.Value(userIDKey).(string)
+	return id
+}
+
+// Logger returns logger enriched with the request ID, user ID and trace ID
+// carried by ctx. Fields that are absent are omitted.
+func Logger(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	fields := make([]zap.Field, 0, 3)
+
+	if id := RequestID(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if id := UserID(ctx); id != "" {
+		fields = append(fields, zap.String("user_id", id))
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.HasTraceID() {
+		fields = append(fields, zap.String("trace_id", spanCtx.TraceID().String()))
+	}
+
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
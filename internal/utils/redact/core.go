@@ -0,0 +1,102 @@
+package redact
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// core masks sensitive fields before they reach the wrapped core. Fields
+// named after a secret or one of the configured PII keys are replaced by
+// Mask; string, error and stringer values, and the message itself, have
+// token shaped substrings masked. Values nested in objects and reflected
+// structs are not inspected, log sensitive data in them as Secret.
+type core struct {
+	zapcore.Core
+	keys map[string]struct{}
+}
+
+// NewCore wraps c so every entry written through it is redacted. piiKeys
+// are masked in addition to the built in secret keys, matched without
+// regard to case.
+func NewCore(c zapcore.Core, piiKeys []string) zapcore.Core {
+	keys := make(map[string]struct{}, len(secretKeys)+len(piiKeys))
+	for _, key := range secretKeys {
+		keys[key] = struct{}{}
+	}
+	for _, key := range piiKeys {
+		keys[strings.ToLower(strings.TrimSpace(key))] = struct{}{}
+	}
+
+	return &core{Core: c, keys: keys}
+}
+
+// With redacts fields added to child loggers once, when they are added
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{
+		Core: c.Core.With(c.redact(fields)),
+		keys: c.keys,
+	}
+}
+
+// Check registers c rather than the wrapped core, so Write goes through it
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = Text(ent.Message)
+	return c.Core.Write(ent, c.redact(fields))
+}
+
+// redact returns fields with sensitive values masked. The input slice is
+// not modified, callers may reuse it.
+func (c *core) redact(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+
+	for i, f := range fields {
+		if _, ok := c.keys[strings.ToLower(f.Key)]; ok {
+			if f.Type == zapcore.StringType && f.String == "" {
+				redacted[i] = f
+			} else {
+				redacted[i] = zap.String(f.Key, Mask)
+			}
+			continue
+		}
+
+		switch f.Type {
+		case zapcore.StringType:
+			f.String = Text(f.String)
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok && err != nil {
+				f = zap.String(f.Key, Text(err.Error()))
+			}
+		case zapcore.StringerType:
+			if _, ok := f.Interface.(Secret); !ok {
+				f = zap.String(f.Key, Text(stringOf(f)))
+			}
+		}
+		redacted[i] = f
+	}
+
+	return redacted
+}
+
+// stringOf renders a stringer field the way zap would
+func stringOf(f zapcore.Field) (s string) {
+	defer func() {
+		// A nil pointer receiver panics, zap reports those the same way
+		if r := recover(); r != nil {
+			s = "<nil>"
+		}
+	}()
+
+	if stringer, ok := f.Interface.(interface{ String() string }); ok {
+		return stringer.String()
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+// Package redact keeps credentials and personal data out of logs. It
+// provides a zap core that masks sensitive fields by key and token shaped
+// values, and types that mask themselves however they are printed.
+package redact
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Mask replaces redacted values
+const Mask = "[REDACTED]"
+
+// Secret is a string that never prints its value. Use it for tokens and
+// keys passed to loggers or formatted into errors; string(s) returns the
+// raw value where it is actually needed.
+type Secret string
+
+// String implements fmt.Stringer
+func (s Secret) String() string {
+	return Mask
+}
+
+// GoString implements fmt.GoStringer, used by %#v
+func (s Secret) GoString() string {
+	return Mask
+}
+
+// Format masks the value for every verb, including %x and %q
+func (s Secret) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, Mask)
+}
+
+// MarshalText masks the value in text and JSON encoders
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(Mask), nil
+}
+
+// String returns a log field whose value is masked. Unlike omitting the
+// field, it still shows whether a value was present.
+func String(key, value string) zap.Field {
+	if value == "" {
+		return zap.String(key, "")
+	}
+	return zap.Stringer(key, Secret(value))
+}
+
+// URL masks the password in a connection URL, keeping the host and
+// database visible. Values that can't be parsed are masked entirely.
+func URL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return Mask
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+	}
+
+	// Passwords can also be passed as query parameters
+	query := u.Query()
+	for key := range query {
+		if isSecretKey(key) {
+			query.Set(key, "xxxxx")
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+var (
+	// JWTs, including the ones issued by this service
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]{4,}\.[A-Za-z0-9_-]*`)
+	// Telegram bot tokens, <bot id>:<secret>
+	botTokenPattern = regexp.MustCompile(`\d{6,}:[A-Za-z0-9_-]{30,}`)
+	// Authorization header values
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]{8,}`)
+	// Passwords in URLs, as printed by connection errors
+	userinfoPattern = regexp.MustCompile(`://([^:/@\s]*):[^@\s]+@`)
+)
+
+// Text masks token shaped substrings of s. Telegram init data is masked
+// as a whole since it carries the user's profile next to its signature.
+func Text(s string) string {
+	if isInitData(s) {
+		return Mask
+	}
+
+	// Cheap checks first, most log values contain none of these
+	if strings.Contains(s, "eyJ") {
+		s = jwtPattern.ReplaceAllString(s, Mask)
+	}
+	if strings.Contains(s, ":") {
+		s = botTokenPattern.ReplaceAllString(s, Mask)
+	}
+	if strings.Contains(s, "://") {
+		s = userinfoPattern.ReplaceAllString(s, "://$1:xxxxx@")
+	}
+	if strings.Contains(strings.ToLower(s), "bearer") {
+		s = bearerPattern.ReplaceAllString(s, "Bearer "+Mask)
+	}
+
+	return s
+}
+
+// isInitData reports whether s looks like Telegram Mini App init data
+func isInitData(s string) bool {
+	return strings.Contains(s, "auth_date=") && strings.Contains(s, "hash=")
+}
+
+// secretKeys are always masked, whatever the configuration
+var secretKeys = []string{
+	"token",
+	"access_token",
+	"refresh_token",
+	"bot_token",
+	"csrf_token",
+	"authorization",
+	"cookie",
+	"set-cookie",
+	"password",
+	"secret",
+	"api_key",
+	"init_data",
+	"initdata",
+	"hash",
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range secretKeys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
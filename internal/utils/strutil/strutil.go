@@ -0,0 +1,14 @@
+// Package strutil holds string helpers shared by the usecases
+package strutil
+
+// MaxUserAgentLength matches the user_agent columns
+const MaxUserAgentLength = 512
+
+// Truncate cuts s to at most n characters
+func Truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -2,10 +2,10 @@ package validator
 
 import (
 	"errors"
-	"fmt"
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 	"reflect"
+	"renfound_v1/internal/i18n"
 	"strings"
 )
 
@@ -16,7 +16,12 @@ type Validator struct {
 
 type ValidationError struct {
 	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
 	Message string `json:"message"`
+
+	// MessageKey is the catalog key of Message, used to translate it
+	MessageKey string `json:"-"`
 }
 
 func NewValidator(logger *zap.Logger) *Validator {
@@ -45,10 +50,14 @@ func (v *Validator) Validate(data interface{}) ([]ValidationError, error) {
 		if errors.As(err, &ve) {
 			for _, err := range ve {
 				// creates validation for each field
+				key := getMessageKey(err)
 				validationError := ValidationError{
-					Field:   err.Field(),
-					Message: getErrorMessage(err),
+					Field:      err.Field(),
+					Rule:       err.Tag(),
+					Param:      err.Param(),
+					MessageKey: key,
 				}
+				validationError.Message = validationError.translate(i18n.DefaultLocale)
 				validationErrors = append(validationErrors, validationError)
 			}
 
@@ -60,25 +69,43 @@ func (v *Validator) Validate(data interface{}) ([]ValidationError, error) {
 	return nil, nil
 }
 
-func getErrorMessage(err validator.FieldError) string {
+// Localize returns a copy of errs with messages translated to locale
+func Localize(locale string, errs []ValidationError) []ValidationError {
+	localized := make([]ValidationError, len(errs))
+	for i, err := range errs {
+		localized[i] = err
+		localized[i].Message = err.translate(locale)
+	}
+	return localized
+}
+
+func (e ValidationError) translate(locale string) string {
+	return i18n.T(locale, e.MessageKey, "Failed validation for '"+e.Rule+"'", map[string]string{
+		"param": e.Param,
+		"tag":   e.Rule,
+	})
+}
+
+// getMessageKey returns the catalog key describing a failed rule
+func getMessageKey(err validator.FieldError) string {
 	switch err.Tag() {
 	case "required":
-		return "This field is required"
+		return "validation.required"
 	case "email":
-		return "Invalid email format"
+		return "validation.email"
 	case "min":
 		if err.Type().Kind() == reflect.String {
-			return fmt.Sprintf("Must be at least %s characters long", err.Param())
+			return "validation.min_length"
 		}
-		return fmt.Sprintf("Must be at least %s", err.Param())
+		return "validation.min"
 	case "max":
 		if err.Type().Kind() == reflect.String {
-			return fmt.Sprintf("Must be at most %s characters long", err.Param())
+			return "validation.max_length"
 		}
-		return fmt.Sprintf("Must be at most %s", err.Param())
+		return "validation.max"
 	case "oneof":
-		return fmt.Sprintf("Must be one of: %s", err.Param())
+		return "validation.oneof"
 	default:
-		return fmt.Sprintf("Failed validation for '%s'", err.Tag())
+		return "validation.default"
 	}
 }
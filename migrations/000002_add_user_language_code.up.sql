@@ -0,0 +1,2 @@
+-- Telegram language of the user, used to localize API responses
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language_code VARCHAR(16) NOT NULL DEFAULT '';
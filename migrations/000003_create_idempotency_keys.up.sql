@@ -0,0 +1,13 @@
+-- Responses of requests sent with an Idempotency-Key, replayed on retries
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(512) PRIMARY KEY,
+    fingerprint VARCHAR(64) NOT NULL,
+    status INT,
+    headers JSONB,
+    body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+    );
+
+-- Create index on expires_at for sweeping expired keys
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,31 @@
+-- Append only log of security relevant events. There are no foreign keys,
+-- events outlive the users they mention.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_id UUID,
+    action VARCHAR(64) NOT NULL,
+    target_id UUID,
+    outcome VARCHAR(16) NOT NULL,
+    reason VARCHAR(64),
+    ip_address VARCHAR(45),
+    user_agent VARCHAR(512),
+    request_id VARCHAR(128),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+    );
+
+-- Create indexes for the newest first listings, overall and per user
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target_id ON audit_events(target_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events(actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action, created_at DESC);
+
+-- Reject updates and deletes, even from the application role
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
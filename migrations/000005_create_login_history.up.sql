@@ -0,0 +1,26 @@
+-- Devices users have signed in from. Unlike audit events, these are
+-- personal data and go away with the user.
+CREATE TABLE IF NOT EXISTS user_devices (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fingerprint VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(512),
+    ip_address VARCHAR(45),
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, fingerprint)
+    );
+
+-- Login history, one row per sign in or token refresh
+CREATE TABLE IF NOT EXISTS login_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    method VARCHAR(16) NOT NULL,
+    device_id VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(512),
+    ip_address VARCHAR(45),
+    new_device BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+    );
+
+-- Create index for the newest first listing per user
+CREATE INDEX IF NOT EXISTS idx_login_history_user_id ON login_history(user_id, created_at DESC);
@@ -0,0 +1,2 @@
+-- Whether the bot can message the user, false once the user blocked it
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bot_reachable BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,28 @@
+-- Broadcast campaigns, messages sent by admins to a segment of users
+CREATE TABLE IF NOT EXISTS broadcasts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    text TEXT NOT NULL,
+    segment JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(16) NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+    );
+
+-- Recipients of a broadcast, chosen when it is created. Sending claims a
+-- recipient until locked_until so a crashed instance gives it back.
+CREATE TABLE IF NOT EXISTS broadcast_recipients (
+    broadcast_id UUID NOT NULL REFERENCES broadcasts(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    error VARCHAR(256),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (broadcast_id, user_id)
+    );
+
+-- Create index for finding recipients still to be sent to
+CREATE INDEX IF NOT EXISTS idx_broadcast_recipients_unsent ON broadcast_recipients(broadcast_id, user_id)
+    WHERE status IN ('pending', 'sending');
@@ -0,0 +1,18 @@
+-- Referral code of a user, created the first time the user asks for it
+ALTER TABLE users ADD COLUMN IF NOT EXISTS referral_code VARCHAR(16) UNIQUE;
+
+-- Attribution of a signup to the user or campaign that brought the user
+-- in. Rows are keyed by Telegram user and outlive the account, so signing
+-- up again after deleting it is not attributed a second time.
+CREATE TABLE IF NOT EXISTS referrals (
+    telegram_id BIGINT PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    referrer_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    campaign VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (referrer_id IS NULL OR referrer_id <> user_id)
+    );
+
+-- Create indexes for the stats of referrers and campaigns
+CREATE INDEX IF NOT EXISTS idx_referrals_referrer_id ON referrals(referrer_id);
+CREATE INDEX IF NOT EXISTS idx_referrals_campaign ON referrals(campaign);
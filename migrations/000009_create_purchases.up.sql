@@ -0,0 +1,26 @@
+-- Ledger of products bought for Telegram Stars. A row is created with the
+-- invoice and its id is the invoice payload. Rows are tied to the Telegram
+-- user as well and outlive the account, so entitlements bought before
+-- deleting it come back when signing up again.
+CREATE TABLE IF NOT EXISTS purchases (
+    id UUID PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    telegram_id BIGINT NOT NULL,
+    product_id VARCHAR(64) NOT NULL,
+    entitlement VARCHAR(64) NOT NULL,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    currency VARCHAR(8) NOT NULL,
+    -- Seconds the entitlement lasts, 0 for forever, as sold
+    duration_seconds BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    charge_id VARCHAR(255) UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    paid_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    refunded_at TIMESTAMP WITH TIME ZONE
+    );
+
+-- Create indexes for listing purchases and resolving entitlements
+CREATE INDEX IF NOT EXISTS idx_purchases_user_id ON purchases(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_purchases_entitlements ON purchases(telegram_id, entitlement)
+    WHERE status = 'paid';
@@ -0,0 +1,39 @@
+// Package migrations embeds the SQL migrations so the binary knows which
+// schema version it expects.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// LatestVersion returns the highest migration version found in FS
+func LatestVersion() uint {
+	var latest uint
+
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return 0
+	}
+
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+
+	return latest
+}
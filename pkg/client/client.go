@@ -0,0 +1,374 @@
+// Package client is a typed Go client for the renfound API. It keeps the
+// token pair in a TokenStore and refreshes the access token transparently
+// when the API reports it as expired.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// basePath is the prefix of every API route
+const basePath = "/api/v1"
+
+// ErrNotAuthenticated is returned by operations that need tokens when the
+// store holds none
+var ErrNotAuthenticated = errors.New("client: not authenticated")
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	tokens     TokenStore
+	userAgent  string
+	language   string
+
+	// refreshMu makes concurrent requests share a single refresh
+	refreshMu sync.Mutex
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTokenStore sets where tokens are kept, in memory by default
+func WithTokenStore(store TokenStore) Option {
+	return func(c *Client) {
+		c.tokens = store
+	}
+}
+
+// WithUserAgent sets the User-Agent header, recorded with sessions
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithLanguage sets the Accept-Language header used to localize messages
+func WithLanguage(language string) Option {
+	return func(c *Client) {
+		c.language = language
+	}
+}
+
+// New creates a client for the API served at baseURL, e.g. https://api.example.com
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		tokens:     NewMemoryTokenStore(),
+		userAgent:  "renfound-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// AuthWithTelegram signs in with Telegram Mini App init data and stores the
+// issued tokens
+func (c *Client) AuthWithTelegram(ctx context.Context, initData string) (*Tokens, error) {
+	var tokens Tokens
+	if err := c.send(ctx, http.MethodPost, "/auth/telegram", "", telegramAuthRequest{InitData: initData}, &tokens); err != nil {
+		return nil, err
+	}
+
+	if err := c.tokens.Save(ctx, &tokens); err != nil {
+		return nil, fmt.Errorf("client: failed to save tokens: %w", err)
+	}
+	return &tokens, nil
+}
+
+// Refresh exchanges the stored refresh token for a new token pair. If the
+// API rejects the refresh token, the store is cleared.
+func (c *Client) Refresh(ctx context.Context) (*Tokens, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	current, err := c.loadTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.refresh(ctx, current.RefreshToken)
+}
+
+// Logout revokes the stored refresh token and clears the store
+func (c *Client) Logout(ctx context.Context) error {
+	current, err := c.loadTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	var resp MessageResponse
+	if err := c.send(ctx, http.MethodPost, "/auth/logout", "", refreshTokenRequest{RefreshToken: current.RefreshToken}, &resp); err != nil {
+		return err
+	}
+	return c.tokens.Clear(ctx)
+}
+
+// LogoutAll revokes every session of the current user and clears the store
+func (c *Client) LogoutAll(ctx context.Context) error {
+	var resp MessageResponse
+	if err := c.authorized(ctx, http.MethodPost, "/auth/logout-all", nil, &resp); err != nil {
+		return err
+	}
+	return c.tokens.Clear(ctx)
+}
+
+// GetMe returns the current user
+func (c *Client) GetMe(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.authorized(ctx, http.MethodGet, "/users/me", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeleteMe deletes the current user and clears the store
+func (c *Client) DeleteMe(ctx context.Context) error {
+	var resp MessageResponse
+	if err := c.authorized(ctx, http.MethodDelete, "/users/me", nil, &resp); err != nil {
+		return err
+	}
+	return c.tokens.Clear(ctx)
+}
+
+// SecurityEvents returns a page of the current user's security history,
+// newest first. Pass the NextCursor of a page to get the next one.
+func (c *Client) SecurityEvents(ctx context.Context, cursor string, limit int) (*SecurityEventPage, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	path := "/users/me/security-events"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var page SecurityEventPage
+	if err := c.authorized(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Logins returns the current user's latest sign ins and token refreshes,
+// newest first. A limit of 0 uses the server default.
+func (c *Client) Logins(ctx context.Context, limit int) ([]Login, error) {
+	path := "/users/me/logins"
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+
+	var resp struct {
+		Logins []Login `json:"logins"`
+	}
+	if err := c.authorized(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Logins, nil
+}
+
+// Referrals returns the referral code of the user with the signups it
+// brought in
+func (c *Client) Referrals(ctx context.Context) (*ReferralStats, error) {
+	var stats ReferralStats
+	if err := c.authorized(ctx, http.MethodGet, "/users/me/referrals", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Products returns the products for sale
+func (c *Client) Products(ctx context.Context) ([]Product, error) {
+	var resp struct {
+		Products []Product `json:"products"`
+	}
+	if err := c.authorized(ctx, http.MethodGet, "/payments/products", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Products, nil
+}
+
+// CreateInvoice creates an invoice link to buy a product, to be opened by
+// the Mini App with Telegram.WebApp.openInvoice
+func (c *Client) CreateInvoice(ctx context.Context, productID string) (*Invoice, error) {
+	var invoice Invoice
+	if err := c.authorized(ctx, http.MethodPost, "/payments/invoices", createInvoiceRequest{ProductID: productID}, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// Purchases returns the current user's paid and refunded purchases,
+// newest first. A limit of 0 uses the server default.
+func (c *Client) Purchases(ctx context.Context, limit int) ([]Purchase, error) {
+	path := "/users/me/purchases"
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+
+	var resp struct {
+		Purchases []Purchase `json:"purchases"`
+	}
+	if err := c.authorized(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Purchases, nil
+}
+
+// Entitlements returns the features the current user paid for and may
+// use now
+func (c *Client) Entitlements(ctx context.Context) ([]Entitlement, error) {
+	var resp struct {
+		Entitlements []Entitlement `json:"entitlements"`
+	}
+	if err := c.authorized(ctx, http.MethodGet, "/users/me/entitlements", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Entitlements, nil
+}
+
+// authorized sends a request with the stored access token. If the token has
+// expired, it is refreshed once and the request is retried.
+func (c *Client) authorized(ctx context.Context, method, path string, in, out interface{}) error {
+	current, err := c.loadTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.send(ctx, method, path, current.AccessToken, in, out)
+	if !errors.Is(err, ErrTokenExpired) {
+		return err
+	}
+
+	accessToken, err := c.refreshExpired(ctx, current.AccessToken)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, method, path, accessToken, in, out)
+}
+
+// refreshExpired refreshes the tokens after expired was rejected, unless a
+// concurrent request has already replaced it. It returns the access token
+// to retry with.
+func (c *Client) refreshExpired(ctx context.Context, expired string) (string, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	current, err := c.loadTokens(ctx)
+	if err != nil {
+		return "", err
+	}
+	if current.AccessToken != expired {
+		return current.AccessToken, nil
+	}
+
+	tokens, err := c.refresh(ctx, current.RefreshToken)
+	if err != nil {
+		return "", err
+	}
+	return tokens.AccessToken, nil
+}
+
+// refresh calls the refresh endpoint and stores the result.
+// Must be called with c.refreshMu held.
+func (c *Client) refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	var tokens Tokens
+	err := c.send(ctx, http.MethodPost, "/auth/refresh", "", refreshTokenRequest{RefreshToken: refreshToken}, &tokens)
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized {
+		// The session is gone, a new sign in is required
+		_ = c.tokens.Clear(ctx)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.tokens.Save(ctx, &tokens); err != nil {
+		return nil, fmt.Errorf("client: failed to save tokens: %w", err)
+	}
+	return &tokens, nil
+}
+
+func (c *Client) loadTokens(ctx context.Context) (*Tokens, error) {
+	tokens, err := c.tokens.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to load tokens: %w", err)
+	}
+	if tokens == nil {
+		return nil, ErrNotAuthenticated
+	}
+	return tokens, nil
+}
+
+// send performs a single request. in is encoded as the JSON body and out
+// receives the decoded response; error responses are returned as *Error.
+func (c *Client) send(ctx context.Context, method, path, accessToken string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("client: failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+basePath+path, body)
+	if err != nil {
+		return fmt.Errorf("client: failed to build request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if c.language != "" {
+		req.Header.Set("Accept-Language", c.language)
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("client: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: failed to decode response: %w", err)
+	}
+	return nil
+}
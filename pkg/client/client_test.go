@@ -0,0 +1,249 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"renfound_v1/pkg/client"
+)
+
+// writeProblem answers with problem details carrying code
+func writeProblem(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"type":   "about:blank",
+		"title":  http.StatusText(status),
+		"status": status,
+		"code":   code,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newClient returns a client of a test server serving mux, signed in with
+// tokens unless it is nil
+func newClient(t *testing.T, mux *http.ServeMux, tokens *client.Tokens) (*client.Client, *client.MemoryTokenStore) {
+	t.Helper()
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	store := client.NewMemoryTokenStore()
+	if tokens != nil {
+		if err := store.Save(context.Background(), tokens); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	return client.New(srv.URL, client.WithTokenStore(store)), store
+}
+
+func TestAuthWithTelegram(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/telegram", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			InitData string `json:"initData"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.InitData != "query_id=1" {
+			writeProblem(w, http.StatusBadRequest, "bad_request")
+			return
+		}
+		writeJSON(w, client.Tokens{AccessToken: "access", RefreshToken: "refresh"})
+	})
+	mux.HandleFunc("GET /api/v1/users/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			writeProblem(w, http.StatusUnauthorized, "invalid_token")
+			return
+		}
+		writeJSON(w, client.User{ID: "user-1", TelegramID: 42})
+	})
+
+	c, store := newClient(t, mux, nil)
+	ctx := context.Background()
+
+	if _, err := c.GetMe(ctx); !errors.Is(err, client.ErrNotAuthenticated) {
+		t.Fatalf("GetMe before sign in = %v, want ErrNotAuthenticated", err)
+	}
+
+	if _, err := c.AuthWithTelegram(ctx, "query_id=1"); err != nil {
+		t.Fatalf("AuthWithTelegram: %v", err)
+	}
+	stored, _ := store.Load(ctx)
+	if stored == nil || stored.AccessToken != "access" || stored.RefreshToken != "refresh" {
+		t.Fatalf("stored tokens = %+v", stored)
+	}
+
+	user, err := c.GetMe(ctx)
+	if err != nil {
+		t.Fatalf("GetMe: %v", err)
+	}
+	if user.ID != "user-1" || user.TelegramID != 42 {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+func TestRefreshOnExpiredToken(t *testing.T) {
+	var refreshes atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		refreshes.Add(1)
+		writeJSON(w, client.Tokens{AccessToken: "fresh", RefreshToken: "refresh-2"})
+	})
+	mux.HandleFunc("GET /api/v1/users/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			writeProblem(w, http.StatusUnauthorized, "token_expired")
+			return
+		}
+		writeJSON(w, client.User{ID: "user-1"})
+	})
+
+	c, store := newClient(t, mux, &client.Tokens{AccessToken: "stale", RefreshToken: "refresh-1"})
+	ctx := context.Background()
+
+	if _, err := c.GetMe(ctx); err != nil {
+		t.Fatalf("GetMe: %v", err)
+	}
+	if got := refreshes.Load(); got != 1 {
+		t.Errorf("got %d refreshes, want 1", got)
+	}
+	stored, _ := store.Load(ctx)
+	if stored == nil || stored.AccessToken != "fresh" || stored.RefreshToken != "refresh-2" {
+		t.Errorf("stored tokens = %+v", stored)
+	}
+
+	// The fresh token is used directly, without another refresh
+	if _, err := c.GetMe(ctx); err != nil {
+		t.Fatalf("second GetMe: %v", err)
+	}
+	if got := refreshes.Load(); got != 1 {
+		t.Errorf("got %d refreshes after second GetMe, want 1", got)
+	}
+}
+
+func TestRefreshOnlyOnce(t *testing.T) {
+	var refreshes, requests atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		refreshes.Add(1)
+		writeJSON(w, client.Tokens{AccessToken: "fresh", RefreshToken: "refresh-2"})
+	})
+	mux.HandleFunc("GET /api/v1/users/me", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		writeProblem(w, http.StatusUnauthorized, "token_expired")
+	})
+
+	c, _ := newClient(t, mux, &client.Tokens{AccessToken: "stale", RefreshToken: "refresh-1"})
+
+	if _, err := c.GetMe(context.Background()); !errors.Is(err, client.ErrTokenExpired) {
+		t.Fatalf("GetMe = %v, want ErrTokenExpired", err)
+	}
+	if got := refreshes.Load(); got != 1 {
+		t.Errorf("got %d refreshes, want 1", got)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
+
+func TestRefreshRejectedClearsTokens(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, http.StatusUnauthorized, "invalid_session")
+	})
+	mux.HandleFunc("GET /api/v1/users/me", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, http.StatusUnauthorized, "token_expired")
+	})
+
+	c, store := newClient(t, mux, &client.Tokens{AccessToken: "stale", RefreshToken: "revoked"})
+	ctx := context.Background()
+
+	if _, err := c.GetMe(ctx); !errors.Is(err, client.ErrInvalidSession) {
+		t.Fatalf("GetMe = %v, want ErrInvalidSession", err)
+	}
+	if stored, _ := store.Load(ctx); stored != nil {
+		t.Errorf("tokens were not cleared: %+v", stored)
+	}
+}
+
+func TestErrorDecoding(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/payments/invoices", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"type":       "about:blank",
+			"title":      "Too Many Requests",
+			"status":     http.StatusTooManyRequests,
+			"detail":     "Slow down",
+			"code":       "too_many_requests",
+			"request_id": "req-1",
+			"errors": []map[string]string{
+				{"field": "product_id", "rule": "required", "message": "product_id is required"},
+			},
+		})
+	})
+	mux.HandleFunc("GET /api/v1/payments/products", func(w http.ResponseWriter, r *http.Request) {
+		// A proxy in front of the API answers without problem details
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("<html>bad gateway</html>"))
+	})
+
+	c, _ := newClient(t, mux, &client.Tokens{AccessToken: "access", RefreshToken: "refresh"})
+	ctx := context.Background()
+
+	_, err := c.CreateInvoice(ctx, "premium")
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("CreateInvoice = %v, want *client.Error", err)
+	}
+	if apiErr.Status != http.StatusTooManyRequests || apiErr.Code != "too_many_requests" ||
+		apiErr.Detail != "Slow down" || apiErr.RequestID != "req-1" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+	if apiErr.RetryAfter != 7*time.Second {
+		t.Errorf("RetryAfter = %v, want 7s", apiErr.RetryAfter)
+	}
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "product_id" {
+		t.Errorf("field errors = %+v", apiErr.Errors)
+	}
+
+	_, err = c.Products(ctx)
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Products = %v, want *client.Error", err)
+	}
+	if apiErr.Status != http.StatusBadGateway || apiErr.Code != "" || apiErr.Detail != "<html>bad gateway</html>" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	err := error(&client.Error{Status: http.StatusNotFound, Code: "product_not_found", Title: "Product not found"})
+
+	if !errors.Is(err, client.ErrProductNotFound) {
+		t.Error("product_not_found does not match ErrProductNotFound")
+	}
+	if errors.Is(err, client.ErrNotFound) || errors.Is(err, client.ErrPurchaseNotFound) {
+		t.Error("product_not_found matches the sentinel of another code")
+	}
+	if errors.Is(err, errors.New("product_not_found")) {
+		t.Error("product_not_found matches an error that is not a *client.Error")
+	}
+
+	wrapped := errors.Join(errors.New("buying failed"), err)
+	if !errors.Is(wrapped, client.ErrProductNotFound) {
+		t.Error("wrapped product_not_found does not match ErrProductNotFound")
+	}
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// FieldError describes a single failed validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error is a problem details response returned by the API. Use errors.Is
+// with the sentinels below to branch on the error code, or errors.As to
+// read the details.
+type Error struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+
+	// RetryAfter is set from the Retry-After header of 429 responses
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("api error %d %s: %s", e.Status, e.Code, e.Title)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// Is matches errors by their code, so errors.Is(err, client.ErrTokenExpired)
+// holds for any token_expired response
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Sentinels for the error codes published by the API
+var (
+	ErrInternal           = &Error{Code: "internal_error"}
+	ErrNotFound           = &Error{Code: "not_found"}
+	ErrConflict           = &Error{Code: "conflict"}
+	ErrBadRequest         = &Error{Code: "bad_request"}
+	ErrValidation         = &Error{Code: "validation_failed"}
+	ErrTooManyRequests    = &Error{Code: "too_many_requests"}
+	ErrForbidden          = &Error{Code: "forbidden"}
+	ErrInvalidCursor      = &Error{Code: "invalid_cursor"}
+	ErrIdempotencyReused  = &Error{Code: "idempotency_key_reused"}
+	ErrIdempotencyBusy    = &Error{Code: "idempotency_in_progress"}
+	ErrUnauthorized       = &Error{Code: "unauthorized"}
+	ErrInvalidToken       = &Error{Code: "invalid_token"}
+	ErrTokenExpired       = &Error{Code: "token_expired"}
+	ErrInvalidCredentials = &Error{Code: "invalid_credentials"}
+	ErrInvalidSignature   = &Error{Code: "invalid_signature"}
+	ErrInvalidInitData    = &Error{Code: "invalid_init_data"}
+	ErrInvalidCSRFToken   = &Error{Code: "invalid_csrf_token"}
+	ErrUserNotFound       = &Error{Code: "user_not_found"}
+	ErrUserExists         = &Error{Code: "user_exists"}
+	ErrSessionNotFound    = &Error{Code: "session_not_found"}
+	ErrInvalidSession     = &Error{Code: "invalid_session"}
+
+	ErrBroadcastNotFound       = &Error{Code: "broadcast_not_found"}
+	ErrBroadcastStatusConflict = &Error{Code: "broadcast_status_conflict"}
+
+	ErrProductNotFound       = &Error{Code: "product_not_found"}
+	ErrPurchaseNotFound      = &Error{Code: "purchase_not_found"}
+	ErrPurchaseNotRefundable = &Error{Code: "purchase_not_refundable"}
+)
+
+// decodeError builds an *Error from a non-2xx response. Bodies that are not
+// problem details, e.g. from a proxy, still produce an *Error with the status.
+func decodeError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	apiErr := &Error{}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Code == "" {
+		apiErr = &Error{
+			Status: resp.StatusCode,
+			Title:  http.StatusText(resp.StatusCode),
+			Detail: string(body),
+		}
+	}
+	if apiErr.Status == 0 {
+		apiErr.Status = resp.StatusCode
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return apiErr
+}
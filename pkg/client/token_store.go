@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+// TokenStore persists the token pair between requests. Implementations must
+// be safe for concurrent use.
+type TokenStore interface {
+	// Load returns the stored tokens, or nil if there are none
+	Load(ctx context.Context) (*Tokens, error)
+	Save(ctx context.Context, tokens *Tokens) error
+	Clear(ctx context.Context) error
+}
+
+// MemoryTokenStore keeps tokens in memory. It is the default store.
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens *Tokens
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+func (s *MemoryTokenStore) Load(_ context.Context) (*Tokens, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.tokens == nil {
+		return nil, nil
+	}
+	tokens := *s.tokens
+	return &tokens, nil
+}
+
+func (s *MemoryTokenStore) Save(_ context.Context, tokens *Tokens) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := *tokens
+	s.tokens = &saved
+	return nil
+}
+
+func (s *MemoryTokenStore) Clear(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = nil
+	return nil
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"time"
+)
+
+// Tokens is the token pair issued by the auth endpoints
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// User is the authenticated user
+type User struct {
+	ID           string    `json:"id"`
+	TelegramID   int64     `json:"telegram_id"`
+	Username     string    `json:"username,omitempty"`
+	FirstName    string    `json:"first_name,omitempty"`
+	LastName     string    `json:"last_name,omitempty"`
+	PhotoURL     string    `json:"photo_url,omitempty"`
+	LanguageCode string    `json:"language_code,omitempty"`
+	AuthDate     int64     `json:"auth_date"`
+	BotReachable bool      `json:"bot_reachable"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// MessageResponse is returned by operations that have no resource to return
+type MessageResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// SecurityEvent is a security relevant event on the user's account
+type SecurityEvent struct {
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+	BySelf    bool      `json:"by_self"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SecurityEventPage is a page of security events
+type SecurityEventPage struct {
+	Events     []SecurityEvent `json:"events"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// Login is a sign in or token refresh of the user
+type Login struct {
+	ID        string    `json:"id"`
+	Method    string    `json:"method"` // "telegram" or "refresh"
+	DeviceID  string    `json:"device_id"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	NewDevice bool      `json:"new_device"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReferralStats is the referral code of the user with the signups it
+// brought in. Link is empty unless the server knows the Mini App link.
+type ReferralStats struct {
+	Code           string     `json:"code"`
+	Link           string     `json:"link,omitempty"`
+	Referred       int        `json:"referred"`
+	LastReferredAt *time.Time `json:"last_referred_at,omitempty"`
+}
+
+// Product is a premium feature sold for Telegram Stars
+type Product struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	Price           int    `json:"price"` // in Stars
+	Entitlement     string `json:"entitlement"`
+	DurationSeconds int64  `json:"duration_seconds,omitempty"` // 0 for forever
+}
+
+// Invoice is an invoice link for a purchase not paid yet
+type Invoice struct {
+	PurchaseID string `json:"purchase_id"`
+	Link       string `json:"link"`
+}
+
+// Purchase is a product the user bought
+type Purchase struct {
+	ID          string     `json:"id"`
+	ProductID   string     `json:"product_id"`
+	Entitlement string     `json:"entitlement"`
+	Amount      int        `json:"amount"`
+	Currency    string     `json:"currency"`
+	Status      string     `json:"status"` // "paid" or "refunded"
+	CreatedAt   time.Time  `json:"created_at"`
+	PaidAt      *time.Time `json:"paid_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RefundedAt  *time.Time `json:"refunded_at,omitempty"`
+}
+
+// Entitlement is a feature the user may use. ExpiresAt is nil if it never
+// expires.
+type Entitlement struct {
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type telegramAuthRequest struct {
+	InitData string `json:"initData"`
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type createInvoiceRequest struct {
+	ProductID string `json:"product_id"`
+}